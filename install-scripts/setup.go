@@ -29,6 +29,7 @@ type BackendConfig struct {
 	JackettIP      string
 	JackettPort    string
 	JackettAPIKey  string
+	TMDBToken      string
 }
 
 type PlexConfig struct {
@@ -94,7 +95,8 @@ DELUGE_USER=%s
 DELUGE_PASSWORD=%s
 JACKETT_IP=%s
 JACKETT_PORT=%s
-JACKETT_API_KEY=%s`,
+JACKETT_API_KEY=%s
+TMDB_API_TOKEN=%s`,
 		config.DBUser,
 		config.DBPassword,
 		config.DBIP,
@@ -106,6 +108,7 @@ JACKETT_API_KEY=%s`,
 		config.JackettIP,
 		config.JackettPort,
 		config.JackettAPIKey,
+		config.TMDBToken,
 	)
 
 	return os.WriteFile(".env", []byte(envContent), 0644)
@@ -187,6 +190,7 @@ func main() {
 		JackettIP:      promptUser("Enter Jackett IP: "),
 		JackettPort:    promptUser("Enter Jackett Port: "),
 		JackettAPIKey:  promptUser("Enter Jackett API Key: "),
+		TMDBToken:      frontendConfig.TMDBToken,
 	}
 
 	// Get Plex Configuration
@@ -0,0 +1,126 @@
+package activity
+
+import (
+	"sync"
+	"time"
+
+	"high-seas/src/db"
+	"high-seas/src/logger"
+)
+
+// Actions
+const (
+	ActionSeedingDone    = "seeding_done"
+	ActionStalledRemoved = "stalled_removed"
+)
+
+// Filter narrows the entries returned by List
+type Filter struct {
+	Action string
+	Limit  int
+}
+
+// Store keeps recent activity in memory and persists it when a database is configured
+type Store struct {
+	mutex   sync.RWMutex
+	entries []db.Activity
+	limit   int
+	nextID  uint
+}
+
+var (
+	globalStore *Store
+	once        sync.Once
+)
+
+// New creates an activity store that keeps at most limit entries in memory
+func New(limit int) *Store {
+	return &Store{
+		entries: make([]db.Activity, 0, limit),
+		limit:   limit,
+		nextID:  1,
+	}
+}
+
+// GetGlobalStore returns the global activity store
+func GetGlobalStore() *Store {
+	once.Do(func() {
+		globalStore = New(500)
+	})
+	return globalStore
+}
+
+// Record stores an action, persisting it when the database is available
+func (s *Store) Record(entry db.Activity) db.Activity {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	logger.WriteInfo(entry.Message)
+
+	if conn, err := db.GetDB(); err == nil {
+		if err := conn.Create(&entry).Error; err != nil {
+			logger.WriteError("Failed to persist activity", err)
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entry.ID == 0 {
+		entry.ID = s.nextID
+	}
+	if entry.ID >= s.nextID {
+		s.nextID = entry.ID + 1
+	}
+
+	s.entries = append(s.entries, entry)
+	if len(s.entries) > s.limit {
+		s.entries = s.entries[len(s.entries)-s.limit:]
+	}
+
+	return entry
+}
+
+// List returns activity, newest first
+func (s *Store) List(filter Filter) []db.Activity {
+	if conn, err := db.GetDB(); err == nil {
+		var entries []db.Activity
+		query := conn.Order("created_at desc").Where(&db.Activity{Action: filter.Action})
+		if filter.Limit > 0 {
+			query = query.Limit(filter.Limit)
+		}
+
+		err = query.Find(&entries).Error
+		if err == nil {
+			return entries
+		}
+		logger.WriteError("Failed to load activity, using in-memory entries", err)
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entries := make([]db.Activity, 0)
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		entry := s.entries[i]
+		if filter.Action != "" && entry.Action != filter.Action {
+			continue
+		}
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			break
+		}
+	}
+
+	return entries
+}
+
+// Record stores an action in the global activity log
+func Record(entry db.Activity) db.Activity {
+	return GetGlobalStore().Record(entry)
+}
+
+// List returns entries from the global activity log
+func List(filter Filter) []db.Activity {
+	return GetGlobalStore().List(filter)
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"high-seas/src/activity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Activity lists what high-seas did on its own, such as removing torrents done seeding
+func Activity(c *gin.Context) {
+	filter := activity.Filter{
+		Action: c.Query("action"),
+		Limit:  100,
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		filter.Limit = n
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    activity.List(filter),
+	})
+}
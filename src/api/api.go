@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/jrudio/go-plex-client"
 	"high-seas/src/jackett"
@@ -9,10 +11,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"high-seas/src/db"
+	"high-seas/src/deluge"
 	"high-seas/src/logger"
 
 	"github.com/gin-gonic/gin"
@@ -129,7 +133,10 @@ func QueryMovieRequest(c *gin.Context) {
 		logger.WriteError("Failed to Unmarshal JSON.", err)
 	}
 
-	jackett.MakeMovieQuery(request.Query, request.TMDb, request.Quality)
+	err = jackett.MakeMovieQuery(searchContext(c), request.Query, request.TMDb, request.Year, request.Quality)
+	if respondSearchTimeout(c, err) || respondWanted(c, err) || respondSearchError(c, err) {
+		return
+	}
 
 	logger.WriteCMDInfo("Read body complete.", "Success")
 
@@ -155,7 +162,16 @@ func QueryShowRequest(c *gin.Context) {
 
 	// fmt.Println("{}", request.Query, request.Seasons, request.Name, request.Year, request.Description)
 
-	jackett.MakeShowQuery(request.Query, request.Seasons, request.TMDb, request.Quality)
+	selection := jackett.ShowSelection{
+		Seasons:  request.SeasonNumbers,
+		Episodes: request.Episodes,
+		Latest:   request.LatestSeason,
+		Specials: request.IncludeSpecials,
+	}
+	err = jackett.MakeShowQuery(searchContext(c), request.Query, request.Seasons, selection, request.TMDb, request.Year, request.Quality)
+	if respondSearchTimeout(c, err) || respondWanted(c, err) || respondSearchError(c, err) {
+		return
+	}
 
 	logger.WriteCMDInfo("Read body complete.", "Success")
 
@@ -179,9 +195,9 @@ func QueryAnimeMovieRequest(c *gin.Context) {
 		logger.WriteError("Failed to Unmarshal JSON.", err)
 	}
 
-	err = jackett.MakeAnimeMovieQuery(request.Query, request.TMDb, request.Quality)
-	if err != nil {
-		logger.WriteError("Failed to Query Anime Movie Request.", err)
+	err = jackett.MakeAnimeMovieQuery(searchContext(c), request.Query, request.TMDb, request.Quality)
+	if respondSearchTimeout(c, err) || respondWanted(c, err) || respondSearchError(c, err) {
+		return
 	}
 
 	logger.WriteCMDInfo("Read body complete.", "Success")
@@ -206,7 +222,16 @@ func MakeAnimeShowQuery(c *gin.Context) {
 		logger.WriteError("Failed to Unmarshal JSON.", err)
 	}
 
-	jackett.MakeAnimeShowQuery(request.Query, request.Seasons, request.TMDb, request.Quality)
+	selection := jackett.ShowSelection{
+		Seasons:  request.SeasonNumbers,
+		Episodes: request.Episodes,
+		Latest:   request.LatestSeason,
+		Specials: request.IncludeSpecials,
+	}
+	err = jackett.MakeAnimeShowQuery(searchContext(c), request.Query, request.Seasons, selection, request.TMDb, request.Quality)
+	if respondSearchTimeout(c, err) || respondWanted(c, err) || respondSearchError(c, err) {
+		return
+	}
 
 	logger.WriteCMDInfo("Read body complete.", "Success")
 
@@ -215,6 +240,72 @@ func MakeAnimeShowQuery(c *gin.Context) {
 	})
 }
 
+// searchContext ties a search to the request, skipping cached indexer responses when
+// the client asks for a refresh with ?refresh=true
+func searchContext(c *gin.Context) context.Context {
+	ctx := c.Request.Context()
+	if refresh, _ := strconv.ParseBool(c.Query("refresh")); refresh {
+		ctx = jackett.WithCacheBypass(ctx)
+	}
+	return ctx
+}
+
+// respondSearchTimeout logs a failed search and answers 504 if it ran out of time
+func respondSearchTimeout(c *gin.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	logger.WriteError("Search request failed.", err)
+
+	if statusForSearchError(err) != http.StatusGatewayTimeout {
+		return false
+	}
+	c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+	return true
+}
+
+// respondWanted answers 202 when nothing matched or was released yet and the request was
+// left on the wanted list to be retried
+func respondWanted(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, jackett.ErrNoMatches):
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "No suitable release found yet, the request was added to the wanted list.",
+		})
+	case errors.Is(err, jackett.ErrNotReleased):
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Not released yet, the request was added to the wanted list to search on release.",
+		})
+	default:
+		return false
+	}
+	return true
+}
+
+// respondSearchError answers any other failed search, such as Jackett or Deluge being
+// unreachable, with the status statusForSearchError picks
+func respondSearchError(c *gin.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	c.JSON(statusForSearchError(err), gin.H{"error": err.Error()})
+	return true
+}
+
+// statusForSearchError maps search errors to a status, 504 for indexer or Deluge timeouts
+// and 400 for a season selection the show can't satisfy
+func statusForSearchError(err error) int {
+	var searchTimeout *jackett.TimeoutError
+	var delugeTimeout *deluge.TimeoutError
+	if errors.As(err, &searchTimeout) || errors.As(err, &delugeTimeout) {
+		return http.StatusGatewayTimeout
+	}
+	if errors.Is(err, jackett.ErrInvalidSelection) {
+		return http.StatusBadRequest
+	}
+	return http.StatusBadGateway
+}
+
 func processTMDbRequest(c *gin.Context, url string) (*db.TMDbResponse, error) {
 	header := c.Request.Header.Get("Authorization")
 
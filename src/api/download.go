@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"high-seas/src/db"
+	"high-seas/src/jackett"
+	"high-seas/src/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DownloadMovie grabs a movie release picked from a preview search
+func DownloadMovie(c *gin.Context) {
+	downloadRelease(c, func(request db.DownloadRequest) (string, error) {
+		return jackett.MediaMovie, nil
+	})
+}
+
+// DownloadTV grabs a show release picked from a preview search
+func DownloadTV(c *gin.Context) {
+	downloadRelease(c, func(request db.DownloadRequest) (string, error) {
+		return jackett.MediaShow, nil
+	})
+}
+
+// DownloadAnime grabs an anime release picked from a preview search; media_type says
+// whether it's for an anime movie or show, defaulting to show
+func DownloadAnime(c *gin.Context) {
+	downloadRelease(c, func(request db.DownloadRequest) (string, error) {
+		switch request.MediaType {
+		case "", jackett.MediaAnimeShow:
+			return jackett.MediaAnimeShow, nil
+		case jackett.MediaAnimeMovie:
+			return jackett.MediaAnimeMovie, nil
+		}
+		return "", fmt.Errorf("media_type must be %s or %s", jackett.MediaAnimeMovie, jackett.MediaAnimeShow)
+	})
+}
+
+func downloadRelease(c *gin.Context, mediaTypeOf func(db.DownloadRequest) (string, error)) {
+	var request db.DownloadRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mediaType, err := mediaTypeOf(request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	link := request.MagnetURI
+	if link == "" {
+		link = request.Link
+	}
+	ref := jackett.ReleaseRef{
+		GUID:     request.GUID,
+		InfoHash: request.InfoHash,
+		Link:     link,
+		Title:    request.Title,
+	}
+
+	grabbed, err := jackett.GrabRelease(searchContext(c), mediaType, request.Query, request.TMDb, ref)
+	if err != nil {
+		logger.WriteError(fmt.Sprintf("Failed to grab release for %s", request.Query), err)
+		c.JSON(statusForGrabError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    grabbed,
+	})
+}
+
+// statusForGrabError maps a failed manual grab to a client or upstream error
+func statusForGrabError(err error) int {
+	switch {
+	case errors.Is(err, jackett.ErrReleaseNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, jackett.ErrInvalidRelease):
+		return http.StatusBadRequest
+	case errors.Is(err, jackett.ErrInsufficientSpace):
+		return http.StatusInsufficientStorage
+	}
+	return statusForSearchError(err)
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"high-seas/src/deluge"
+	"high-seas/src/downloads"
+	"high-seas/src/importer"
+	"high-seas/src/jackett"
+	"high-seas/src/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Downloads lists the torrents high-seas sent to Deluge with their last polled state
+func Downloads(c *gin.Context) {
+	filter := downloads.Filter{
+		MediaType: c.Query("media_type"),
+		State:     c.Query("state"),
+	}
+
+	if tmdb := c.Query("tmdb"); tmdb != "" {
+		id, err := strconv.Atoi(tmdb)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tmdb must be a number"})
+			return
+		}
+		filter.TMDb = id
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    downloads.List(filter),
+	})
+}
+
+// Download shows one tracked torrent, refreshed from Deluge
+func Download(c *gin.Context) {
+	download, err := downloads.Get(c.Request.Context(), c.Param("hash"))
+	if errors.Is(err, downloads.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"success": true,
+		"data":    download,
+	}
+	// The last known state is still useful, but say the refresh failed
+	if err != nil {
+		logger.WriteError("Failed to refresh download from Deluge", err)
+		response["error"] = err.Error()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ImportDownload imports a finished download into the library now, retrying a failed import
+func ImportDownload(c *gin.Context) {
+	folder, err := importer.ImportNow(c.Request.Context(), c.Param("hash"))
+	switch {
+	case errors.Is(err, downloads.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, importer.ErrNotComplete):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, importer.ErrImportFailed):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "path": folder})
+		return
+	case err != nil:
+		logger.WriteError("Failed to import download", err)
+		c.JSON(statusForSearchError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"path":    folder,
+	})
+}
+
+// DelugeStatus checks Deluge is reachable and reports its version and transfer rates
+func DelugeStatus(c *gin.Context) {
+	status, err := deluge.DaemonStatus(c.Request.Context())
+	if err != nil {
+		logger.WriteError("Deluge status check failed", err)
+		c.JSON(statusForSearchError(err), gin.H{
+			"success":   false,
+			"connected": false,
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"connected": true,
+		"data":      status,
+	})
+}
+
+// DiskSpace reports the room left in each download path and the usage of each library root
+func DiskSpace(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"downloads": jackett.DownloadSpaces(c.Request.Context()),
+			"library":   importer.LibraryUsages(),
+		},
+	})
+}
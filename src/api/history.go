@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"high-seas/src/history"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GrabHistory lists recent grabs with the score breakdown that selected each release
+func GrabHistory(c *gin.Context) {
+	filter := history.Filter{
+		MediaType: c.Query("media_type"),
+		Limit:     100,
+	}
+
+	if tmdb := c.Query("tmdb"); tmdb != "" {
+		id, err := strconv.Atoi(tmdb)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tmdb must be a number"})
+			return
+		}
+		filter.TMDb = id
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		filter.Limit = n
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    history.List(filter),
+	})
+}
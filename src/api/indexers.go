@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"high-seas/src/jackett"
+	"high-seas/src/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IndexerCapabilities shows what each Jackett indexer supports and the categories searched
+// per media type. ?refresh=true asks the indexers again instead of using the cached caps.
+func IndexerCapabilities(c *gin.Context) {
+	refresh, _ := strconv.ParseBool(c.Query("refresh"))
+
+	caps, err := jackett.IndexerCapabilities(c.Request.Context(), refresh)
+	if err != nil {
+		logger.WriteError("Failed to discover indexer capabilities", err)
+		if caps == nil {
+			c.JSON(statusForSearchError(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	response := gin.H{
+		"success": true,
+		"data":    caps,
+	}
+	// Stale caps are still useful, but say the refresh failed
+	if err != nil {
+		response["error"] = err.Error()
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+
+	"high-seas/src/db"
+	"high-seas/src/jackett"
+	"high-seas/src/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnhancedMovieSearch previews movie candidates and their score breakdowns without grabbing
+func EnhancedMovieSearch(c *gin.Context) {
+	var request db.MovieRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	candidates, err := jackett.PreviewMovieQuery(searchContext(c), request.Query, request.TMDb, request.Year, request.Quality)
+	if err != nil {
+		logger.WriteError("Failed to preview movie search", err)
+		c.JSON(statusForSearchError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    candidates,
+	})
+}
+
+// EnhancedTVSearch previews series and season pack candidates without grabbing
+func EnhancedTVSearch(c *gin.Context) {
+	var request db.ShowRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	selection := jackett.ShowSelection{
+		Seasons:  request.SeasonNumbers,
+		Episodes: request.Episodes,
+		Latest:   request.LatestSeason,
+		Specials: request.IncludeSpecials,
+	}
+	candidates, err := jackett.PreviewShowQuery(searchContext(c), request.Query, request.Seasons, selection, request.TMDb, request.Year, request.Quality)
+	if err != nil {
+		logger.WriteError("Failed to preview TV search", err)
+		c.JSON(statusForSearchError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    candidates,
+	})
+}
+
+// EnhancedAnimeMovieSearch previews anime movie candidates without grabbing
+func EnhancedAnimeMovieSearch(c *gin.Context) {
+	var request db.AnimeMovieRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	candidates, err := jackett.PreviewAnimeMovieQuery(searchContext(c), request.Query, request.TMDb, request.Quality)
+	if err != nil {
+		logger.WriteError("Failed to preview anime movie search", err)
+		c.JSON(statusForSearchError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    candidates,
+	})
+}
+
+// EnhancedAnimeTVSearch previews anime batch candidates without grabbing
+func EnhancedAnimeTVSearch(c *gin.Context) {
+	var request db.AnimeTvRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	selection := jackett.ShowSelection{
+		Seasons:  request.SeasonNumbers,
+		Episodes: request.Episodes,
+		Latest:   request.LatestSeason,
+		Specials: request.IncludeSpecials,
+	}
+	candidates, err := jackett.PreviewAnimeShowQuery(searchContext(c), request.Query, request.Seasons, selection, request.TMDb, request.Quality)
+	if err != nil {
+		logger.WriteError("Failed to preview anime TV search", err)
+		c.JSON(statusForSearchError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    candidates,
+	})
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"high-seas/src/jackett"
+	"high-seas/src/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WantedList lists the movies and shows waiting on a release, soonest retry first
+func WantedList(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    jackett.WantedList(),
+	})
+}
+
+// SearchWanted searches for a wanted entry now instead of waiting for its next retry
+func SearchWanted(c *gin.Context) {
+	id, ok := wantedID(c)
+	if !ok {
+		return
+	}
+
+	item, err := jackett.SearchWanted(searchContext(c), id)
+	switch {
+	case errors.Is(err, jackett.ErrWantedNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil && !errors.Is(err, jackett.ErrNoMatches) && !errors.Is(err, jackett.ErrNotReleased):
+		logger.WriteError(fmt.Sprintf("Wanted search %d failed", id), err)
+		c.JSON(statusForSearchError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// A fulfilled entry is gone from the list
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"fulfilled": item == nil,
+		"data":      item,
+	})
+}
+
+// DropWanted removes an entry from the wanted list
+func DropWanted(c *gin.Context) {
+	id, ok := wantedID(c)
+	if !ok {
+		return
+	}
+
+	if err := jackett.DropWanted(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func wantedID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be a positive number"})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -2,8 +2,11 @@ package db
 
 //
 import (
+	"encoding/json"
 	"fmt"
 	"high-seas/src/utils"
+	"sync"
+	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -193,11 +196,17 @@ type MovieRequest struct {
 // ShowRequest represents a request to download a TV show
 type ShowRequest struct {
 	Query       string `json:"query"`
-	Seasons     []int  `json:"seasons"`
+	Seasons     []int  `json:"seasons"` // episode counts, only needed without a TMDb ID
 	Quality     string `json:"quality"`
 	TMDb        int    `json:"TMDb"`
 	Description string `json:"description"`
 	Year        int    `json:"year,omitempty"` // Now includes year
+	// The season numbers, episodes (e.g. "S03E04-E08") or latest season to search, every
+	// regular season when none are given, and whether to include specials
+	SeasonNumbers   []int  `json:"season_numbers,omitempty"`
+	Episodes        string `json:"episodes,omitempty"`
+	LatestSeason    bool   `json:"latest_season,omitempty"`
+	IncludeSpecials bool   `json:"include_specials,omitempty"`
 }
 
 // AnimeMovieRequest represents a request to download an anime movie
@@ -213,11 +222,29 @@ type AnimeMovieRequest struct {
 // AnimeTvRequest represents a request to download an anime TV show
 type AnimeTvRequest struct {
 	Query       string `json:"query"`
-	Seasons     []int  `json:"seasons"`
+	Seasons     []int  `json:"seasons"` // episode counts, only needed without a TMDb ID
 	Quality     string `json:"quality"`
 	TMDb        int    `json:"TMDb"`
 	Description string `json:"description"`
 	Year        int    `json:"year,omitempty"` // Now includes year
+	// The season numbers, episodes (e.g. "S03E04-E08") or latest season to search, every
+	// regular season when none are given, and whether to include specials
+	SeasonNumbers   []int  `json:"season_numbers,omitempty"`
+	Episodes        string `json:"episodes,omitempty"`
+	LatestSeason    bool   `json:"latest_season,omitempty"`
+	IncludeSpecials bool   `json:"include_specials,omitempty"`
+}
+
+// DownloadRequest represents a manual grab of a release picked from a preview search
+type DownloadRequest struct {
+	Query     string `json:"query"`
+	TMDb      int    `json:"TMDb"`
+	MediaType string `json:"media_type,omitempty"` // anime only: anime_movie or anime_show
+	GUID      string `json:"guid,omitempty"`
+	InfoHash  string `json:"info_hash,omitempty"`
+	Link      string `json:"link,omitempty"`
+	MagnetURI string `json:"magnet_uri,omitempty"`
+	Title     string `json:"title,omitempty"`
 }
 
 // TvShowSeasonRequest represents a request for TV show season information
@@ -238,6 +265,129 @@ type MovieRecommendationsRequest struct {
 	Page    int `json:"page,omitempty"`
 }
 
+// GrabHistory records a release sent to the download client and how it was scored
+type GrabHistory struct {
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	MediaType string          `gorm:"index" json:"media_type"`
+	Query     string          `json:"query"`
+	TMDb      int             `gorm:"index" json:"TMDb"`
+	Title     string          `json:"title"`
+	Indexer   string          `json:"indexer"`
+	GUID      string          `gorm:"type:text" json:"guid"`
+	InfoHash  string          `json:"info_hash,omitempty"`
+	Link      string          `gorm:"type:text" json:"link"`
+	Size      uint            `json:"size"`
+	Seeders   uint            `json:"seeders"`
+	Score     float64         `json:"score"`
+	Breakdown json.RawMessage `gorm:"type:text" json:"breakdown,omitempty"`
+	Status    string          `json:"status"`
+	Error     string          `gorm:"type:text" json:"error,omitempty"`
+	Manual    bool            `json:"manual"`
+	Episodes  string          `json:"episodes,omitempty"`              // the requested episodes a show grab covers
+	Path      string          `gorm:"type:text" json:"path,omitempty"` // where an import put the files
+	Quality   string          `json:"quality,omitempty"`
+}
+
+// WantedItem is a movie or show still being searched for, kept so retries survive a restart
+type WantedItem struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Key            string     `gorm:"uniqueIndex;size:191" json:"-"`
+	MediaType      string     `gorm:"index" json:"media_type"`
+	Query          string     `json:"query"`
+	TMDb           int        `gorm:"index" json:"TMDb"`
+	Year           int        `json:"year,omitempty"`
+	Quality        string     `json:"quality"`
+	Seasons        []int      `gorm:"serializer:json;type:text" json:"seasons,omitempty"`
+	Missing        []string   `gorm:"serializer:json;type:text" json:"missing,omitempty"`
+	Grabbed        []string   `gorm:"serializer:json;type:text" json:"grabbed,omitempty"`
+	Monitored      bool       `json:"monitored"`
+	Attempts       int        `json:"attempts"`
+	LastSearchedAt *time.Time `json:"last_searched_at,omitempty"`
+	NextSearchAt   *time.Time `gorm:"index" json:"next_search_at,omitempty"`
+	// The latest episode aired and the next release date, as of the last search
+	LastAired string     `json:"last_aired,omitempty"`
+	ReleaseAt *time.Time `json:"release_at,omitempty"`
+}
+
+// Download is a torrent high-seas sent to Deluge, linked to the request that grabbed it
+// and refreshed from Deluge until it's removed there
+type Download struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Hash         string     `gorm:"uniqueIndex;size:40" json:"hash"`
+	HistoryID    uint       `json:"history_id,omitempty"` // the grab history entry
+	MediaType    string     `gorm:"index" json:"media_type"`
+	Query        string     `json:"query"`
+	TMDb         int        `gorm:"index" json:"TMDb"`
+	Episodes     string     `json:"episodes,omitempty"`
+	Title        string     `json:"title"`
+	Indexer      string     `json:"indexer,omitempty"`
+	Quality      string     `json:"quality,omitempty"`
+	Name         string     `json:"name,omitempty"` // the torrent's name in Deluge
+	State        string     `gorm:"index" json:"state"`
+	Progress     float64    `json:"progress"`
+	ETA          int64      `json:"eta"`
+	DownloadRate int64      `json:"download_rate"`
+	UploadRate   int64      `json:"upload_rate"`
+	Ratio        float64    `json:"ratio"`
+	SeedingTime  int64      `json:"seeding_time"` // seconds
+	SavePath     string     `gorm:"type:text" json:"save_path,omitempty"`
+	TotalSize    int64      `json:"total_size"`
+	Message      string     `gorm:"type:text" json:"message,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	CheckedAt    *time.Time `json:"checked_at,omitempty"`
+	// LastProgressAt is when the download last moved forward, or was paused or queued
+	LastProgressAt *time.Time `json:"last_progress_at,omitempty"`
+	// Set once the completed files have been imported into the library, or failed to
+	ImportStatus string     `gorm:"index" json:"import_status,omitempty"`
+	ImportedTo   string     `gorm:"type:text" json:"imported_to,omitempty"`
+	ImportError  string     `gorm:"type:text" json:"import_error,omitempty"`
+	ImportedAt   *time.Time `json:"imported_at,omitempty"`
+	// Plex is asked to scan the imported folder, then checked until the item shows up
+	PlexStatus  string     `gorm:"index" json:"plex_status,omitempty"`
+	PlexSection string     `json:"plex_section,omitempty"`
+	PlexKey     string     `json:"plex_key,omitempty"`
+	AvailableAt *time.Time `json:"available_at,omitempty"`
+}
+
+// BlockedRelease is a release that won't be grabbed again, such as one that stalled
+type BlockedRelease struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	MediaType string    `json:"media_type"`
+	Query     string    `json:"query"`
+	TMDb      int       `gorm:"index" json:"TMDb"`
+	Title     string    `gorm:"type:text" json:"title"`
+	InfoHash  string    `gorm:"index;size:40" json:"info_hash,omitempty"`
+	Reason    string    `json:"reason"`
+}
+
+// Activity is something high-seas did on its own, such as removing a torrent
+type Activity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Action    string    `gorm:"index" json:"action"`
+	MediaType string    `json:"media_type,omitempty"`
+	Query     string    `json:"query,omitempty"`
+	TMDb      int       `gorm:"index" json:"TMDb,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	InfoHash  string    `json:"info_hash,omitempty"`
+	Message   string    `gorm:"type:text" json:"message"`
+}
+
+// TitleAlias remembers which title variant last found a release for a TMDb title
+type TitleAlias struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Kind      string    `gorm:"uniqueIndex:idx_title_alias;size:16" json:"kind"`
+	TMDb      int       `gorm:"uniqueIndex:idx_title_alias" json:"TMDb"`
+	Title     string    `json:"title"`
+}
+
 // FilterOptions represents filter options for TMDb API
 type FilterOptions struct {
 	Genres         []int    `json:"genres,omitempty"`
@@ -455,3 +605,30 @@ func ConnectToDb() (*gorm.DB, error) {
 
 	return db, err
 }
+
+var (
+	sharedDB     *gorm.DB
+	sharedDBErr  error
+	sharedDBOnce sync.Once
+)
+
+// GetDB returns the shared database connection, migrating the high-seas tables
+// on first use. It returns an error when no database is configured so callers
+// can fall back to in-memory state.
+func GetDB() (*gorm.DB, error) {
+	sharedDBOnce.Do(func() {
+		if ip == "" {
+			sharedDBErr = fmt.Errorf("database not configured")
+			return
+		}
+
+		sharedDB, sharedDBErr = ConnectToDb()
+		if sharedDBErr != nil {
+			return
+		}
+
+		sharedDBErr = sharedDB.AutoMigrate(&GrabHistory{}, &TitleAlias{}, &WantedItem{}, &Download{}, &BlockedRelease{}, &Activity{})
+	})
+
+	return sharedDB, sharedDBErr
+}
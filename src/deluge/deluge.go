@@ -1,11 +1,14 @@
 package deluge
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"high-seas/src/logger"
 	"high-seas/src/utils"
 	"strconv"
 	"strings"
+	"time"
 
 	delugeclient "github.com/gdm85/go-libdeluge"
 )
@@ -15,8 +18,84 @@ var (
 	password = utils.EnvVar("DELUGE_PASSWORD", "")
 	ip       = utils.EnvVar("DELUGE_IP", "")
 	port     = utils.EnvVar("DELUGE_PORT", "")
+
+	// timeout bounds a single call to Deluge, connecting included
+	timeout = utils.EnvVarDuration("DELUGE_TIMEOUT", 30*time.Second)
 )
 
+// TimeoutError reports that Deluge didn't answer before the deadline
+type TimeoutError struct {
+	Operation string
+	Timeout   time.Duration
+	Err       error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("deluge %s timed out after %s", e.Operation, e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// call runs fn on a pooled session with the per-call deadline. The Deluge client doesn't
+// take a context, so on timeout or cancellation we stop waiting and the session goes back
+// to the pool once fn returns.
+func call(ctx context.Context, operation string, fn func(*delugeclient.ClientV2) error) error {
+	_, err := callResult(ctx, operation, func(deluge *delugeclient.ClientV2) (struct{}, error) {
+		return struct{}{}, fn(deluge)
+	})
+	return err
+}
+
+// callResult is call for operations that return a value. The value comes back with fn's
+// error once fn finishes, so a call abandoned on timeout never touches what the caller reads.
+func callResult[T any](ctx context.Context, operation string, fn func(*delugeclient.ClientV2) (T, error)) (T, error) {
+	type outcome struct {
+		value T
+		err   error
+	}
+	var zero T
+
+	callCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	p := getPool()
+	done := make(chan outcome, 1)
+	s, err := p.acquire(callCtx)
+	if err == nil {
+		go func() {
+			var out outcome
+			out.err = p.use(callCtx, s, func(deluge *delugeclient.ClientV2) error {
+				var err error
+				out.value, err = fn(deluge)
+				return err
+			})
+			p.release(s, out.err)
+			done <- out
+		}()
+
+		select {
+		case out := <-done:
+			return out.value, out.err
+		case <-callCtx.Done():
+			err = callCtx.Err()
+		}
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+		return zero, err
+	}
+	if ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return zero, &TimeoutError{Operation: operation, Timeout: timeout, Err: callCtx.Err()}
+	}
+	return zero, fmt.Errorf("deluge %s cancelled: %w", operation, ctx.Err())
+}
+
 // connectToDeluge creates and connects to a deluge client
 func connectToDeluge() (*delugeclient.ClientV2, error) {
 	numPort, err := strconv.Atoi(port)
@@ -31,9 +110,10 @@ func connectToDeluge() (*delugeclient.ClientV2, error) {
 		Password: password,
 	})
 
-	logger.WriteInfo("Attempting to connect to Deluge")
+	logger.WriteInfo(fmt.Sprintf("Attempting to connect to Deluge at %s:%s", ip, port))
 	err = deluge.Connect()
 	if err != nil {
+		deluge.Close()
 		return nil, fmt.Errorf("failed to connect to deluge: %v", err)
 	}
 	logger.WriteInfo("Successfully connected to Deluge")
@@ -41,32 +121,48 @@ func connectToDeluge() (*delugeclient.ClientV2, error) {
 	return deluge, nil
 }
 
-// AddTorrent adds either a magnet link or torrent URL to Deluge
-func AddTorrent(file string) error {
-	logger.WriteInfo(fmt.Sprintf("Initializing Deluge connection to %s:%s", ip, port))
+// AddTorrent adds either a magnet link or torrent URL to Deluge with the given options and
+// returns the torrent's hash. After a TimeoutError the torrent may still have been added.
+func AddTorrent(ctx context.Context, file string, addOptions AddOptions) (string, error) {
+	return callResult(ctx, "add torrent", func(deluge *delugeclient.ClientV2) (string, error) {
+		options := addOptions.clientOptions()
 
-	deluge, err := connectToDeluge()
-	if err != nil {
-		return err
-	}
+		var hash string
+		if strings.HasPrefix(file, "magnet:") {
+			logger.WriteInfo(fmt.Sprintf("Sending magnet link to Deluge: %s", file))
+			result, err := deluge.AddTorrentMagnet(file, options)
+			if err != nil {
+				return "", fmt.Errorf("failed to add magnet link: %w", err)
+			}
+			logger.WriteInfo(fmt.Sprintf("Successfully added magnet, Deluge response: %v", result))
+			hash = strings.ToLower(result)
+		} else {
+			logger.WriteInfo(fmt.Sprintf("Sending torrent URL to Deluge: %s", file))
+			result, err := deluge.AddTorrentURL(file, options)
+			if err != nil {
+				return "", fmt.Errorf("failed to add torrent URL: %w", err)
+			}
+			logger.WriteInfo(fmt.Sprintf("Successfully added URL, Deluge response: %v", result))
+			hash = strings.ToLower(result)
+		}
 
-	options := &delugeclient.Options{}
+		applyLabel(deluge, hash, addOptions.Label)
+		return hash, nil
+	})
+}
 
-	if strings.HasPrefix(file, "magnet:") {
-		logger.WriteInfo(fmt.Sprintf("Sending magnet link to Deluge: %s", file))
-		result, err := deluge.AddTorrentMagnet(file, options)
-		if err != nil {
-			return fmt.Errorf("failed to add magnet link: %v", err)
-		}
-		logger.WriteInfo(fmt.Sprintf("Successfully added magnet, Deluge response: %v", result))
-	} else {
-		logger.WriteInfo(fmt.Sprintf("Sending torrent URL to Deluge: %s", file))
-		result, err := deluge.AddTorrentURL(file, options)
-		if err != nil {
-			return fmt.Errorf("failed to add torrent URL: %v", err)
-		}
-		logger.WriteInfo(fmt.Sprintf("Successfully added URL, Deluge response: %v", result))
-	}
+// RemoveTorrent removes a torrent from Deluge, with its downloaded data when removeData is set
+func RemoveTorrent(ctx context.Context, hash string, removeData bool) error {
+	return call(ctx, "remove torrent", func(deluge *delugeclient.ClientV2) error {
+		_, err := deluge.RemoveTorrent(hash, removeData)
+		return err
+	})
+}
 
-	return nil
+// FreeSpace returns the bytes free on the disk holding path, or Deluge's download
+// location when path is empty
+func FreeSpace(ctx context.Context, path string) (int64, error) {
+	return callResult(ctx, "free space", func(deluge *delugeclient.ClientV2) (int64, error) {
+		return deluge.GetFreeSpace(path)
+	})
 }
@@ -0,0 +1,90 @@
+package deluge
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"high-seas/src/logger"
+	"high-seas/src/utils"
+
+	delugeclient "github.com/gdm85/go-libdeluge"
+)
+
+// AddOptions controls where a torrent is saved and how it starts; zero values leave
+// Deluge's defaults alone
+type AddOptions struct {
+	DownloadPath      string `json:"download_path,omitempty"`
+	MoveCompletedPath string `json:"move_completed_path,omitempty"`
+	// Label is set through the Label plugin, which has to be enabled in Deluge
+	Label  string `json:"label,omitempty"`
+	Paused bool   `json:"paused,omitempty"`
+	// Priority downloads the first and last pieces first, so a video can be previewed early
+	Priority bool `json:"priority,omitempty"`
+}
+
+// OptionsFromEnv reads the options for one kind of download from DELUGE_<KIND>_PATH,
+// _MOVE_COMPLETED_PATH, _LABEL, _PAUSED and _PRIORITY
+func OptionsFromEnv(kind string) AddOptions {
+	prefix := "DELUGE_" + strings.ToUpper(kind) + "_"
+	return AddOptions{
+		DownloadPath:      utils.EnvVar(prefix+"PATH", ""),
+		MoveCompletedPath: utils.EnvVar(prefix+"MOVE_COMPLETED_PATH", ""),
+		Label:             strings.ToLower(utils.EnvVar(prefix+"LABEL", "")),
+		Paused:            utils.EnvVarBool(prefix+"PAUSED", false),
+		Priority:          utils.EnvVarBool(prefix+"PRIORITY", false),
+	}
+}
+
+// clientOptions converts the options to the ones Deluge takes when adding a torrent
+func (o AddOptions) clientOptions() *delugeclient.Options {
+	options := &delugeclient.Options{}
+	if o.DownloadPath != "" {
+		options.DownloadLocation = &o.DownloadPath
+	}
+	if o.MoveCompletedPath != "" {
+		moveCompleted := true
+		options.MoveCompleted = &moveCompleted
+		options.MoveCompletedPath = &o.MoveCompletedPath
+	}
+	if o.Paused {
+		options.AddPaused = &o.Paused
+	}
+	if o.Priority {
+		options.PrioritizeFirstLastPieces = &o.Priority
+	}
+	return options
+}
+
+// applyLabel labels a newly added torrent, creating the label if Deluge doesn't have it.
+// Labels are a convenience, so failures are logged rather than failing the add.
+func applyLabel(deluge *delugeclient.ClientV2, hash string, label string) {
+	if label == "" || hash == "" {
+		return
+	}
+
+	plugin, err := deluge.LabelPlugin()
+	if err != nil {
+		logger.WriteWarning(fmt.Sprintf("Failed to check for the Deluge Label plugin: %v", err))
+		return
+	}
+	if plugin == nil {
+		logger.WriteWarning(fmt.Sprintf("Can't label %s as %s, the Deluge Label plugin isn't enabled", hash, label))
+		return
+	}
+
+	labels, err := plugin.GetLabels()
+	if err != nil {
+		logger.WriteWarning(fmt.Sprintf("Failed to list Deluge labels: %v", err))
+		return
+	}
+	if !slices.Contains(labels, label) {
+		if err := plugin.AddLabel(label); err != nil {
+			logger.WriteWarning(fmt.Sprintf("Failed to create Deluge label %s: %v", label, err))
+			return
+		}
+	}
+	if err := plugin.SetTorrentLabel(hash, label); err != nil {
+		logger.WriteWarning(fmt.Sprintf("Failed to label %s as %s: %v", hash, label, err))
+	}
+}
@@ -0,0 +1,257 @@
+package deluge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"high-seas/src/logger"
+	"high-seas/src/utils"
+
+	delugeclient "github.com/gdm85/go-libdeluge"
+)
+
+var (
+	// poolSize is how many authenticated sessions are kept open; the client isn't safe
+	// for concurrent use, so each session serves one call at a time
+	poolSize = utils.EnvVarInt("DELUGE_POOL_SIZE", 2)
+	// healthInterval is how often idle sessions are pinged, 0 to disable
+	healthInterval = utils.EnvVarDuration("DELUGE_HEALTH_INTERVAL", time.Minute)
+	// maxReconnectBackoff caps the wait between failed connection attempts
+	maxReconnectBackoff = utils.EnvVarDuration("DELUGE_RECONNECT_MAX_BACKOFF", time.Minute)
+
+	// connectClient logs a new session in; tests swap in a fake
+	connectClient = connectToDeluge
+)
+
+const minReconnectBackoff = time.Second
+
+// ErrPoolClosed is returned for calls made after Shutdown
+var ErrPoolClosed = errors.New("deluge connection pool is closed")
+
+// session is one pool slot, connected lazily; a nil client means it needs to reconnect
+type session struct {
+	client *delugeclient.ClientV2
+}
+
+// pool hands out long-lived Deluge sessions and reconnects them with backoff
+type pool struct {
+	sessions chan *session
+	done     chan struct{}
+	wg       sync.WaitGroup
+
+	mutex       sync.Mutex
+	closed      bool
+	failures    int
+	nextAttempt time.Time
+}
+
+var (
+	sharedPool     *pool
+	sharedPoolOnce sync.Once
+)
+
+// getPool returns the shared pool, starting its health checks on first use
+func getPool() *pool {
+	sharedPoolOnce.Do(func() {
+		sharedPool = newPool(poolSize)
+		if healthInterval > 0 {
+			sharedPool.wg.Add(1)
+			go sharedPool.healthLoop(healthInterval)
+		}
+	})
+	return sharedPool
+}
+
+func newPool(size int) *pool {
+	if size < 1 {
+		size = 1
+	}
+	p := &pool{
+		sessions: make(chan *session, size),
+		done:     make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		p.sessions <- &session{}
+	}
+	return p
+}
+
+// acquire takes a free session, which may still need to connect
+func (p *pool) acquire(ctx context.Context) (*session, error) {
+	var s *session
+	select {
+	case s = <-p.sessions:
+	case <-p.done:
+		return nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if p.isClosed() {
+		p.sessions <- s
+		return nil, ErrPoolClosed
+	}
+	return s, nil
+}
+
+// use connects the session if needed and runs fn on it
+func (p *pool) use(ctx context.Context, s *session, fn func(*delugeclient.ClientV2) error) error {
+	if s.client == nil {
+		if err := p.connect(ctx, s); err != nil {
+			return err
+		}
+	}
+	return fn(s.client)
+}
+
+// release returns a session to the pool, dropping its connection if the call failed
+// for any reason other than Deluge rejecting the request
+func (p *pool) release(s *session, err error) {
+	if err != nil && s.client != nil && !isRPCError(err) {
+		logger.WriteWarning(fmt.Sprintf("Dropping Deluge connection after error: %v", err))
+		p.disconnect(s)
+	}
+
+	if p.isClosed() {
+		p.disconnect(s)
+	}
+	p.sessions <- s
+}
+
+func (p *pool) isClosed() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.closed
+}
+
+// connect logs the session in, waiting out the backoff left by earlier failures
+func (p *pool) connect(ctx context.Context, s *session) error {
+	p.mutex.Lock()
+	wait := time.Until(p.nextAttempt)
+	p.mutex.Unlock()
+
+	if wait > 0 {
+		logger.WriteInfo(fmt.Sprintf("Waiting %s before reconnecting to Deluge", wait.Round(time.Millisecond)))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-p.done:
+			timer.Stop()
+			return ErrPoolClosed
+		case <-timer.C:
+		}
+	}
+
+	client, err := connectClient()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if err != nil {
+		p.failures++
+		backoff := minReconnectBackoff << min(p.failures-1, 16)
+		if backoff > maxReconnectBackoff {
+			backoff = maxReconnectBackoff
+		}
+		p.nextAttempt = time.Now().Add(backoff)
+		return err
+	}
+
+	p.failures = 0
+	p.nextAttempt = time.Time{}
+	s.client = client
+	return nil
+}
+
+func (p *pool) disconnect(s *session) {
+	if s.client == nil {
+		return
+	}
+	if err := s.client.Close(); err != nil && !errors.Is(err, delugeclient.ErrAlreadyClosed) {
+		logger.WriteError("Failed to close Deluge connection", err)
+	}
+	s.client = nil
+}
+
+// healthLoop pings idle sessions so dead connections are replaced before they're needed
+func (p *pool) healthLoop(interval time.Duration) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.checkIdle()
+		}
+	}
+}
+
+// checkIdle pings each session that's free right now, leaving busy ones alone
+func (p *pool) checkIdle() {
+	for i := 0; i < cap(p.sessions); i++ {
+		var s *session
+		select {
+		case s = <-p.sessions:
+		default:
+			return
+		}
+
+		if s.client != nil {
+			if _, err := s.client.DaemonVersion(); err != nil {
+				logger.WriteWarning(fmt.Sprintf("Deluge health check failed, reconnecting: %v", err))
+				p.disconnect(s)
+			}
+		}
+		if s.client == nil {
+			if err := p.connect(context.Background(), s); err != nil {
+				logger.WriteError("Failed to reconnect to Deluge", err)
+			}
+		}
+		p.sessions <- s
+	}
+}
+
+// close stops the health checks and logs out every session, waiting for calls in
+// flight to finish until the context is done
+func (p *pool) close(ctx context.Context) error {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mutex.Unlock()
+
+	close(p.done)
+	p.wg.Wait()
+
+	for i := 0; i < cap(p.sessions); i++ {
+		select {
+		case s := <-p.sessions:
+			p.disconnect(s)
+		case <-ctx.Done():
+			return fmt.Errorf("closing deluge connections: %w", ctx.Err())
+		}
+	}
+	return nil
+}
+
+// isRPCError reports whether Deluge answered with an error, which leaves the connection usable
+func isRPCError(err error) bool {
+	var rpcErr delugeclient.RPCError
+	return errors.As(err, &rpcErr)
+}
+
+// Shutdown closes the pooled Deluge connections once in-flight calls finish or the
+// context is done. Later calls fail with ErrPoolClosed.
+func Shutdown(ctx context.Context) error {
+	logger.WriteInfo("Closing Deluge connections")
+	return getPool().close(ctx)
+}
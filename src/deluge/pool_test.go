@@ -0,0 +1,104 @@
+package deluge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	delugeclient "github.com/gdm85/go-libdeluge"
+)
+
+// fakeConnect makes connectClient fail with err, or hand out unconnected clients when
+// err is nil, counting the attempts
+func fakeConnect(t *testing.T, err error) *int {
+	attempts := 0
+	old := connectClient
+	connectClient = func() (*delugeclient.ClientV2, error) {
+		attempts++
+		if err != nil {
+			return nil, err
+		}
+		return delugeclient.NewV2(delugeclient.Settings{}), nil
+	}
+	t.Cleanup(func() { connectClient = old })
+	return &attempts
+}
+
+func TestPoolBacksOffBetweenFailedConnects(t *testing.T) {
+	attempts := fakeConnect(t, errors.New("connection refused"))
+	oldMax := maxReconnectBackoff
+	maxReconnectBackoff = 3 * time.Second
+	t.Cleanup(func() { maxReconnectBackoff = oldMax })
+
+	p := newPool(1)
+	s := &session{}
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		// Skip the wait so each attempt reaches connectClient
+		p.nextAttempt = time.Time{}
+		if err := p.connect(context.Background(), s); err == nil {
+			t.Fatal("expected the connect to fail")
+		}
+		if backoff := time.Until(p.nextAttempt); backoff > want || backoff < want-100*time.Millisecond {
+			t.Errorf("after %d failures expected a %s backoff, got %s", p.failures, want, backoff)
+		}
+	}
+
+	// Within the backoff the pool waits instead of trying again
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.connect(ctx, s); !errors.Is(err, context.DeadlineExceeded) || *attempts != 4 {
+		t.Errorf("expected to wait out the backoff without another attempt, got %v after %d attempts", err, *attempts)
+	}
+}
+
+func TestPoolReleaseDropsBrokenSessions(t *testing.T) {
+	fakeConnect(t, nil)
+	p := newPool(1)
+
+	tests := []struct {
+		name string
+		err  error
+		keep bool
+	}{
+		{"success", nil, true},
+		{"rpc error", delugeclient.RPCError{ExceptionType: "AddTorrentError", ExceptionMessage: "Torrent already in session"}, true},
+		{"connection error", errors.New("broken pipe"), false},
+	}
+	for _, test := range tests {
+		s, err := p.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.use(context.Background(), s, func(*delugeclient.ClientV2) error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+		p.release(s, test.err)
+		if kept := s.client != nil; kept != test.keep {
+			t.Errorf("%s: expected the session kept %v, got %v", test.name, test.keep, kept)
+		}
+	}
+}
+
+func TestPoolCloseStopsWaitingAtDeadline(t *testing.T) {
+	fakeConnect(t, nil)
+	p := newPool(1)
+
+	// A call still in flight holds the only session
+	if _, err := p.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := p.close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the close to give up at the deadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("close took %s", elapsed)
+	}
+	if _, err := p.acquire(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("expected ErrPoolClosed after close, got %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package deluge
+
+import (
+	"context"
+	"strings"
+
+	delugeclient "github.com/gdm85/go-libdeluge"
+)
+
+// Torrent is the state of one torrent in Deluge
+type Torrent struct {
+	Hash         string  `json:"hash"`
+	Name         string  `json:"name"`
+	State        string  `json:"state"`
+	Progress     float64 `json:"progress"`
+	ETA          int64   `json:"eta"` // seconds, 0 once finished or when unknown
+	DownloadRate int64   `json:"download_rate"`
+	UploadRate   int64   `json:"upload_rate"`
+	Ratio        float64 `json:"ratio"`
+	SavePath     string  `json:"save_path"`
+	TotalSize    int64   `json:"total_size"`
+	TotalDone    int64   `json:"total_done"`
+	Seeds        int64   `json:"seeds"`
+	Peers        int64   `json:"peers"`
+	Finished     bool    `json:"finished"`
+	SeedingTime  int64   `json:"seeding_time"`
+	TrackerHost  string  `json:"tracker_host,omitempty"`
+	Message      string  `json:"message,omitempty"` // the tracker's status, which explains an error state
+	Files        []File  `json:"files,omitempty"`
+}
+
+// File is one file of a torrent, its path relative to the save path
+type File struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// Status describes the Deluge daemon and its session
+type Status struct {
+	Version      string  `json:"version"`
+	DownloadRate float64 `json:"download_rate"`
+	UploadRate   float64 `json:"upload_rate"`
+	Peers        int     `json:"peers"`
+	Connectable  bool    `json:"connectable"`
+}
+
+// TorrentsStatus returns the torrents with the given hashes that Deluge still has, keyed
+// by hash. Hashes Deluge doesn't know are missing from the result.
+func TorrentsStatus(ctx context.Context, hashes []string) (map[string]Torrent, error) {
+	if len(hashes) == 0 {
+		return make(map[string]Torrent), nil
+	}
+
+	return callResult(ctx, "torrent status", func(deluge *delugeclient.ClientV2) (map[string]Torrent, error) {
+		statuses, err := deluge.TorrentsStatus(delugeclient.StateUnspecified, hashes)
+		if err != nil {
+			return nil, err
+		}
+		torrents := make(map[string]Torrent, len(statuses))
+		for hash, status := range statuses {
+			hash = strings.ToLower(hash)
+			torrents[hash] = newTorrent(hash, status)
+		}
+		return torrents, nil
+	})
+}
+
+// DaemonStatus checks Deluge is reachable and reports its version and transfer rates
+func DaemonStatus(ctx context.Context) (*Status, error) {
+	var status Status
+	err := call(ctx, "status", func(deluge *delugeclient.ClientV2) error {
+		version, err := deluge.DaemonVersion()
+		if err != nil {
+			return err
+		}
+		session, err := deluge.GetSessionStatus()
+		if err != nil {
+			return err
+		}
+
+		status = Status{
+			Version:      version,
+			DownloadRate: float64(session.PayloadDownloadRate),
+			UploadRate:   float64(session.PayloadUploadRate),
+			Peers:        int(session.NumPeers),
+			Connectable:  session.HasIncomingConnections,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func newTorrent(hash string, status *delugeclient.TorrentStatus) Torrent {
+	eta := int64(status.ETA)
+	if eta < 0 {
+		eta = 0
+	}
+	savePath := status.DownloadLocation
+	if savePath == "" {
+		savePath = status.SavePath
+	}
+
+	files := make([]File, 0, len(status.Files))
+	for _, file := range status.Files {
+		files = append(files, File{Path: file.Path, Size: file.Size})
+	}
+
+	return Torrent{
+		Hash:         hash,
+		Name:         status.Name,
+		State:        status.State,
+		Progress:     float64(status.Progress),
+		ETA:          eta,
+		DownloadRate: status.DownloadPayloadRate,
+		UploadRate:   status.UploadPayloadRate,
+		Ratio:        float64(status.Ratio),
+		SavePath:     savePath,
+		TotalSize:    status.TotalSize,
+		TotalDone:    status.TotalDone,
+		Seeds:        status.NumSeeds,
+		Peers:        status.NumPeers,
+		Finished:     status.IsFinished,
+		SeedingTime:  status.SeedingTime,
+		TrackerHost:  status.TrackerHost,
+		Message:      status.TrackerStatus,
+		Files:        files,
+	}
+}
@@ -0,0 +1,470 @@
+package downloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"high-seas/src/db"
+	"high-seas/src/deluge"
+	"high-seas/src/logger"
+	"high-seas/src/utils"
+)
+
+// pollInterval is how often tracked torrents are refreshed from Deluge, 0 to disable
+var pollInterval = utils.EnvVarDuration("DOWNLOAD_POLL_INTERVAL", 30*time.Second)
+
+// States set by high-seas; the others are Deluge's own, such as Downloading or Seeding
+const (
+	StateAdded   = "Added"   // sent to Deluge but not polled yet
+	StateRemoved = "Removed" // no longer in Deluge
+	StateStalled = "Stalled" // removed from Deluge by high-seas for making no progress
+)
+
+// States in which Deluge isn't trying to download, so no progress isn't a stall
+var waitingStates = map[string]bool{"Paused": true, "Queued": true, "Checking": true, "Allocating": true, "Moving": true}
+
+// Import statuses
+const (
+	ImportDone   = "imported"
+	ImportFailed = "failed"
+)
+
+// Plex statuses of an imported download
+const (
+	PlexScanning  = "scanning"
+	PlexAvailable = "available"
+	PlexNotFound  = "not_found"
+)
+
+// ErrNotFound is returned for a hash high-seas isn't tracking
+var ErrNotFound = errors.New("download not found")
+
+// Filter narrows the downloads returned by List
+type Filter struct {
+	MediaType string
+	TMDb      int
+	State     string
+}
+
+var (
+	// fetchStatus asks Deluge about torrents; tests swap in a fake
+	fetchStatus = deluge.TorrentsStatus
+
+	tracked      = make(map[string]*db.Download)
+	trackedMutex sync.Mutex
+	nextID       uint = 1
+	loaded       sync.Once
+)
+
+// Track starts following a torrent high-seas sent to Deluge, linked to the grab that sent it
+func Track(hash string, grab db.GrabHistory) {
+	hash = strings.ToLower(hash)
+	if hash == "" {
+		return
+	}
+	ensureLoaded()
+
+	now := time.Now()
+	trackedMutex.Lock()
+	download, ok := tracked[hash]
+	if !ok {
+		download = &db.Download{ID: nextID, CreatedAt: now, Hash: hash}
+		nextID++
+		tracked[hash] = download
+	}
+	// A release grabbed again after it was removed or stalled is followed from scratch
+	download.State = StateAdded
+	download.LastProgressAt = &now
+	download.CompletedAt = nil
+	download.ImportStatus, download.ImportedTo, download.ImportError, download.ImportedAt = "", "", "", nil
+	download.PlexStatus, download.PlexSection, download.PlexKey, download.AvailableAt = "", "", "", nil
+	// A release grabbed again is linked to the latest request for it
+	download.HistoryID = grab.ID
+	download.MediaType = grab.MediaType
+	download.Query = grab.Query
+	download.TMDb = grab.TMDb
+	download.Episodes = grab.Episodes
+	download.Title = grab.Title
+	download.Indexer = grab.Indexer
+	download.Quality = grab.Quality
+	download.UpdatedAt = now
+	record := *download
+	trackedMutex.Unlock()
+
+	save(record)
+}
+
+// List returns tracked downloads, newest first
+func List(filter Filter) []db.Download {
+	ensureLoaded()
+	trackedMutex.Lock()
+	defer trackedMutex.Unlock()
+
+	downloads := make([]db.Download, 0, len(tracked))
+	for _, download := range tracked {
+		if filter.MediaType != "" && download.MediaType != filter.MediaType {
+			continue
+		}
+		if filter.TMDb > 0 && download.TMDb != filter.TMDb {
+			continue
+		}
+		if filter.State != "" && !strings.EqualFold(download.State, filter.State) {
+			continue
+		}
+		downloads = append(downloads, *download)
+	}
+	sort.Slice(downloads, func(i, j int) bool {
+		return downloads[i].ID > downloads[j].ID
+	})
+	return downloads
+}
+
+// Get returns a tracked download, refreshed from Deluge first. When Deluge can't be
+// reached the last known state is returned along with the error.
+func Get(ctx context.Context, hash string) (db.Download, error) {
+	hash = strings.ToLower(hash)
+	ensureLoaded()
+
+	trackedMutex.Lock()
+	download, ok := tracked[hash]
+	trackedMutex.Unlock()
+	if !ok {
+		return db.Download{}, fmt.Errorf("%w: %s", ErrNotFound, hash)
+	}
+
+	err := poll(ctx, []string{hash})
+
+	trackedMutex.Lock()
+	defer trackedMutex.Unlock()
+	return *download, err
+}
+
+// ToImport returns the downloads that finished but haven't been imported yet, oldest first
+func ToImport() []db.Download {
+	ensureLoaded()
+	trackedMutex.Lock()
+	defer trackedMutex.Unlock()
+
+	var pending []db.Download
+	for _, download := range tracked {
+		if download.CompletedAt != nil && download.ImportStatus == "" && download.State != StateRemoved {
+			pending = append(pending, *download)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].ID < pending[j].ID
+	})
+	return pending
+}
+
+// MarkImported records where a download's files were imported to, or why they weren't
+func MarkImported(hash string, path string, err error) {
+	trackedMutex.Lock()
+	download, ok := tracked[strings.ToLower(hash)]
+	if !ok {
+		trackedMutex.Unlock()
+		return
+	}
+	now := time.Now()
+	download.ImportedAt = &now
+	download.ImportedTo = path
+	download.ImportStatus = ImportDone
+	download.ImportError = ""
+	if err != nil {
+		download.ImportStatus = ImportFailed
+		download.ImportError = err.Error()
+	}
+	record := *download
+	trackedMutex.Unlock()
+
+	save(record)
+}
+
+// AwaitingPlex returns the imported downloads Plex was asked to scan but hasn't shown yet
+func AwaitingPlex() []db.Download {
+	ensureLoaded()
+	trackedMutex.Lock()
+	defer trackedMutex.Unlock()
+
+	var pending []db.Download
+	for _, download := range tracked {
+		if download.PlexStatus == PlexScanning {
+			pending = append(pending, *download)
+		}
+	}
+	return pending
+}
+
+// Seeding lists imported downloads still in Deluge
+func Seeding() []db.Download {
+	ensureLoaded()
+	trackedMutex.Lock()
+	defer trackedMutex.Unlock()
+
+	var seeding []db.Download
+	for _, download := range tracked {
+		if download.ImportStatus == ImportDone && download.State != StateRemoved && download.State != StateStalled {
+			seeding = append(seeding, *download)
+		}
+	}
+	return seeding
+}
+
+// Committed returns the bytes active downloads saving in path or a folder inside it still
+// need, counting downloads whose save path isn't known yet too. An empty path counts every
+// download. Deluge only reports free space per path, so downloads in another path on the
+// same disk aren't counted.
+func Committed(path string) int64 {
+	ensureLoaded()
+	trackedMutex.Lock()
+	defer trackedMutex.Unlock()
+
+	var committed int64
+	for _, download := range tracked {
+		if download.CompletedAt != nil || download.State == StateRemoved || download.State == StateStalled {
+			continue
+		}
+		if path != "" && download.SavePath != "" && !inFolder(download.SavePath, path) {
+			continue
+		}
+		committed += int64(float64(download.TotalSize) * (100 - download.Progress) / 100)
+	}
+	return committed
+}
+
+// inFolder reports whether path is folder or inside it, so /data/movies2 isn't counted
+// against /data/movies
+func inFolder(path, folder string) bool {
+	folder = strings.TrimRight(folder, "/")
+	return path == folder || strings.HasPrefix(path, folder+"/")
+}
+
+// SetPlexStatus records how far Plex has got with an imported download, marking it
+// available once Plex has it
+func SetPlexStatus(hash string, status string, section string, key string) {
+	trackedMutex.Lock()
+	download, ok := tracked[strings.ToLower(hash)]
+	if !ok {
+		trackedMutex.Unlock()
+		return
+	}
+	download.PlexStatus = status
+	download.PlexSection = section
+	download.PlexKey = key
+	if status == PlexAvailable && download.AvailableAt == nil {
+		now := time.Now()
+		download.AvailableAt = &now
+	}
+	record := *download
+	trackedMutex.Unlock()
+
+	save(record)
+}
+
+// Stall is a download making no progress and why
+type Stall struct {
+	Download db.Download
+	Reason   string
+}
+
+// Stalled returns the unfinished downloads whose magnet hasn't resolved to a torrent
+// within metadataTimeout, or that haven't moved forward within stallTimeout. Only
+// downloads polled recently are judged, so Deluge being unreachable isn't taken for a stall.
+func Stalled(stallTimeout time.Duration, metadataTimeout time.Duration) []Stall {
+	ensureLoaded()
+	trackedMutex.Lock()
+	defer trackedMutex.Unlock()
+
+	now := time.Now()
+	var stalls []Stall
+	for _, download := range tracked {
+		if download.CompletedAt != nil || download.State == StateRemoved || download.State == StateStalled ||
+			download.CheckedAt == nil || download.LastProgressAt == nil {
+			continue
+		}
+		if pollInterval > 0 && now.Sub(*download.CheckedAt) > 2*pollInterval {
+			continue
+		}
+
+		idle := now.Sub(*download.LastProgressAt)
+		switch {
+		case download.TotalSize == 0 && metadataTimeout > 0 && idle > metadataTimeout:
+			stalls = append(stalls, Stall{*download, fmt.Sprintf("metadata not resolved after %s", metadataTimeout)})
+		case stallTimeout > 0 && idle > stallTimeout:
+			stalls = append(stalls, Stall{*download, fmt.Sprintf("no progress at %.1f%% for %s", download.Progress, stallTimeout)})
+		}
+	}
+	sort.Slice(stalls, func(i, j int) bool {
+		return stalls[i].Download.ID < stalls[j].Download.ID
+	})
+	return stalls
+}
+
+// MarkStalled records that a stalled download was removed from Deluge, so it's no
+// longer polled
+func MarkStalled(hash string, reason string) {
+	markRemoved(hash, StateStalled, reason)
+}
+
+// MarkRemoved records that high-seas removed a download from Deluge and why
+func MarkRemoved(hash string, reason string) {
+	markRemoved(hash, StateRemoved, reason)
+}
+
+func markRemoved(hash string, state string, reason string) {
+	trackedMutex.Lock()
+	download, ok := tracked[strings.ToLower(hash)]
+	if !ok {
+		trackedMutex.Unlock()
+		return
+	}
+	download.State = state
+	download.Message = reason
+	download.ETA, download.DownloadRate, download.UploadRate = 0, 0, 0
+	record := *download
+	trackedMutex.Unlock()
+
+	save(record)
+}
+
+// StartTracker refreshes every download still in Deluge on an interval, until the
+// context is done
+func StartTracker(ctx context.Context) {
+	if pollInterval <= 0 {
+		logger.WriteInfo("Download tracking disabled")
+		return
+	}
+
+	logger.WriteInfo(fmt.Sprintf("Tracking downloads every %s", pollInterval))
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.WriteInfo("Download tracking stopped")
+			return
+		case <-ticker.C:
+			if err := poll(ctx, activeHashes()); err != nil && ctx.Err() == nil {
+				logger.WriteWarning(fmt.Sprintf("Failed to refresh downloads from Deluge: %v", err))
+			}
+		}
+	}
+}
+
+// activeHashes lists the downloads Deluge still had when last asked
+func activeHashes() []string {
+	ensureLoaded()
+	trackedMutex.Lock()
+	defer trackedMutex.Unlock()
+
+	var hashes []string
+	for hash, download := range tracked {
+		if download.State != StateRemoved && download.State != StateStalled {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+// poll refreshes the given downloads from Deluge, marking the ones it no longer has as removed
+func poll(ctx context.Context, hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	torrents, err := fetchStatus(ctx, hashes)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var changed []db.Download
+	trackedMutex.Lock()
+	for _, hash := range hashes {
+		download, ok := tracked[hash]
+		if !ok {
+			continue
+		}
+		download.CheckedAt = &now
+
+		torrent, ok := torrents[hash]
+		if !ok {
+			if download.State != StateRemoved {
+				logger.WriteInfo(fmt.Sprintf("%s is no longer in Deluge", download.Title))
+				download.State = StateRemoved
+				download.ETA, download.DownloadRate, download.UploadRate = 0, 0, 0
+				changed = append(changed, *download)
+			}
+			continue
+		}
+
+		if torrent.Progress > download.Progress || waitingStates[torrent.State] || download.LastProgressAt == nil {
+			download.LastProgressAt = &now
+		}
+		if torrent.Finished && download.CompletedAt == nil {
+			logger.WriteInfo(fmt.Sprintf("Finished downloading %s for %s", download.Title, download.Query))
+			download.CompletedAt = &now
+		}
+		download.Name = torrent.Name
+		download.State = torrent.State
+		download.Progress = torrent.Progress
+		download.ETA = torrent.ETA
+		download.DownloadRate = torrent.DownloadRate
+		download.UploadRate = torrent.UploadRate
+		download.Ratio = torrent.Ratio
+		download.SeedingTime = torrent.SeedingTime
+		download.SavePath = torrent.SavePath
+		download.TotalSize = torrent.TotalSize
+		download.Message = torrent.Message
+		download.UpdatedAt = now
+		changed = append(changed, *download)
+	}
+	trackedMutex.Unlock()
+
+	for _, record := range changed {
+		save(record)
+	}
+	return nil
+}
+
+func ensureLoaded() {
+	loaded.Do(func() {
+		conn, err := db.GetDB()
+		if err != nil {
+			return
+		}
+
+		var records []db.Download
+		if err := conn.Find(&records).Error; err != nil {
+			logger.WriteError("Failed to load tracked downloads", err)
+			return
+		}
+
+		trackedMutex.Lock()
+		defer trackedMutex.Unlock()
+		for i := range records {
+			record := records[i]
+			tracked[record.Hash] = &record
+			if record.ID >= nextID {
+				nextID = record.ID + 1
+			}
+		}
+		logger.WriteInfo(fmt.Sprintf("Loaded %d tracked downloads", len(records)))
+	})
+}
+
+func save(record db.Download) {
+	conn, err := db.GetDB()
+	if err != nil {
+		return
+	}
+	if err := conn.Save(&record).Error; err != nil {
+		logger.WriteError(fmt.Sprintf("Failed to persist download %s", record.Title), err)
+	}
+}
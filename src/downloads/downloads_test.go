@@ -0,0 +1,85 @@
+package downloads
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"high-seas/src/db"
+	"high-seas/src/deluge"
+)
+
+func TestPollUpdatesTrackedDownloads(t *testing.T) {
+	torrents := map[string]deluge.Torrent{
+		"abc123": {Hash: "abc123", State: "Downloading", Progress: 42.5, ETA: 600, SavePath: "/downloads"},
+	}
+	oldFetch := fetchStatus
+	fetchStatus = func(ctx context.Context, hashes []string) (map[string]deluge.Torrent, error) {
+		return torrents, nil
+	}
+	t.Cleanup(func() { fetchStatus = oldFetch })
+
+	Track("ABC123", db.GrabHistory{ID: 7, MediaType: "show", Query: "Night Harbor", Episodes: "S01E01-E03"})
+	if got := List(Filter{State: StateAdded}); len(got) != 1 || got[0].HistoryID != 7 {
+		t.Fatalf("expected the grab to be tracked, got %+v", got)
+	}
+
+	download, err := Get(context.Background(), "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if download.State != "Downloading" || download.Progress != 42.5 || download.SavePath != "/downloads" {
+		t.Errorf("expected Deluge's state, got %+v", download)
+	}
+
+	torrents["abc123"] = deluge.Torrent{Hash: "abc123", State: "Seeding", Progress: 100, Finished: true}
+	if err := poll(context.Background(), activeHashes()); err != nil {
+		t.Fatal(err)
+	}
+	if got := List(Filter{MediaType: "show"}); len(got) != 1 || got[0].CompletedAt == nil {
+		t.Errorf("expected the download to be completed, got %+v", got)
+	}
+
+	delete(torrents, "abc123")
+	if err := poll(context.Background(), activeHashes()); err != nil {
+		t.Fatal(err)
+	}
+	if got := List(Filter{State: StateRemoved}); len(got) != 1 || len(activeHashes()) != 0 {
+		t.Errorf("expected the download to be removed and no longer polled, got %+v", got)
+	}
+
+	// Grabbing the release again follows it afresh
+	Track("abc123", db.GrabHistory{ID: 9, MediaType: "show", Query: "Night Harbor", Episodes: "S01E01-E03"})
+	if got := List(Filter{State: StateAdded}); len(got) != 1 || got[0].HistoryID != 9 || got[0].CompletedAt != nil || len(activeHashes()) != 1 {
+		t.Errorf("expected the re-grabbed download to be polled again, got %+v", got)
+	}
+
+	if _, err := Get(context.Background(), "def456"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCommittedStopsAtFolderBoundaries(t *testing.T) {
+	sizes := map[string]string{"aaa111": "/data/movies", "bbb222": "/data/movies/4k", "ccc333": "/data/movies2", "ddd444": ""}
+	for hash, savePath := range sizes {
+		Track(hash, db.GrabHistory{MediaType: "movie"})
+		trackedMutex.Lock()
+		tracked[hash].SavePath, tracked[hash].TotalSize, tracked[hash].Progress = savePath, 100, 50
+		trackedMutex.Unlock()
+	}
+	t.Cleanup(func() {
+		trackedMutex.Lock()
+		defer trackedMutex.Unlock()
+		for hash := range sizes {
+			delete(tracked, hash)
+		}
+	})
+
+	// The unknown save path counts everywhere; the sibling folder doesn't
+	if got := Committed("/data/movies/"); got != 150 {
+		t.Errorf("expected 150 bytes committed under /data/movies, got %d", got)
+	}
+	if got := Committed(""); got != 200 {
+		t.Errorf("expected 200 bytes committed overall, got %d", got)
+	}
+}
@@ -0,0 +1,136 @@
+package history
+
+import (
+	"sync"
+	"time"
+
+	"high-seas/src/db"
+	"high-seas/src/logger"
+)
+
+// Grab and import statuses
+const (
+	StatusGrabbed      = "grabbed"
+	StatusFailed       = "failed"
+	StatusImported     = "imported"
+	StatusImportFailed = "import_failed"
+	StatusAvailable    = "available"
+	StatusStalled      = "stalled"
+)
+
+// Filter narrows the entries returned by List
+type Filter struct {
+	MediaType string
+	TMDb      int
+	Limit     int
+}
+
+// Store keeps recent grab history in memory and persists it when a database is configured
+type Store struct {
+	mutex   sync.RWMutex
+	entries []db.GrabHistory
+	limit   int
+	nextID  uint
+}
+
+var (
+	globalStore *Store
+	once        sync.Once
+)
+
+// New creates a history store that keeps at most limit entries in memory
+func New(limit int) *Store {
+	return &Store{
+		entries: make([]db.GrabHistory, 0, limit),
+		limit:   limit,
+		nextID:  1,
+	}
+}
+
+// GetGlobalStore returns the global history store
+func GetGlobalStore() *Store {
+	once.Do(func() {
+		globalStore = New(500)
+	})
+	return globalStore
+}
+
+// Record stores a grab, persisting it when the database is available
+func (s *Store) Record(entry db.GrabHistory) db.GrabHistory {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	if conn, err := db.GetDB(); err == nil {
+		if err := conn.Create(&entry).Error; err != nil {
+			logger.WriteError("Failed to persist grab history", err)
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entry.ID == 0 {
+		entry.ID = s.nextID
+	}
+	if entry.ID >= s.nextID {
+		s.nextID = entry.ID + 1
+	}
+
+	s.entries = append(s.entries, entry)
+	if len(s.entries) > s.limit {
+		s.entries = s.entries[len(s.entries)-s.limit:]
+	}
+
+	return entry
+}
+
+// List returns history entries, newest first
+func (s *Store) List(filter Filter) []db.GrabHistory {
+	if conn, err := db.GetDB(); err == nil {
+		var entries []db.GrabHistory
+		query := conn.Order("created_at desc").Where(&db.GrabHistory{
+			MediaType: filter.MediaType,
+			TMDb:      filter.TMDb,
+		})
+		if filter.Limit > 0 {
+			query = query.Limit(filter.Limit)
+		}
+
+		err = query.Find(&entries).Error
+		if err == nil {
+			return entries
+		}
+		logger.WriteError("Failed to load grab history, using in-memory entries", err)
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entries := make([]db.GrabHistory, 0)
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		entry := s.entries[i]
+		if filter.MediaType != "" && entry.MediaType != filter.MediaType {
+			continue
+		}
+		if filter.TMDb > 0 && entry.TMDb != filter.TMDb {
+			continue
+		}
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			break
+		}
+	}
+
+	return entries
+}
+
+// Record stores a grab in the global history store
+func Record(entry db.GrabHistory) db.GrabHistory {
+	return GetGlobalStore().Record(entry)
+}
+
+// List returns entries from the global history store
+func List(filter Filter) []db.GrabHistory {
+	return GetGlobalStore().List(filter)
+}
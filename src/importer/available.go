@@ -0,0 +1,152 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"high-seas/src/db"
+	"high-seas/src/downloads"
+	"high-seas/src/history"
+	"high-seas/src/jackett"
+	"high-seas/src/logger"
+	"high-seas/src/notify"
+	"high-seas/src/plex"
+	"high-seas/src/utils"
+)
+
+var (
+	// confirmTimeout is how long to wait for Plex to show an import before giving up
+	confirmTimeout = utils.EnvVarDuration("PLEX_CONFIRM_TIMEOUT", 30*time.Minute)
+	// Plex may see the library under another path than high-seas, e.g. in containers
+	plexRemotePath = utils.EnvVar("PLEX_REMOTE_PATH", "")
+	plexLocalPath  = utils.EnvVar("PLEX_LOCAL_PATH", "")
+)
+
+// requestScan asks Plex to scan the folder an import went to, so it shows up without
+// waiting for Plex's own schedule
+func requestScan(ctx context.Context, download db.Download, folder string) {
+	if !plex.Configured() {
+		return
+	}
+
+	section, err := plex.ScanFolder(ctx, plexKind(download.MediaType), plexPath(folder))
+	if err != nil {
+		logger.WriteWarning(fmt.Sprintf("Failed to ask Plex to scan %s: %v", folder, err))
+		return
+	}
+	logger.WriteInfo(fmt.Sprintf("Asked Plex to scan %s in %s", folder, section.Title))
+	downloads.SetPlexStatus(download.Hash, downloads.PlexScanning, section.Key, "")
+}
+
+// confirmAvailable checks whether Plex has picked up the imports it was asked to scan,
+// marking them available and sending notifications once it has
+func confirmAvailable(ctx context.Context) {
+	for _, download := range downloads.AwaitingPlex() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		key, err := findInPlex(ctx, download)
+		if err != nil {
+			logger.WriteWarning(fmt.Sprintf("Failed to check Plex for %s: %v", download.Title, err))
+			continue
+		}
+		if key != "" {
+			markAvailable(ctx, download, key)
+			continue
+		}
+		if download.ImportedAt != nil && time.Since(*download.ImportedAt) > confirmTimeout {
+			logger.WriteWarning(fmt.Sprintf("Plex still doesn't show %s %s after importing it", download.Query, download.Episodes))
+			downloads.SetPlexStatus(download.Hash, downloads.PlexNotFound, download.PlexSection, "")
+		}
+	}
+}
+
+// findInPlex returns the Plex rating key of an imported download by its TMDb GUID, and
+// for shows only once Plex has every episode the grab covered
+func findInPlex(ctx context.Context, download db.Download) (string, error) {
+	if download.TMDb <= 0 {
+		return "", fmt.Errorf("%s has no TMDb ID to look up", download.Query)
+	}
+
+	section := plex.Section{Key: download.PlexSection}
+	var title string
+	if plexKind(download.MediaType) == "movie" {
+		title, _ = movieTitle(download)
+	} else {
+		title = showTitle(download)
+	}
+
+	item, err := plex.FindByTMDb(ctx, section, download.TMDb, title)
+	if err != nil || item == nil {
+		return "", err
+	}
+
+	wanted := parseEpisodeList(download.Episodes)
+	if plexKind(download.MediaType) == "movie" || len(wanted) == 0 {
+		return item.RatingKey, nil
+	}
+	episodes, err := plex.Episodes(ctx, item.RatingKey)
+	if err != nil {
+		return "", err
+	}
+	have := make(map[episode]bool, len(episodes))
+	for _, e := range episodes {
+		have[episode{e.Season, e.Episode}] = true
+	}
+	for _, e := range wanted {
+		if !have[e] {
+			return "", nil
+		}
+	}
+	return item.RatingKey, nil
+}
+
+func markAvailable(ctx context.Context, download db.Download, key string) {
+	logger.WriteInfo(fmt.Sprintf("%s is available in Plex", download.Title))
+	downloads.SetPlexStatus(download.Hash, downloads.PlexAvailable, download.PlexSection, key)
+
+	history.Record(db.GrabHistory{
+		MediaType: download.MediaType,
+		Query:     download.Query,
+		TMDb:      download.TMDb,
+		Title:     download.Title,
+		InfoHash:  download.Hash,
+		Status:    history.StatusAvailable,
+		Episodes:  download.Episodes,
+		Path:      download.ImportedTo,
+	})
+
+	message := fmt.Sprintf("%s is available in Plex", download.Query)
+	if download.Episodes != "" {
+		message = fmt.Sprintf("%s %s is available in Plex", download.Query, download.Episodes)
+	}
+	notify.Send(ctx, notify.Event{
+		Event:     notify.EventAvailable,
+		MediaType: download.MediaType,
+		Query:     download.Query,
+		TMDb:      download.TMDb,
+		Title:     download.Title,
+		Episodes:  download.Episodes,
+		Path:      download.ImportedTo,
+		Message:   message,
+	})
+}
+
+// plexKind is the Plex section type a media type goes in
+func plexKind(mediaType string) string {
+	if mediaType == jackett.MediaMovie || mediaType == jackett.MediaAnimeMovie {
+		return "movie"
+	}
+	return "show"
+}
+
+// plexPath maps a library folder to where Plex sees it
+func plexPath(folder string) string {
+	if rest, ok := underRoot(folder, plexLocalPath); ok {
+		return strings.TrimRight(plexRemotePath, "/") + rest
+	}
+	return folder
+}
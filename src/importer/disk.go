@@ -0,0 +1,36 @@
+package importer
+
+import (
+	"high-seas/src/jackett"
+)
+
+// LibraryUsage is the disk usage of one media type's library root
+type LibraryUsage struct {
+	MediaType string `json:"media_type"`
+	Path      string `json:"path"`
+	Total     uint64 `json:"total"`
+	Free      uint64 `json:"free"`
+	Used      uint64 `json:"used"`
+	Error     string `json:"error,omitempty"`
+}
+
+// LibraryUsages reports the disk usage of each configured library root
+func LibraryUsages() []LibraryUsage {
+	var usages []LibraryUsage
+	for _, mediaType := range []string{jackett.MediaMovie, jackett.MediaShow, jackett.MediaAnimeMovie, jackett.MediaAnimeShow} {
+		root := libraryRoot(mediaType)
+		if root == "" {
+			continue
+		}
+
+		usage := LibraryUsage{MediaType: mediaType, Path: root}
+		total, free, used, err := diskUsage(root)
+		if err != nil {
+			usage.Error = err.Error()
+		} else {
+			usage.Total, usage.Free, usage.Used = total, free, used
+		}
+		usages = append(usages, usage)
+	}
+	return usages
+}
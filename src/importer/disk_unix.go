@@ -0,0 +1,18 @@
+//go:build !windows
+
+package importer
+
+import "syscall"
+
+// diskUsage returns the size of the filesystem holding path, the space free to
+// unprivileged users and the space in use
+func diskUsage(path string) (total, free, used uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, 0, err
+	}
+	total = stat.Blocks * uint64(stat.Bsize)
+	free = stat.Bavail * uint64(stat.Bsize)
+	used = total - stat.Bfree*uint64(stat.Bsize)
+	return total, free, used, nil
+}
@@ -0,0 +1,333 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"high-seas/src/db"
+	"high-seas/src/deluge"
+	"high-seas/src/downloads"
+	"high-seas/src/history"
+	"high-seas/src/jackett"
+	"high-seas/src/logger"
+	"high-seas/src/tmdb"
+	"high-seas/src/utils"
+)
+
+var (
+	// Library roots per media type; anime goes with movies and shows unless set apart
+	movieRoot      = utils.EnvVar("LIBRARY_MOVIES_PATH", "")
+	showRoot       = utils.EnvVar("LIBRARY_SHOWS_PATH", "")
+	animeMovieRoot = utils.EnvVar("LIBRARY_ANIME_MOVIES_PATH", movieRoot)
+	animeShowRoot  = utils.EnvVar("LIBRARY_ANIME_SHOWS_PATH", showRoot)
+
+	// importMode is hardlink, falling back to a copy across file systems, or copy
+	importMode = utils.EnvVar("IMPORT_MODE", "hardlink")
+	// importInterval is how often finished downloads are looked for, 0 to disable
+	importInterval = utils.EnvVarDuration("IMPORT_INTERVAL", time.Minute)
+	// Deluge may see its downloads under another path than high-seas, e.g. in containers
+	remotePath = utils.EnvVar("IMPORT_REMOTE_PATH", "")
+	localPath  = utils.EnvVar("IMPORT_LOCAL_PATH", "")
+)
+
+var (
+	// ErrNoLibrary is returned when no library folder is configured for a media type
+	ErrNoLibrary = errors.New("no library folder configured")
+	// ErrNotComplete is returned when importing a download that hasn't finished
+	ErrNotComplete = errors.New("download hasn't finished")
+	// ErrImportFailed wraps the reason a download's files couldn't be placed in the library
+	ErrImportFailed = errors.New("import failed")
+)
+
+// Lookups the importer makes; tests swap in fakes
+var (
+	fetchTorrents = deluge.TorrentsStatus
+	getMovie      = tmdb.GetMovie
+	getShow       = tmdb.GetShow
+	getSeason     = tmdb.GetSeason
+)
+
+// StartImporter imports finished downloads into the library on an interval, until the
+// context is done
+func StartImporter(ctx context.Context) {
+	if importInterval <= 0 || (movieRoot == "" && showRoot == "" && animeMovieRoot == "" && animeShowRoot == "") {
+		logger.WriteInfo("Library import disabled")
+		return
+	}
+
+	logger.WriteInfo(fmt.Sprintf("Importing finished downloads every %s", importInterval))
+	ticker := time.NewTicker(importInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.WriteInfo("Library import stopped")
+			return
+		case <-ticker.C:
+			importFinished(ctx)
+			confirmAvailable(ctx)
+		}
+	}
+}
+
+// importFinished imports every download that finished since the last pass. Failed imports
+// are recorded and left for ImportNow rather than tried again each pass.
+func importFinished(ctx context.Context) {
+	pending := downloads.ToImport()
+	if len(pending) == 0 {
+		return
+	}
+
+	hashes := make([]string, 0, len(pending))
+	for _, download := range pending {
+		hashes = append(hashes, download.Hash)
+	}
+	torrents, err := fetchTorrents(ctx, hashes)
+	if err != nil {
+		logger.WriteWarning(fmt.Sprintf("Failed to list finished downloads from Deluge: %v", err))
+		return
+	}
+
+	for _, download := range pending {
+		// The tracker notices torrents removed from Deluge
+		if torrent, ok := torrents[download.Hash]; ok {
+			importDownload(ctx, download, torrent)
+		}
+	}
+}
+
+// ImportNow imports a finished download straight away, including one that failed before,
+// and returns the library folder it went to
+func ImportNow(ctx context.Context, hash string) (string, error) {
+	download, err := downloads.Get(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+	if download.CompletedAt == nil {
+		return "", fmt.Errorf("%w: %s is %.0f%% done", ErrNotComplete, download.Title, download.Progress)
+	}
+
+	torrents, err := fetchTorrents(ctx, []string{download.Hash})
+	if err != nil {
+		return "", err
+	}
+	torrent, ok := torrents[download.Hash]
+	if !ok {
+		return "", fmt.Errorf("%s is no longer in Deluge", download.Title)
+	}
+
+	folder, err := importDownload(ctx, download, torrent)
+	if err != nil {
+		return folder, fmt.Errorf("%w: %w", ErrImportFailed, err)
+	}
+	return folder, nil
+}
+
+// importDownload places the download's video files in the library, records the
+// outcome on the download and in history, and asks Plex to scan them
+func importDownload(ctx context.Context, download db.Download, torrent deluge.Torrent) (string, error) {
+	folder, err := importFiles(download, torrent)
+
+	entry := db.GrabHistory{
+		MediaType: download.MediaType,
+		Query:     download.Query,
+		TMDb:      download.TMDb,
+		Title:     download.Title,
+		InfoHash:  download.Hash,
+		Status:    history.StatusImported,
+		Episodes:  download.Episodes,
+		Path:      folder,
+	}
+	if err != nil {
+		logger.WriteError(fmt.Sprintf("Failed to import %s", download.Title), err)
+		entry.Status = history.StatusImportFailed
+		entry.Error = err.Error()
+	} else {
+		logger.WriteInfo(fmt.Sprintf("Imported %s to %s", download.Title, folder))
+	}
+	history.Record(entry)
+	downloads.MarkImported(download.Hash, folder, err)
+	if err == nil {
+		requestScan(ctx, download, folder)
+	}
+	return folder, err
+}
+
+// importFiles works out the library names for the download's files and links or
+// copies them there, returning the movie or show folder
+func importFiles(download db.Download, torrent deluge.Torrent) (string, error) {
+	root := libraryRoot(download.MediaType)
+	if root == "" {
+		return "", fmt.Errorf("%w for %s", ErrNoLibrary, download.MediaType)
+	}
+	videos := videoFiles(torrent.Files)
+	if len(videos) == 0 {
+		return "", fmt.Errorf("no video files in %s", torrent.Name)
+	}
+	base := localSavePath(torrent.SavePath)
+
+	var placements []placement
+	var folder string
+	switch download.MediaType {
+	case jackett.MediaMovie, jackett.MediaAnimeMovie:
+		title, year := movieTitle(download)
+		placements = planMovie(root, base, title, year, videos)
+		folder = filepath.Join(root, movieFolder(title, year))
+	case jackett.MediaShow, jackett.MediaAnimeShow:
+		show := showTitle(download)
+		var err error
+		placements, err = planEpisodes(root, base, show, download.Episodes, videos, episodeTitles(download.TMDb))
+		if err != nil {
+			return "", err
+		}
+		folder = filepath.Join(root, safeName(show))
+	default:
+		return "", fmt.Errorf("can't import media type %q", download.MediaType)
+	}
+
+	for _, placement := range placements {
+		if err := place(placement.source, placement.target); err != nil {
+			return folder, err
+		}
+	}
+	return folder, nil
+}
+
+func libraryRoot(mediaType string) string {
+	switch mediaType {
+	case jackett.MediaMovie:
+		return movieRoot
+	case jackett.MediaShow:
+		return showRoot
+	case jackett.MediaAnimeMovie:
+		return animeMovieRoot
+	case jackett.MediaAnimeShow:
+		return animeShowRoot
+	}
+	return ""
+}
+
+// localSavePath maps Deluge's save path to where high-seas sees the same folder
+func localSavePath(savePath string) string {
+	if rest, ok := underRoot(savePath, remotePath); ok {
+		return filepath.Join(localPath, rest)
+	}
+	return savePath
+}
+
+// underRoot returns what follows root in path when path is root or a folder inside it,
+// so a sibling such as /downloads2 doesn't match /downloads
+func underRoot(path, root string) (string, bool) {
+	if root == "" {
+		return "", false
+	}
+	root = strings.TrimRight(root, "/")
+	if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+		return strings.TrimPrefix(path, root), true
+	}
+	return "", false
+}
+
+// movieTitle returns TMDb's title and year, or the requested title when TMDb can't say
+func movieTitle(download db.Download) (string, int) {
+	if download.TMDb > 0 {
+		info, err := getMovie(download.TMDb)
+		if err == nil {
+			return info.Title, info.Year()
+		}
+		logger.WriteWarning(fmt.Sprintf("Naming %s without TMDb: %v", download.Query, err))
+	}
+	return download.Query, 0
+}
+
+// showTitle returns TMDb's name for the show, or the requested title when TMDb can't say
+func showTitle(download db.Download) string {
+	if download.TMDb > 0 {
+		info, err := getShow(download.TMDb)
+		if err == nil {
+			return info.Name
+		}
+		logger.WriteWarning(fmt.Sprintf("Naming %s without TMDb: %v", download.Query, err))
+	}
+	return download.Query
+}
+
+// episodeTitles looks episode titles up by season, leaving them out when TMDb doesn't know
+func episodeTitles(tmdbID int) func(episode) string {
+	seasons := make(map[int]map[int]string)
+	return func(e episode) string {
+		if tmdbID <= 0 {
+			return ""
+		}
+		titles, ok := seasons[e.season]
+		if !ok {
+			titles = make(map[int]string)
+			if info, err := getSeason(tmdbID, e.season); err == nil {
+				for _, summary := range info.Episodes {
+					titles[summary.EpisodeNumber] = summary.Name
+				}
+			}
+			seasons[e.season] = titles
+		}
+		return titles[e.number]
+	}
+}
+
+// place links or copies a file into the library, leaving the original for seeding.
+// A file already there with the same size is taken as imported before.
+func place(source string, target string) error {
+	sourceInfo, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if targetInfo, err := os.Stat(target); err == nil {
+		if os.SameFile(sourceInfo, targetInfo) || targetInfo.Size() == sourceInfo.Size() {
+			return nil
+		}
+		return fmt.Errorf("%s already exists", target)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if importMode != "copy" {
+		err := os.Link(source, target)
+		if err == nil {
+			return nil
+		}
+		logger.WriteWarning(fmt.Sprintf("Can't hardlink %s, copying instead: %v", source, err))
+	}
+	return copyFile(source, target)
+}
+
+// copyFile copies through a temporary file so a half-copied file never shows up in Plex
+func copyFile(source string, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	partial := target + ".partial"
+	out, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(partial)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(partial)
+		return err
+	}
+	return os.Rename(partial, target)
+}
@@ -0,0 +1,152 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"high-seas/src/db"
+	"high-seas/src/deluge"
+	"high-seas/src/tmdb"
+)
+
+func TestParseEpisodes(t *testing.T) {
+	cases := map[string][]episode{
+		"Night.Harbor.S01E05.1080p.WEB.mkv":     {{1, 5}},
+		"Night_Harbor_S02E01-E02_720p.mkv":      {{2, 1}, {2, 2}},
+		"Night Harbor - 1x07 - Pilot.mp4":       {{1, 7}},
+		"[Group] Night Harbor - 05 [1080p].mkv": nil,
+	}
+	for name, want := range cases {
+		got := parseEpisodes(name)
+		if len(got) != len(want) {
+			t.Errorf("%s: expected %v, got %v", name, want, got)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: expected %v, got %v", name, want, got)
+			}
+		}
+	}
+}
+
+func TestPathMappingStopsAtFolderBoundaries(t *testing.T) {
+	oldRemote, oldLocal, oldPlexRemote, oldPlexLocal := remotePath, localPath, plexRemotePath, plexLocalPath
+	remotePath, localPath, plexRemotePath, plexLocalPath = "/downloads", "/mnt/downloads", "/data/", "/library/"
+	t.Cleanup(func() {
+		remotePath, localPath, plexRemotePath, plexLocalPath = oldRemote, oldLocal, oldPlexRemote, oldPlexLocal
+	})
+
+	saves := map[string]string{
+		"/downloads":            "/mnt/downloads",
+		"/downloads/tv/episode": "/mnt/downloads/tv/episode",
+		"/downloads2/x":         "/downloads2/x",
+		"/elsewhere":            "/elsewhere",
+	}
+	for save, want := range saves {
+		if got := localSavePath(save); got != want {
+			t.Errorf("localSavePath(%q): expected %q, got %q", save, want, got)
+		}
+	}
+
+	folders := map[string]string{
+		"/library/Movies/Quiet Orbit (2017)": "/data/Movies/Quiet Orbit (2017)",
+		"/library2/Movies":                   "/library2/Movies",
+	}
+	for folder, want := range folders {
+		if got := plexPath(folder); got != want {
+			t.Errorf("plexPath(%q): expected %q, got %q", folder, want, got)
+		}
+	}
+}
+
+func TestImportShowLinksEpisodesIntoLibrary(t *testing.T) {
+	downloadsDir, library := t.TempDir(), t.TempDir()
+	files := []deluge.File{
+		{Path: "Night.Harbor.S01.1080p/Night.Harbor.S01E02.1080p.mkv", Size: 4},
+		{Path: "Night.Harbor.S01.1080p/Night.Harbor.S01E01.1080p.mkv", Size: 4},
+		{Path: "Night.Harbor.S01.1080p/Sample/night.harbor.s01e01.sample.mkv", Size: 1},
+		{Path: "Night.Harbor.S01.1080p/Night.Harbor.S01.nfo", Size: 1},
+	}
+	for _, file := range files {
+		path := filepath.Join(downloadsDir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, file.Size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldRoot, oldShow, oldSeason := showRoot, getShow, getSeason
+	showRoot = library
+	getShow = func(int) (*tmdb.ShowInfo, error) { return &tmdb.ShowInfo{Name: "Night Harbor"}, nil }
+	getSeason = func(int, int) (*tmdb.SeasonInfo, error) {
+		return &tmdb.SeasonInfo{Episodes: []tmdb.EpisodeSummary{{EpisodeNumber: 1, Name: "Low Tide: Part 1"}}}, nil
+	}
+	t.Cleanup(func() { showRoot, getShow, getSeason = oldRoot, oldShow, oldSeason })
+
+	now := time.Now()
+	download := db.Download{Hash: "abc123", MediaType: "show", Query: "Night Harbor", TMDb: 42, CompletedAt: &now}
+	folder, err := importFiles(download, deluge.Torrent{SavePath: downloadsDir, Files: files})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folder != filepath.Join(library, "Night Harbor") {
+		t.Errorf("unexpected show folder %s", folder)
+	}
+
+	for _, name := range []string{"Night Harbor - S01E01 - Low Tide - Part 1.mkv", "Night Harbor - S01E02.mkv"} {
+		if _, err := os.Stat(filepath.Join(folder, "Season 01", name)); err != nil {
+			t.Errorf("expected %s in the library: %v", name, err)
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Join(folder, "Season 01"))
+	if len(entries) != 2 {
+		t.Errorf("expected only the two episodes, got %d files", len(entries))
+	}
+	// The seeding copy stays where Deluge has it
+	if _, err := os.Stat(filepath.Join(downloadsDir, filepath.FromSlash(files[0].Path))); err != nil {
+		t.Errorf("the download should be left in place: %v", err)
+	}
+}
+
+func TestPlanEpisodesMatchesUnnumberedFilesToTheGrab(t *testing.T) {
+	videos := []deluge.File{{Path: "Show/[Group] Show - 13.mkv"}, {Path: "Show/[Group] Show - 14.mkv"}}
+	noTitles := func(episode) string { return "" }
+
+	placements, err := planEpisodes("/library", "/downloads", "Show", "S02E01-E02", videos, noTitles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("/library", "Show", "Season 02", "Show - S02E02.mkv"); placements[1].target != want {
+		t.Errorf("expected %s, got %s", want, placements[1].target)
+	}
+
+	if _, err := planEpisodes("/library", "/downloads", "Show", "S02E01", videos, noTitles); err == nil {
+		t.Error("expected an error when the grab doesn't say which episodes the files are")
+	}
+}
+
+func TestPlanEpisodesSkipsExtrasInSeasonPacks(t *testing.T) {
+	videos := []deluge.File{
+		{Path: "Night.Harbor.S01/Night.Harbor.S01E01.mkv"},
+		{Path: "Night.Harbor.S01/Featurette.mkv"},
+		{Path: "Night.Harbor.S01/Night.Harbor.S01E02.mkv"},
+		{Path: "Night.Harbor.S01/Behind the Scenes.mkv"},
+	}
+	noTitles := func(episode) string { return "" }
+
+	placements, err := planEpisodes("/library", "/downloads", "Night Harbor", "S01E01-E02", videos, noTitles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(placements) != 2 {
+		t.Fatalf("expected the two episodes without the extras, got %+v", placements)
+	}
+	if want := filepath.Join("/library", "Night Harbor", "Season 01", "Night Harbor - S01E02.mkv"); placements[1].target != want {
+		t.Errorf("expected %s, got %s", want, placements[1].target)
+	}
+}
@@ -0,0 +1,177 @@
+package importer
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"high-seas/src/deluge"
+	"high-seas/src/logger"
+)
+
+var (
+	videoExtensions = map[string]bool{
+		".mkv": true, ".mp4": true, ".m4v": true, ".avi": true, ".mov": true, ".wmv": true, ".ts": true, ".webm": true,
+	}
+	samplePattern = regexp.MustCompile(`(?i)\bsample\b`)
+	// S01E05, S01E05E06, S01E05-E06 and S01E05-06, or 1x05
+	episodePattern      = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])s(\d{1,2})[ ._]?e(\d{1,3})(?:-?e(\d{1,3})|-(\d{1,3})\b)?`)
+	crossEpisodePattern = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(\d{1,2})x(\d{2,3})(?:[^a-z0-9]|$)`)
+	// One entry of a grab's episode list, such as S01E01-E03
+	episodeRangePattern = regexp.MustCompile(`^S(\d+)E(\d+)(?:-E(\d+))?$`)
+	unsafeCharacters    = strings.NewReplacer(": ", " - ", ":", "-", "/", "-", "\\", "-", "<", "", ">", "", "\"", "", "|", "", "?", "", "*", "")
+)
+
+// episode is a season and episode number
+type episode struct {
+	season, number int
+}
+
+// placement is one file to put into the library
+type placement struct {
+	source, target string
+}
+
+// videoFiles returns the torrent's video files, leaving out samples, largest first
+func videoFiles(files []deluge.File) []deluge.File {
+	var videos []deluge.File
+	for _, file := range files {
+		if !videoExtensions[strings.ToLower(path.Ext(file.Path))] || samplePattern.MatchString(path.Base(file.Path)) {
+			continue
+		}
+		videos = append(videos, file)
+	}
+	sort.SliceStable(videos, func(i, j int) bool {
+		return videos[i].Size > videos[j].Size
+	})
+	return videos
+}
+
+// movieFolder names a movie the way Plex expects, e.g. "The Quiet Orbit (2017)"
+func movieFolder(title string, year int) string {
+	name := safeName(title)
+	if year > 0 {
+		name = fmt.Sprintf("%s (%d)", name, year)
+	}
+	return name
+}
+
+// planMovie places a movie's main video file as <root>/<Title (Year)>/<Title (Year)>.ext
+func planMovie(root string, base string, title string, year int, videos []deluge.File) []placement {
+	name := movieFolder(title, year)
+	main := videos[0]
+	return []placement{{
+		source: filepath.Join(base, filepath.FromSlash(main.Path)),
+		target: filepath.Join(root, name, name+strings.ToLower(path.Ext(main.Path))),
+	}}
+}
+
+// planEpisodes places each episode as <root>/<Show>/Season 01/<Show> - S01E01 - <Title>.ext.
+// When no file has an episode number in its name, such as anime numbered absolutely, the
+// files are matched in order to the episodes the grab covered when the counts agree.
+// Otherwise unnumbered files, such as featurettes in a season pack, are left out.
+func planEpisodes(root string, base string, show string, requested string, videos []deluge.File, episodeTitle func(episode) string) ([]placement, error) {
+	sorted := append([]deluge.File(nil), videos...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	parsed := make([][]episode, len(sorted))
+	unnumbered := 0
+	for i, file := range sorted {
+		parsed[i] = parseEpisodes(path.Base(file.Path))
+		if len(parsed[i]) == 0 {
+			unnumbered++
+		}
+	}
+	if unnumbered == len(sorted) {
+		covered := parseEpisodeList(requested)
+		if len(covered) != len(sorted) {
+			return nil, fmt.Errorf("can't tell which episodes %d files are", len(sorted))
+		}
+		for i := range sorted {
+			parsed[i] = []episode{covered[i]}
+		}
+	}
+
+	folder := safeName(show)
+	var placements []placement
+	for i, file := range sorted {
+		episodes := parsed[i]
+		if len(episodes) == 0 {
+			logger.WriteInfo(fmt.Sprintf("Skipping %s, which has no episode number", file.Path))
+			continue
+		}
+		first := episodes[0]
+		number := fmt.Sprintf("S%02dE%02d", first.season, first.number)
+		if last := episodes[len(episodes)-1]; len(episodes) > 1 {
+			number += fmt.Sprintf("-E%02d", last.number)
+		}
+
+		name := folder + " - " + number
+		if title := safeName(episodeTitle(first)); title != "" {
+			name += " - " + title
+		}
+		placements = append(placements, placement{
+			source: filepath.Join(base, filepath.FromSlash(file.Path)),
+			target: filepath.Join(root, folder, fmt.Sprintf("Season %02d", first.season), name+strings.ToLower(path.Ext(file.Path))),
+		})
+	}
+	return placements, nil
+}
+
+// parseEpisodes reads the episode numbers from a file name, more than one for
+// multi-episode files
+func parseEpisodes(name string) []episode {
+	if match := episodePattern.FindStringSubmatch(name); match != nil {
+		season, _ := strconv.Atoi(match[1])
+		first, _ := strconv.Atoi(match[2])
+		last := first
+		for _, end := range match[3:] {
+			if end != "" {
+				last, _ = strconv.Atoi(end)
+			}
+		}
+		var episodes []episode
+		for number := first; number <= last && number-first < 50; number++ {
+			episodes = append(episodes, episode{season, number})
+		}
+		return episodes
+	}
+	if match := crossEpisodePattern.FindStringSubmatch(name); match != nil {
+		season, _ := strconv.Atoi(match[1])
+		number, _ := strconv.Atoi(match[2])
+		return []episode{{season, number}}
+	}
+	return nil
+}
+
+// parseEpisodeList reads a grab's episode list, e.g. "S01E01-E03 S02E01", in order
+func parseEpisodeList(list string) []episode {
+	var episodes []episode
+	for _, entry := range strings.Fields(list) {
+		match := episodeRangePattern.FindStringSubmatch(entry)
+		if match == nil {
+			continue
+		}
+		season, _ := strconv.Atoi(match[1])
+		first, _ := strconv.Atoi(match[2])
+		last := first
+		if match[3] != "" {
+			last, _ = strconv.Atoi(match[3])
+		}
+		for number := first; number <= last; number++ {
+			episodes = append(episodes, episode{season, number})
+		}
+	}
+	return episodes
+}
+
+// safeName drops the characters file systems and Plex don't allow in a name
+func safeName(name string) string {
+	return strings.Trim(strings.TrimSpace(unsafeCharacters.Replace(name)), ".")
+}
@@ -0,0 +1,81 @@
+package jackett
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	jackett "github.com/webtor-io/go-jackett"
+	"high-seas/src/db"
+	"high-seas/src/logger"
+)
+
+// Blocked releases by lowercased info hash and title; titles catch the same release
+// listed without a hash or on another indexer
+var (
+	blockedHashes  = make(map[string]bool)
+	blockedTitles  = make(map[string]bool)
+	blocklistMutex sync.Mutex
+	blocklistOnce  sync.Once
+)
+
+// blockRelease keeps a release from being grabbed again
+func blockRelease(entry db.BlockedRelease) {
+	ensureBlocklistLoaded()
+
+	blocklistMutex.Lock()
+	addBlocked(entry)
+	blocklistMutex.Unlock()
+	logger.WriteInfo(fmt.Sprintf("Blocklisted %s for %s: %s", entry.Title, entry.Query, entry.Reason))
+
+	conn, err := db.GetDB()
+	if err != nil {
+		return
+	}
+	if err := conn.Create(&entry).Error; err != nil {
+		logger.WriteError("Failed to persist blocklisted release", err)
+	}
+}
+
+// isBlocked reports whether a search result is a blocklisted release
+func isBlocked(result *jackett.Result) bool {
+	ensureBlocklistLoaded()
+
+	blocklistMutex.Lock()
+	defer blocklistMutex.Unlock()
+	if result.InfoHash != "" && blockedHashes[strings.ToLower(result.InfoHash)] {
+		return true
+	}
+	return blockedTitles[strings.ToLower(strings.TrimSpace(result.Title))]
+}
+
+// addBlocked indexes a blocked release; callers hold blocklistMutex
+func addBlocked(entry db.BlockedRelease) {
+	if entry.InfoHash != "" {
+		blockedHashes[strings.ToLower(entry.InfoHash)] = true
+	}
+	if title := strings.ToLower(strings.TrimSpace(entry.Title)); title != "" {
+		blockedTitles[title] = true
+	}
+}
+
+func ensureBlocklistLoaded() {
+	blocklistOnce.Do(func() {
+		conn, err := db.GetDB()
+		if err != nil {
+			return
+		}
+
+		var entries []db.BlockedRelease
+		if err := conn.Find(&entries).Error; err != nil {
+			logger.WriteError("Failed to load the blocklist", err)
+			return
+		}
+
+		blocklistMutex.Lock()
+		defer blocklistMutex.Unlock()
+		for _, entry := range entries {
+			addBlocked(entry)
+		}
+	})
+}
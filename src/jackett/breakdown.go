@@ -0,0 +1,105 @@
+package jackett
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Media types attached to candidates and grab history
+const (
+	MediaMovie      = "movie"
+	MediaShow       = "show"
+	MediaAnimeMovie = "anime_movie"
+	MediaAnimeShow  = "anime_show"
+)
+
+// ScoreComponent is a single weighted contribution to a candidate's score
+type ScoreComponent struct {
+	Name         string  `json:"name"`
+	Value        float64 `json:"value"`
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"`
+	Detail       string  `json:"detail,omitempty"`
+}
+
+// RuleHit records a title rule that adjusted a candidate's score
+type RuleHit struct {
+	Rule       string  `json:"rule"`
+	Adjustment float64 `json:"adjustment"`
+}
+
+// ScoreBreakdown explains how a candidate's score was reached and why it
+// was accepted or rejected
+type ScoreBreakdown struct {
+	Total      float64          `json:"total"`
+	Threshold  float64          `json:"threshold"`
+	Accepted   bool             `json:"accepted"`
+	Rejection  string           `json:"rejection,omitempty"`
+	Components []ScoreComponent `json:"components"`
+	RuleHits   []RuleHit        `json:"rule_hits,omitempty"`
+}
+
+func newScoreBreakdown(threshold float64) *ScoreBreakdown {
+	return &ScoreBreakdown{
+		Threshold:  threshold,
+		Components: []ScoreComponent{},
+	}
+}
+
+// addComponent adds a weighted component and updates the running total
+func (b *ScoreBreakdown) addComponent(name string, value, weight float64, detail string) {
+	contribution := value * weight
+	b.Components = append(b.Components, ScoreComponent{
+		Name:         name,
+		Value:        value,
+		Weight:       weight,
+		Contribution: contribution,
+		Detail:       detail,
+	})
+	b.Total += contribution
+}
+
+func (b *ScoreBreakdown) addRuleHit(rule string, adjustment float64) {
+	b.RuleHits = append(b.RuleHits, RuleHit{Rule: rule, Adjustment: adjustment})
+}
+
+// reject marks the candidate as rejected, keeping the first reason given
+func (b *ScoreBreakdown) reject(reason string) {
+	if b.Rejection == "" {
+		b.Rejection = reason
+	}
+	b.Accepted = false
+}
+
+// finalize decides acceptance once all components have been added
+func (b *ScoreBreakdown) finalize() {
+	if b.Rejection != "" {
+		b.Accepted = false
+		return
+	}
+	if b.Total < b.Threshold {
+		b.reject(fmt.Sprintf("score %.2f below minimum %.2f", b.Total, b.Threshold))
+		return
+	}
+	b.Accepted = true
+}
+
+// String returns a compact form suitable for log lines
+func (b *ScoreBreakdown) String() string {
+	if b == nil {
+		return ""
+	}
+
+	parts := make([]string, 0, len(b.Components)+1)
+	for _, component := range b.Components {
+		parts = append(parts, fmt.Sprintf("%s=%.2f", component.Name, component.Contribution))
+	}
+	for _, hit := range b.RuleHits {
+		parts = append(parts, fmt.Sprintf("rule:%s=%+.2f", hit.Rule, hit.Adjustment))
+	}
+	if b.Rejection != "" {
+		parts = append(parts, fmt.Sprintf("rejected=%q", b.Rejection))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,59 @@
+package jackett
+
+import "testing"
+
+func TestScoreBreakdown(t *testing.T) {
+	tests := []struct {
+		name      string
+		build     func(b *ScoreBreakdown)
+		accepted  bool
+		rejection string
+		summary   string
+	}{
+		{
+			name: "above threshold",
+			build: func(b *ScoreBreakdown) {
+				b.addComponent("quality", 1, 0.4, "1080p")
+				b.addComponent("seeders", 0.5, 0.2, "25 seeders")
+			},
+			accepted: true,
+			summary:  "quality=0.40 seeders=0.10",
+		},
+		{
+			name: "below threshold",
+			build: func(b *ScoreBreakdown) {
+				b.addComponent("quality", 0.5, 0.4, "720p")
+			},
+			rejection: "score 0.20 below minimum 0.30",
+			summary:   `quality=0.20 rejected="score 0.20 below minimum 0.30"`,
+		},
+		{
+			name: "rejected despite its score",
+			build: func(b *ScoreBreakdown) {
+				b.addComponent("quality", 1, 0.4, "1080p")
+				b.addRuleHit("proper", 0.05)
+				b.reject("title does not match")
+				b.reject("release year 2019 is not within 1 of 2017")
+			},
+			rejection: "title does not match",
+			summary:   `quality=0.40 rule:proper=+0.05 rejected="title does not match"`,
+		},
+	}
+
+	for _, test := range tests {
+		b := newScoreBreakdown(0.3)
+		test.build(b)
+		b.finalize()
+		if b.Accepted != test.accepted || b.Rejection != test.rejection {
+			t.Errorf("%s: expected accepted %v with %q, got %v with %q", test.name, test.accepted, test.rejection, b.Accepted, b.Rejection)
+		}
+		if got := b.String(); got != test.summary {
+			t.Errorf("%s: expected %q, got %q", test.name, test.summary, got)
+		}
+	}
+
+	var missing *ScoreBreakdown
+	if missing.String() != "" {
+		t.Error("a nil breakdown should print nothing")
+	}
+}
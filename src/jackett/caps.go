@@ -0,0 +1,337 @@
+package jackett
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"high-seas/src/logger"
+	"high-seas/src/utils"
+)
+
+var (
+	// capsTTL is how long discovered indexer capabilities are trusted before asking again
+	capsTTL = utils.EnvVarDuration("INDEXER_CAPS_TTL", 24*time.Hour)
+	// capsRetryDelay spaces out discovery attempts while Jackett is unreachable
+	capsRetryDelay = 5 * time.Minute
+)
+
+// defaultCategories are searched until discovery has succeeded, or when no configured
+// indexer offers a category for the media type
+var defaultCategories = map[string][]uint{
+	MediaMovie:      {2000, 2010, 2020, 2030, 2040, 2050, 2060, 2070, 2080},
+	MediaShow:       {5000, 5020, 5030, 5040, 5045},
+	MediaAnimeMovie: {2000, 2010, 100001},
+	MediaAnimeShow:  {100060, 140679, 5070},
+}
+
+// Newznab category ranges and the standard anime category
+const (
+	moviesCategoryBase = 2000
+	tvCategoryBase     = 5000
+	tvAnimeCategory    = 5070
+	customCategoryBase = 100000
+)
+
+// Torznab search modes as named in the caps document
+var capsModes = map[string]string{
+	torznabSearch:   "search",
+	torznabTVSearch: "tv-search",
+	torznabMovie:    "movie-search",
+}
+
+// IndexerCategory is a category an indexer offers, with its subcategories
+type IndexerCategory struct {
+	ID      uint              `json:"id"`
+	Name    string            `json:"name"`
+	Subcats []IndexerCategory `json:"subcats,omitempty"`
+}
+
+// IndexerCaps is what one configured indexer told us it supports
+type IndexerCaps struct {
+	ID           string              `json:"id"`
+	Title        string              `json:"title"`
+	SearchParams map[string][]string `json:"search_params"`
+	Categories   []IndexerCategory   `json:"categories"`
+	Error        string              `json:"error,omitempty"`
+}
+
+// Capabilities combines every indexer's caps into the categories searched per media type
+type Capabilities struct {
+	Indexers   []IndexerCaps     `json:"indexers"`
+	Categories map[string][]uint `json:"categories"`
+	FetchedAt  time.Time         `json:"fetched_at"`
+}
+
+var (
+	capabilities     *Capabilities
+	capsAttemptedAt  time.Time
+	capabilitiesLock sync.Mutex
+	// discoveryLock lets one discovery run at a time without blocking searches that read
+	// the current capabilities
+	discoveryLock sync.Mutex
+)
+
+type torznabCategory struct {
+	ID      uint              `xml:"id,attr"`
+	Name    string            `xml:"name,attr"`
+	Subcats []torznabCategory `xml:"subcat"`
+}
+
+type torznabCaps struct {
+	Searching struct {
+		Modes []struct {
+			XMLName         xml.Name
+			Available       string `xml:"available,attr"`
+			SupportedParams string `xml:"supportedParams,attr"`
+		} `xml:",any"`
+	} `xml:"searching"`
+	Categories []torznabCategory `xml:"categories>category"`
+}
+
+type torznabIndexers struct {
+	Indexers []struct {
+		ID    string       `xml:"id,attr"`
+		Title string       `xml:"title"`
+		Caps  *torznabCaps `xml:"caps"`
+	} `xml:"indexer"`
+}
+
+// IndexerCapabilities returns the cached capabilities, discovering them from Jackett when
+// they're missing, stale or a refresh is forced
+func IndexerCapabilities(ctx context.Context, refresh bool) (*Capabilities, error) {
+	discoveryLock.Lock()
+	defer discoveryLock.Unlock()
+
+	capabilitiesLock.Lock()
+	current := capabilities
+	if !refresh && current != nil && time.Since(current.FetchedAt) < capsTTL {
+		capabilitiesLock.Unlock()
+		return current, nil
+	}
+	capsAttemptedAt = time.Now()
+	capabilitiesLock.Unlock()
+
+	discovered, err := discoverCapabilities(ctx)
+	if err != nil {
+		return current, err
+	}
+
+	capabilitiesLock.Lock()
+	capabilities = discovered
+	capabilitiesLock.Unlock()
+	return discovered, nil
+}
+
+// ensureCapabilities discovers capabilities before a search if we don't have fresh ones,
+// without retrying on every search while Jackett is down
+func ensureCapabilities(ctx context.Context) {
+	capabilitiesLock.Lock()
+	fresh := capabilities != nil && time.Since(capabilities.FetchedAt) < capsTTL
+	recentlyFailed := time.Since(capsAttemptedAt) < capsRetryDelay
+	capabilitiesLock.Unlock()
+	if fresh || recentlyFailed {
+		return
+	}
+
+	if _, err := IndexerCapabilities(ctx, false); err != nil {
+		logger.WriteError("Failed to discover indexer capabilities, using default categories", err)
+	}
+}
+
+// discoverCapabilities lists the configured indexers and reads each one's caps
+func discoverCapabilities(ctx context.Context) (*Capabilities, error) {
+	params := url.Values{}
+	params.Set("t", "indexers")
+	params.Set("configured", "true")
+	body, err := torznabRequest(ctx, allIndexers, params, "indexer list")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexers: %w", err)
+	}
+	if err := torznabErrorFrom(body); err != nil {
+		return nil, fmt.Errorf("failed to list indexers: %w", err)
+	}
+
+	var listing torznabIndexers
+	if err := xml.Unmarshal(body, &listing); err != nil {
+		return nil, fmt.Errorf("failed to parse indexer list: %w", err)
+	}
+
+	discovered := &Capabilities{FetchedAt: time.Now()}
+	for _, indexer := range listing.Indexers {
+		caps := indexer.Caps
+		if caps == nil || len(caps.Categories) == 0 {
+			caps, err = fetchIndexerCaps(ctx, indexer.ID)
+		}
+
+		entry := IndexerCaps{ID: indexer.ID, Title: indexer.Title}
+		if err != nil {
+			logger.WriteError(fmt.Sprintf("Failed to read caps for indexer %s", indexer.ID), err)
+			entry.Error = err.Error()
+			err = nil
+		} else {
+			entry.SearchParams = caps.searchParams()
+			entry.Categories = convertCategories(caps.Categories)
+		}
+		discovered.Indexers = append(discovered.Indexers, entry)
+	}
+
+	discovered.Categories = mapCategories(discovered.Indexers)
+	logger.WriteInfo(fmt.Sprintf("Discovered capabilities for %d indexers: %v", len(discovered.Indexers), discovered.Categories))
+	return discovered, nil
+}
+
+// fetchIndexerCaps asks one indexer for its t=caps document
+func fetchIndexerCaps(ctx context.Context, indexer string) (*torznabCaps, error) {
+	params := url.Values{}
+	params.Set("t", "caps")
+	body, err := torznabRequest(ctx, indexer, params, fmt.Sprintf("caps for %s", indexer))
+	if err != nil {
+		return nil, err
+	}
+	if err := torznabErrorFrom(body); err != nil {
+		return nil, err
+	}
+
+	var caps torznabCaps
+	if err := xml.Unmarshal(body, &caps); err != nil {
+		return nil, fmt.Errorf("failed to parse caps: %w", err)
+	}
+	return &caps, nil
+}
+
+// searchParams lists the parameters of each available search mode
+func (c *torznabCaps) searchParams() map[string][]string {
+	params := make(map[string][]string)
+	for _, mode := range c.Searching.Modes {
+		if mode.Available != "yes" {
+			continue
+		}
+		var supported []string
+		for _, param := range strings.Split(mode.SupportedParams, ",") {
+			if param = strings.TrimSpace(param); param != "" {
+				supported = append(supported, param)
+			}
+		}
+		params[mode.XMLName.Local] = supported
+	}
+	return params
+}
+
+func convertCategories(categories []torznabCategory) []IndexerCategory {
+	converted := make([]IndexerCategory, 0, len(categories))
+	for _, category := range categories {
+		converted = append(converted, IndexerCategory{
+			ID:      category.ID,
+			Name:    category.Name,
+			Subcats: convertCategories(category.Subcats),
+		})
+	}
+	return converted
+}
+
+// mapCategories picks the categories to search for each media type from every indexer's
+// list: the standard Newznab movie and TV ranges, the standard TV/Anime category, and
+// tracker-specific categories whose names say they hold anime
+func mapCategories(indexers []IndexerCaps) map[string][]uint {
+	sets := map[string]map[uint]bool{
+		MediaMovie:      {},
+		MediaShow:       {},
+		MediaAnimeMovie: {},
+		MediaAnimeShow:  {},
+	}
+
+	var visit func(categories []IndexerCategory)
+	visit = func(categories []IndexerCategory) {
+		for _, category := range categories {
+			name := strings.ToLower(category.Name)
+			switch {
+			case category.ID >= moviesCategoryBase && category.ID < moviesCategoryBase+1000:
+				sets[MediaMovie][category.ID] = true
+				if category.ID == moviesCategoryBase {
+					sets[MediaAnimeMovie][category.ID] = true
+				}
+			case category.ID == tvAnimeCategory:
+				sets[MediaAnimeShow][category.ID] = true
+			case category.ID >= tvCategoryBase && category.ID < tvCategoryBase+1000:
+				sets[MediaShow][category.ID] = true
+			case category.ID >= customCategoryBase && strings.Contains(name, "anime") && !containsAny(name, "amv", "music"):
+				if !containsAny(name, "movie", "film") {
+					sets[MediaAnimeShow][category.ID] = true
+				}
+				if !containsAny(name, "tv", "series", "episode") {
+					sets[MediaAnimeMovie][category.ID] = true
+				}
+			}
+			visit(category.Subcats)
+		}
+	}
+	for _, indexer := range indexers {
+		visit(indexer.Categories)
+	}
+
+	mapped := make(map[string][]uint)
+	for mediaType, set := range sets {
+		for id := range set {
+			mapped[mediaType] = append(mapped[mediaType], id)
+		}
+		sort.Slice(mapped[mediaType], func(i, j int) bool { return mapped[mediaType][i] < mapped[mediaType][j] })
+	}
+	return mapped
+}
+
+func containsAny(text string, words ...string) bool {
+	for _, word := range words {
+		if strings.Contains(text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchCategories returns the categories to search for a media type, falling back to
+// the defaults until discovery finds some
+func searchCategories(mediaType string) []uint {
+	capabilitiesLock.Lock()
+	defer capabilitiesLock.Unlock()
+
+	if capabilities != nil && len(capabilities.Categories[mediaType]) > 0 {
+		return capabilities.Categories[mediaType]
+	}
+	return defaultCategories[mediaType]
+}
+
+// supportsParams reports whether any indexer can run the search mode with all the given
+// parameters. Before discovery we assume it can and let the indexers tell us otherwise.
+func supportsParams(searchType string, params ...string) bool {
+	capabilitiesLock.Lock()
+	defer capabilitiesLock.Unlock()
+
+	if capabilities == nil {
+		return true
+	}
+
+	for _, indexer := range capabilities.Indexers {
+		supported, ok := indexer.SearchParams[capsModes[searchType]]
+		if !ok {
+			continue
+		}
+		all := true
+		for _, param := range params {
+			if !slices.Contains(supported, param) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
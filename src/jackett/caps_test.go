@@ -0,0 +1,91 @@
+package jackett
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestMapCategories(t *testing.T) {
+	indexers := []IndexerCaps{{
+		Categories: []IndexerCategory{
+			{ID: 2000, Name: "Movies", Subcats: []IndexerCategory{{ID: 2040, Name: "Movies/HD"}}},
+			{ID: 5000, Name: "TV", Subcats: []IndexerCategory{{ID: 5040, Name: "TV/HD"}, {ID: 5070, Name: "TV/Anime"}}},
+			{ID: 100001, Name: "Anime - Movies"},
+			{ID: 100002, Name: "Anime TV Series"},
+			{ID: 100003, Name: "Anime - Raw"},
+			{ID: 100004, Name: "Anime Music Video"},
+			{ID: 100005, Name: "Books"},
+		},
+	}}
+
+	mapped := mapCategories(indexers)
+	tests := []struct {
+		mediaType string
+		want      []uint
+	}{
+		{MediaMovie, []uint{2000, 2040}},
+		{MediaShow, []uint{5000, 5040}},
+		{MediaAnimeMovie, []uint{2000, 100001, 100003}},
+		{MediaAnimeShow, []uint{5070, 100002, 100003}},
+	}
+	for _, test := range tests {
+		if got := mapped[test.mediaType]; !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.mediaType, got, test.want)
+		}
+	}
+}
+
+func TestSupportsParams(t *testing.T) {
+	capabilitiesLock.Lock()
+	old := capabilities
+	capabilities = &Capabilities{Indexers: []IndexerCaps{
+		{ID: "movies", SearchParams: map[string][]string{"movie-search": {"q", "imdbid"}}},
+		{ID: "shows", SearchParams: map[string][]string{"tv-search": {"q", "season", "ep"}}},
+	}}
+	capabilitiesLock.Unlock()
+	t.Cleanup(func() {
+		capabilitiesLock.Lock()
+		capabilities = old
+		capabilitiesLock.Unlock()
+	})
+
+	tests := []struct {
+		searchType string
+		params     []string
+		want       bool
+	}{
+		{torznabMovie, []string{"imdbid"}, true},
+		{torznabMovie, []string{"tmdbid"}, false},
+		{torznabTVSearch, []string{"season", "ep"}, true},
+		// No single indexer supports both
+		{torznabTVSearch, []string{"season", "imdbid"}, false},
+	}
+	for _, test := range tests {
+		if got := supportsParams(test.searchType, test.params...); got != test.want {
+			t.Errorf("%s %v: got %v, want %v", test.searchType, test.params, got, test.want)
+		}
+	}
+}
+
+func TestDiscoverCapabilitiesFetchesMissingCaps(t *testing.T) {
+	indexer, _ := setupHarness(t, nil)
+	indexer.indexers = "indexers_without_caps.xml"
+
+	discovered, err := IndexerCapabilities(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(discovered.Indexers) != 1 || discovered.Indexers[0].Error != "" {
+		t.Fatalf("unexpected indexers %+v", discovered.Indexers)
+	}
+	if got := discovered.Categories[MediaAnimeShow]; !slices.Equal(got, []uint{5070}) {
+		t.Errorf("expected TV/Anime from the indexer's own caps, got %v", got)
+	}
+	if got := discovered.Categories[MediaAnimeMovie]; !slices.Equal(got, []uint{100001}) {
+		t.Errorf("expected the anime movie category without music videos, got %v", got)
+	}
+	if !supportsParams(torznabTVSearch, "tvdbid") || supportsParams(torznabMovie, "q") {
+		t.Error("search params should come from the fetched caps")
+	}
+}
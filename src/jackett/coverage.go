@@ -0,0 +1,340 @@
+package jackett
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"high-seas/src/torrent"
+)
+
+// MIN_NEW_COVERAGE_RATIO is the share of a pack's episodes that must be wanted and still
+// missing for it to be grabbed, so a full series pack isn't grabbed to fill one gap or for
+// a few selected episodes
+const MIN_NEW_COVERAGE_RATIO = 0.5
+
+var (
+	episodeRangePattern   = regexp.MustCompile(`\bs(\d{1,2}) ?e(\d{1,3})((?:(?: ?- ?| ?)e?\d{1,3}\b)*)`)
+	crossEpisodePattern   = regexp.MustCompile(`\b(\d{1,2})x(\d{1,3})\b`)
+	seasonRangePattern    = regexp.MustCompile(`\bs(\d{1,2}) ?(?:-|to) ?s?(\d{1,2})\b|\bseasons? (\d{1,2}) ?(?:-|to|thru|through) ?(\d{1,2})\b`)
+	episodeNumberPattern  = regexp.MustCompile(`\d{1,3}`)
+	seasonPattern         = regexp.MustCompile(`\bs(\d{1,2})\b|\bseason (\d{1,2})\b`)
+	completeSeriesPattern = regexp.MustCompile(`\bcomplete series\b|\bthe complete\b|\ball seasons\b|\bcomplete collection\b`)
+	videoExtensions       = map[string]bool{".mkv": true, ".mp4": true, ".avi": true, ".m4v": true, ".ts": true, ".wmv": true}
+)
+
+// episodeKey identifies a single episode of a show
+type episodeKey struct {
+	season  int
+	episode int
+}
+
+func (k episodeKey) String() string {
+	return fmt.Sprintf("S%02dE%02d", k.season, k.episode)
+}
+
+// episodeSet is a set of episodes, used both for what we want and what a release covers
+type episodeSet map[episodeKey]bool
+
+// wantedEpisodes expands per-season episode counts (index 0 is season 1) into a set
+func wantedEpisodes(seasons []int) episodeSet {
+	wanted := episodeSet{}
+	for i, count := range seasons {
+		wanted.addSeason(i+1, count)
+	}
+	return wanted
+}
+
+func (s episodeSet) addSeason(season, episodeCount int) {
+	for episode := 1; episode <= episodeCount; episode++ {
+		s[episodeKey{season, episode}] = true
+	}
+}
+
+// newEpisodeSet builds a set from a list of episodes
+func newEpisodeSet(keys []episodeKey) episodeSet {
+	set := episodeSet{}
+	for _, key := range keys {
+		set[key] = true
+	}
+	return set
+}
+
+// seasonsWithGaps returns the distinct seasons among the given episodes, in order
+func seasonsWithGaps(gaps []episodeKey) []int {
+	var seasons []int
+	for _, gap := range gaps {
+		if len(seasons) == 0 || seasons[len(seasons)-1] != gap.season {
+			seasons = append(seasons, gap.season)
+		}
+	}
+	return seasons
+}
+
+// union adds every episode in other to s
+func (s episodeSet) union(other episodeSet) {
+	for key := range other {
+		s[key] = true
+	}
+}
+
+// intersect returns the episodes present in both sets
+func (s episodeSet) intersect(other episodeSet) episodeSet {
+	result := episodeSet{}
+	for key := range s {
+		if other[key] {
+			result[key] = true
+		}
+	}
+	return result
+}
+
+// missing returns the episodes in s that aren't in have, in season/episode order
+func (s episodeSet) missing(have episodeSet) []episodeKey {
+	var keys []episodeKey
+	for key := range s {
+		if !have[key] {
+			keys = append(keys, key)
+		}
+	}
+	sortEpisodes(keys)
+	return keys
+}
+
+// String summarises the set as per-season ranges, e.g. "S01E01-E10 S02E03"
+func (s episodeSet) String() string {
+	keys := s.missing(nil)
+	var parts []string
+	for i := 0; i < len(keys); {
+		j := i
+		for j+1 < len(keys) && keys[j+1].season == keys[i].season && keys[j+1].episode == keys[j].episode+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, keys[i].String())
+		} else {
+			parts = append(parts, fmt.Sprintf("%s-E%02d", keys[i], keys[j].episode))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, " ")
+}
+
+func sortEpisodes(keys []episodeKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].season != keys[j].season {
+			return keys[i].season < keys[j].season
+		}
+		return keys[i].episode < keys[j].episode
+	})
+}
+
+// parseCoverage works out which wanted episodes a release title covers. Episode markers
+// win over season markers; a title with no markers covers nothing we can prove.
+func parseCoverage(title string, seasons []int) episodeSet {
+	normalized := strings.ToLower(title)
+	normalized = strings.NewReplacer(".", " ", "_", " ", "[", " ", "]", " ", "(", " ", ")", " ").Replace(normalized)
+	covered := episodeSet{}
+
+	for _, match := range episodeRangePattern.FindAllStringSubmatch(normalized, -1) {
+		season, _ := strconv.Atoi(match[1])
+		first, _ := strconv.Atoi(match[2])
+		episodes := []int{first}
+		for _, number := range episodeNumberPattern.FindAllString(match[3], -1) {
+			n, _ := strconv.Atoi(number)
+			episodes = append(episodes, n)
+		}
+
+		last := episodes[len(episodes)-1]
+		if strings.Contains(match[3], "-") && last > first {
+			for episode := first; episode <= last; episode++ {
+				covered[episodeKey{season, episode}] = true
+			}
+			continue
+		}
+		for _, episode := range episodes {
+			covered[episodeKey{season, episode}] = true
+		}
+	}
+	for _, match := range crossEpisodePattern.FindAllStringSubmatch(normalized, -1) {
+		season, _ := strconv.Atoi(match[1])
+		episode, _ := strconv.Atoi(match[2])
+		covered[episodeKey{season, episode}] = true
+	}
+	if len(covered) > 0 {
+		return covered
+	}
+
+	addSeason := func(season int) {
+		if season >= 1 && season <= len(seasons) {
+			covered.addSeason(season, seasons[season-1])
+		}
+	}
+
+	for _, match := range seasonRangePattern.FindAllStringSubmatch(normalized, -1) {
+		first, last := match[1], match[2]
+		if first == "" {
+			first, last = match[3], match[4]
+		}
+		from, _ := strconv.Atoi(first)
+		to, _ := strconv.Atoi(last)
+		for season := from; season <= to; season++ {
+			addSeason(season)
+		}
+	}
+	for _, match := range seasonPattern.FindAllStringSubmatch(normalized, -1) {
+		number := match[1]
+		if number == "" {
+			number = match[2]
+		}
+		season, _ := strconv.Atoi(number)
+		addSeason(season)
+	}
+	if len(covered) > 0 {
+		return covered
+	}
+
+	if completeSeriesPattern.MatchString(normalized) {
+		return wantedEpisodes(seasons)
+	}
+	return covered
+}
+
+// coverageFromFiles works out coverage from the video files inside a torrent
+func coverageFromFiles(files []torrent.File, seasons []int) episodeSet {
+	covered := episodeSet{}
+	for _, file := range files {
+		name := strings.ToLower(path.Base(file.Path))
+		if !videoExtensions[path.Ext(name)] || strings.Contains(name, "sample") {
+			continue
+		}
+		episodes := parseCoverage(strings.TrimSuffix(name, path.Ext(name)), seasons)
+		// A file that only names a season is an extra, not that whole season
+		if len(episodes) > 1 && !episodeRangePattern.MatchString(name) && !crossEpisodePattern.MatchString(name) {
+			continue
+		}
+		covered.union(episodes)
+	}
+	return covered
+}
+
+// coverageCandidate is an accepted release along with the wanted episodes it covers
+type coverageCandidate struct {
+	searchResult
+	covers   episodeSet
+	verified bool
+	// extra counts the episodes it contains that weren't asked for
+	extra int
+}
+
+// worthGrabbing reports whether enough of a release's episodes are new and wanted
+func worthGrabbing(newEpisodes, total int) bool {
+	return newEpisodes > 0 && float64(newEpisodes) >= float64(total)*MIN_NEW_COVERAGE_RATIO
+}
+
+// isPack reports whether the release covers more than a single episode
+func (c *coverageCandidate) isPack() bool {
+	return len(c.covers) > 1
+}
+
+// newCoverageCandidates attaches title-parsed coverage to accepted results, dropping
+// duplicates and anything that covers none of the wanted episodes
+func newCoverageCandidates(results []searchResult, seasons []int, wanted episodeSet) []*coverageCandidate {
+	seen := make(map[string]bool)
+	var candidates []*coverageCandidate
+
+	for _, sr := range results {
+		key := sr.result.Guid
+		if key == "" {
+			key = sr.result.Link + sr.result.MagnetUri
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		contains := parseCoverage(sr.result.Title, seasons)
+		covers := contains.intersect(wanted)
+		if len(covers) == 0 {
+			continue
+		}
+		candidates = append(candidates, &coverageCandidate{searchResult: sr, covers: covers, extra: len(contains) - len(covers)})
+	}
+
+	return candidates
+}
+
+// episodePlanner picks a small set of releases that covers the wanted episodes, packs first
+type episodePlanner struct {
+	wanted     episodeSet
+	covered    episodeSet
+	candidates []*coverageCandidate
+}
+
+func newEpisodePlanner(wanted, have episodeSet, candidates []*coverageCandidate) *episodePlanner {
+	covered := episodeSet{}
+	covered.union(have.intersect(wanted))
+	return &episodePlanner{wanted: wanted, covered: covered, candidates: candidates}
+}
+
+// add makes more candidates available to the planner
+func (p *episodePlanner) add(candidates []*coverageCandidate) {
+	p.candidates = append(p.candidates, candidates...)
+}
+
+// next returns the candidate adding the most missing episodes, or nil when none is worth grabbing.
+// Ties go to the higher score, then the larger release.
+func (p *episodePlanner) next() *coverageCandidate {
+	var best *coverageCandidate
+	bestNew := 0
+
+	for _, candidate := range p.candidates {
+		newEpisodes := len(candidate.covers.missing(p.covered))
+		if !worthGrabbing(newEpisodes, len(candidate.covers)+candidate.extra) {
+			continue
+		}
+
+		if best == nil || newEpisodes > bestNew ||
+			(newEpisodes == bestNew && (candidate.score > best.score ||
+				(candidate.score == best.score && candidate.result.Size > best.result.Size))) {
+			best = candidate
+			bestNew = newEpisodes
+		}
+	}
+
+	return best
+}
+
+// markGrabbed records a successful grab and removes the candidate from consideration
+func (p *episodePlanner) markGrabbed(candidate *coverageCandidate) {
+	p.covered.union(candidate.covers)
+	p.drop(candidate)
+}
+
+// drop removes a candidate that failed or turned out not to be useful
+func (p *episodePlanner) drop(candidate *coverageCandidate) {
+	for i, c := range p.candidates {
+		if c == candidate {
+			p.candidates = append(p.candidates[:i], p.candidates[i+1:]...)
+			return
+		}
+	}
+}
+
+// gaps returns the wanted episodes not covered yet
+func (p *episodePlanner) gaps() []episodeKey {
+	return p.wanted.missing(p.covered)
+}
+
+// plan runs the planner to completion without grabbing anything, for previews
+func (p *episodePlanner) plan() []*coverageCandidate {
+	var chosen []*coverageCandidate
+	for candidate := p.next(); candidate != nil; candidate = p.next() {
+		chosen = append(chosen, candidate)
+		p.markGrabbed(candidate)
+	}
+	return chosen
+}
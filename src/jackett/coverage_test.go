@@ -0,0 +1,100 @@
+package jackett
+
+import (
+	"testing"
+
+	jackett "github.com/webtor-io/go-jackett"
+	"high-seas/src/torrent"
+)
+
+func TestParseCoverage(t *testing.T) {
+	seasons := []int{10, 8, 6}
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Night.Harbor.S02E05.1080p.WEB.x264-GRP", "S02E05"},
+		{"Night.Harbor.S02E02-E04.1080p", "S02E02-E04"},
+		{"Night Harbor S01E01E02 720p", "S01E01-E02"},
+		{"Night Harbor 3x07 HDTV", "S03E07"},
+		{"Night.Harbor.S01.1080p.BluRay", "S01E01-E10"},
+		{"Night Harbor Season 2 Complete", "S02E01-E08"},
+		{"Night Harbor S01-S02 1080p", "S01E01-E10 S02E01-E08"},
+		{"Night Harbor Seasons 2 to 3", "S02E01-E08 S03E01-E06"},
+		{"Night Harbor Complete Series 1080p", "S01E01-E10 S02E01-E08 S03E01-E06"},
+		// A season TMDb doesn't know of covers nothing we can count
+		{"Night Harbor S05 1080p", ""},
+		{"Night Harbor 1080p WEB", ""},
+	}
+
+	for _, test := range tests {
+		if got := parseCoverage(test.title, seasons).String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.title, got, test.want)
+		}
+	}
+}
+
+func TestCoverageFromFiles(t *testing.T) {
+	files := []torrent.File{
+		{Path: "Night.Harbor.S01/Night.Harbor.S01E01.mkv"},
+		{Path: "Night.Harbor.S01/Night.Harbor.S01E02-E03.mkv"},
+		{Path: "Night.Harbor.S01/Night.Harbor.S01E04.sample.mkv"},
+		{Path: "Night.Harbor.S01/Night.Harbor.S01E05.nfo"},
+		// Named for the season, but it's an extra rather than the whole season
+		{Path: "Night.Harbor.S01/Extras/Night.Harbor.S01.Behind.The.Scenes.mkv"},
+	}
+
+	if got := coverageFromFiles(files, []int{10}).String(); got != "S01E01-E03" {
+		t.Errorf("got %q, want S01E01-E03", got)
+	}
+}
+
+func TestEpisodePlannerPrefersPacks(t *testing.T) {
+	wanted := wantedEpisodes([]int{4})
+	candidate := func(title string, score float64, size uint) *coverageCandidate {
+		return &coverageCandidate{
+			searchResult: searchResult{result: &jackett.Result{Title: title, Size: size}, score: score},
+			covers:       parseCoverage(title, []int{4}).intersect(wanted),
+		}
+	}
+	pack := candidate("Show.S01.1080p", 0.7, 8<<30)
+	smallerPack := candidate("Show.S01.1080p.x265", 0.7, 4<<30)
+	single := candidate("Show.S01E04.1080p", 0.9, 2<<30)
+	partial := candidate("Show.S01E01-E03.1080p", 0.9, 6<<30)
+
+	// S01E04 is already in the library
+	planner := newEpisodePlanner(wanted, newEpisodeSet([]episodeKey{{1, 4}}), []*coverageCandidate{single, smallerPack, partial, pack})
+	if got := planner.next(); got != partial {
+		t.Fatalf("expected the release adding the most missing episodes at the best score, got %s", got.result.Title)
+	}
+
+	planner.drop(partial)
+	if got := planner.next(); got != pack {
+		t.Fatalf("expected the larger of two equally scored packs, got %s", got.result.Title)
+	}
+
+	planner.markGrabbed(pack)
+	if gaps := planner.gaps(); len(gaps) != 0 {
+		t.Errorf("expected no gaps after the pack, got %v", gaps)
+	}
+	if got := planner.next(); got != nil {
+		t.Errorf("nothing should be worth grabbing once everything is covered, got %s", got.result.Title)
+	}
+}
+
+func TestEpisodePlannerSkipsMostlyOwnedPacks(t *testing.T) {
+	wanted := wantedEpisodes([]int{10})
+	pack := &coverageCandidate{
+		searchResult: searchResult{result: &jackett.Result{Title: "Show.S01"}},
+		covers:       wanted,
+	}
+	have := wantedEpisodes([]int{9})
+
+	planner := newEpisodePlanner(wanted, have, []*coverageCandidate{pack})
+	if got := planner.plan(); len(got) != 0 {
+		t.Errorf("a pack filling one gap of ten shouldn't be grabbed, got %d", len(got))
+	}
+	if gaps := planner.gaps(); len(gaps) != 1 || gaps[0] != (episodeKey{1, 10}) {
+		t.Errorf("expected S01E10 left to search for, got %v", gaps)
+	}
+}
@@ -0,0 +1,87 @@
+package jackett
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"high-seas/src/deluge"
+	"high-seas/src/downloads"
+	"high-seas/src/logger"
+	"high-seas/src/utils"
+)
+
+const gigabyte = 1 << 30
+
+var (
+	// diskReserve is the space left free on the download disk after every grab, in GB;
+	// a negative reserve turns the check off
+	diskReserve = utils.EnvVarInt("DISK_SPACE_RESERVE_GB", 10)
+
+	// freeSpace asks Deluge how much room a download path has; tests swap in a fake
+	freeSpace = deluge.FreeSpace
+
+	// ErrInsufficientSpace is returned when a release doesn't fit on the download disk
+	ErrInsufficientSpace = errors.New("not enough disk space")
+)
+
+// DownloadSpace is the room left where one media type downloads to
+type DownloadSpace struct {
+	MediaType string `json:"media_type"`
+	// Path is Deluge's download location for the media type, empty for its default
+	Path      string `json:"path"`
+	Free      int64  `json:"free"`
+	Committed int64  `json:"committed"`
+	Reserve   int64  `json:"reserve"`
+	Available int64  `json:"available"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DownloadSpaces reports the room left in each media type's download path. Paths shared
+// by several media types are listed once for each.
+func DownloadSpaces(ctx context.Context) []DownloadSpace {
+	var spaces []DownloadSpace
+	for _, mediaType := range []string{MediaMovie, MediaShow, MediaAnimeMovie, MediaAnimeShow} {
+		space := DownloadSpace{MediaType: mediaType, Path: torrentOptions[mediaType].DownloadPath}
+		free, err := freeSpace(ctx, space.Path)
+		if err != nil {
+			space.Error = err.Error()
+		} else {
+			space.Free = free
+			space.Committed = downloads.Committed(space.Path)
+			space.Reserve = int64(max(diskReserve, 0)) * gigabyte
+			space.Available = space.Free - space.Committed - space.Reserve
+		}
+		spaces = append(spaces, space)
+	}
+	return spaces
+}
+
+// checkDiskSpace makes sure a release fits in its download path along with what active
+// downloads there still need, leaving the reserve free. When Deluge can't say how much
+// room there is, the release is let through.
+func checkDiskSpace(ctx context.Context, sr *searchResult) error {
+	if diskReserve < 0 {
+		return nil
+	}
+
+	size := int64(sr.result.Size)
+	if sr.meta != nil {
+		size = sr.meta.TotalSize()
+	}
+
+	path := torrentOptions[sr.mediaType].DownloadPath
+	free, err := freeSpace(ctx, path)
+	if err != nil {
+		logger.WriteWarning(fmt.Sprintf("Couldn't check free space for %s: %v", sr.result.Title, err))
+		return nil
+	}
+	committed := downloads.Committed(path)
+	reserve := int64(diskReserve) * gigabyte
+
+	if free-committed-size < reserve {
+		return fmt.Errorf("%w: %s needs %.1f GB, but only %.1f GB is free with %.1f GB committed to active downloads and %d GB kept in reserve",
+			ErrInsufficientSpace, sr.result.Title, float64(size)/gigabyte, float64(free)/gigabyte, float64(committed)/gigabyte, diskReserve)
+	}
+	return nil
+}
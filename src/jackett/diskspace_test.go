@@ -0,0 +1,29 @@
+package jackett
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"high-seas/src/torrent"
+)
+
+func TestGrabReleaseRefusesWhenDiskIsFull(t *testing.T) {
+	indexer, client := setupHarness(t, nil)
+	link := indexer.server.URL + "/download/night-harbor-s01.torrent"
+	indexer.files[link] = []torrent.File{{Path: "Night.Harbor.S01.1080p/Night.Harbor.S01E01.1080p.mkv", Length: 20 * gigabyte}}
+	client.free = 25 * gigabyte
+
+	_, err := GrabRelease(context.Background(), MediaShow, "Night Harbor", 0, ReleaseRef{Link: link})
+	if !errors.Is(err, ErrInsufficientSpace) {
+		t.Fatalf("expected ErrInsufficientSpace with 5 GB left against a 10 GB reserve, got %v", err)
+	}
+	if links := client.links(); len(links) != 0 {
+		t.Errorf("expected nothing to be added, got %v", links)
+	}
+
+	client.free = 40 * gigabyte
+	if _, err := GrabRelease(context.Background(), MediaShow, "Night Harbor", 0, ReleaseRef{Link: link}); err != nil {
+		t.Errorf("expected the grab to fit in 40 GB, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package jackett
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"high-seas/src/deluge"
+	"high-seas/src/torrent"
+)
+
+func TestMakeMovieQueryGrabsBestMatch(t *testing.T) {
+	indexer, client := setupHarness(t, map[string]string{
+		`"The Quiet Orbit" 1080p`: "movie_quiet_orbit.xml",
+	})
+
+	if err := MakeMovieQuery(context.Background(), "The Quiet Orbit", 0, 2017, "1080p"); err != nil {
+		t.Fatalf("MakeMovieQuery: %v", err)
+	}
+
+	// The sequel, the cam and the 720p release all lose to the 1080p BluRay
+	checkGolden(t, "movie_quiet_orbit", indexer, client)
+}
+
+func TestMakeMovieQueryAddsWithMovieOptions(t *testing.T) {
+	_, client := setupHarness(t, map[string]string{
+		`"The Quiet Orbit" 1080p`: "movie_quiet_orbit.xml",
+	})
+	movieOptions := deluge.AddOptions{DownloadPath: "/media/movies", Label: "movies", Paused: true}
+	oldOptions := torrentOptions
+	torrentOptions = map[string]deluge.AddOptions{MediaMovie: movieOptions}
+	t.Cleanup(func() { torrentOptions = oldOptions })
+
+	if err := MakeMovieQuery(context.Background(), "The Quiet Orbit", 0, 2017, "1080p"); err != nil {
+		t.Fatalf("MakeMovieQuery: %v", err)
+	}
+	links := client.links()
+	if len(links) != 1 || client.options[links[0]] != movieOptions {
+		t.Errorf("expected the movie options for %v, got %+v", links, client.options)
+	}
+}
+
+func TestMakeMovieQueryKeepsAddsDelugeAnsweredLate(t *testing.T) {
+	indexer, client := setupHarness(t, map[string]string{
+		`"The Quiet Orbit" 1080p`: "movie_quiet_orbit.xml",
+	})
+	bluray := indexer.server.URL + "/download/quiet-orbit-2017-bluray.torrent"
+	indexer.files[bluray] = []torrent.File{{Path: "The.Quiet.Orbit.2017.1080p.BluRay.x264-GRP.mkv", Length: 9 << 30}}
+	client.late[bluray] = true
+
+	if err := MakeMovieQuery(context.Background(), "The Quiet Orbit", 0, 2017, "1080p"); err != nil {
+		t.Fatalf("MakeMovieQuery: %v", err)
+	}
+	// The timed-out add went through, so no other release is grabbed as well
+	if links := client.links(); len(links) != 1 || links[0] != bluray {
+		t.Errorf("expected only the BluRay, got %v", links)
+	}
+}
+
+func TestMakeMovieQueryWantsUnmatchedMovie(t *testing.T) {
+	setupHarness(t, nil)
+
+	if err := MakeMovieQuery(context.Background(), "The Quiet Orbit", 0, 2017, "1080p"); !errors.Is(err, ErrNoMatches) {
+		t.Fatalf("expected ErrNoMatches when no release matches, got %v", err)
+	}
+	if !movieWanted(wantedKey(MediaMovie, 0, "The Quiet Orbit")) {
+		t.Error("unmatched movie should be on the wanted list")
+	}
+}
+
+func TestSearchWantedRetriesMissingEpisodesWithBackoff(t *testing.T) {
+	indexer, client := setupHarness(t, map[string]string{
+		`"Night Harbor" S02E02 1080p`: "night_harbor_s02e02.xml",
+	})
+	identity := resolveShowIdentity("Night Harbor", 0, 0)
+	wantShow(MediaShow, "Night Harbor", identity, "1080p", []int{3, 3}, wantedEpisodes([]int{3, 3}), []episodeKey{{2, 2}, {2, 3}}, nil, true)
+
+	item, err := SearchWanted(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("SearchWanted: %v", err)
+	}
+
+	// S02E02-E03 covers both missing episodes, leaving nothing to retry
+	if len(item.Missing) != 0 || item.NextSearchAt != nil {
+		t.Errorf("expected nothing left to retry, got missing %v next %v", item.Missing, item.NextSearchAt)
+	}
+	if item.Attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", item.Attempts)
+	}
+	if got := client.links(); len(got) != 1 || got[0] != indexer.server.URL+"/download/night-harbor-s02e02-e03.torrent" {
+		t.Errorf("unexpected grabs %v", got)
+	}
+}
+
+func TestRetryDelayBacksOff(t *testing.T) {
+	if got := retryDelay(0); got != wantedRetryMin {
+		t.Errorf("first retry after %s, want %s", got, wantedRetryMin)
+	}
+	if got := retryDelay(2); got != 4*wantedRetryMin {
+		t.Errorf("third retry after %s, want %s", got, 4*wantedRetryMin)
+	}
+	if got := retryDelay(100); got != wantedRetryMax {
+		t.Errorf("retries should cap at %s, got %s", wantedRetryMax, got)
+	}
+}
+
+func TestMakeShowQueryFallsBackFromPacksToEpisodes(t *testing.T) {
+	indexer, client := setupHarness(t, map[string]string{
+		"Night Harbor S01 season 1080p": "night_harbor_s01.xml",
+		`"Night Harbor" S02E01 1080p`:   "night_harbor_s02e01.xml",
+		`"Night Harbor" S02E02 1080p`:   "night_harbor_s02e02.xml",
+		`"Night Harbor" S02E03 1080p`:   "night_harbor_s02e03.xml",
+	})
+	// Deluge turns down the first S02E01 release, so the next one is grabbed instead
+	client.reject[indexer.server.URL+"/download/night-harbor-s02e01.torrent"] = errors.New("tracker unreachable")
+
+	if err := MakeShowQuery(context.Background(), "Night Harbor", []int{3, 3}, ShowSelection{}, 0, 0, "1080p"); err != nil {
+		t.Fatalf("MakeShowQuery: %v", err)
+	}
+
+	// Season 1 comes from the pack rather than the spinoff's, season 2 from single and
+	// multi-episode releases, and S02E03 isn't grabbed again after S02E02-E03
+	checkGolden(t, "show_night_harbor", indexer, client)
+}
+
+func TestMakeShowQueryWantsUnmatchedShow(t *testing.T) {
+	setupHarness(t, nil)
+
+	if err := MakeShowQuery(context.Background(), "Night Harbor", []int{3}, ShowSelection{}, 0, 0, "1080p"); !errors.Is(err, ErrNoMatches) {
+		t.Fatalf("expected ErrNoMatches when nothing was grabbed, got %v", err)
+	}
+	if _, ok := findWantedByKey(wantedKey(MediaShow, 0, "Night Harbor")); !ok {
+		t.Error("unmatched show should be on the wanted list")
+	}
+}
+
+func TestMakeAnimeShowQueryGrabsAnimeTimeBatch(t *testing.T) {
+	indexer, client := setupHarness(t, map[string]string{
+		"[Anime Time] Kaiju Academy [Dual Audio][BD][1080p][HEVC 10bit x265][AAC][Eng Sub] [Batch]": "kaiju_academy_batch.xml",
+	})
+
+	if err := MakeAnimeShowQuery(context.Background(), "Kaiju Academy", []int{12}, ShowSelection{}, 0, "1080p"); err != nil {
+		t.Fatalf("MakeAnimeShowQuery: %v", err)
+	}
+
+	// The Anime Time batch is preferred over the better-seeded SubsPlease one
+	checkGolden(t, "anime_kaiju_academy", indexer, client)
+}
@@ -0,0 +1,162 @@
+package jackett
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	jackett "github.com/webtor-io/go-jackett"
+	"high-seas/src/logger"
+)
+
+// previewedTTL is how long a previewed release can be grabbed by its GUID or info hash
+const previewedTTL = time.Hour
+
+var (
+	// ErrReleaseNotFound is returned when a manual grab names a release no recent preview returned
+	ErrReleaseNotFound = errors.New("release not found in recent previews")
+	// ErrInvalidRelease is returned when a release can't be sent to the download client
+	ErrInvalidRelease = errors.New("invalid release")
+)
+
+var infoHashPattern = regexp.MustCompile(`^(?i:[0-9a-f]{40}|[a-z2-7]{32})$`)
+
+// ReleaseRef identifies the release to grab manually: a GUID or info hash from a preview
+// search, or a .torrent or magnet link
+type ReleaseRef struct {
+	GUID     string
+	InfoHash string
+	Link     string
+	Title    string
+}
+
+type previewedRelease struct {
+	searchResult
+	seenAt time.Time
+}
+
+var (
+	previewed      = make(map[string]previewedRelease)
+	previewedMutex sync.Mutex
+)
+
+// rememberPreviewed keeps preview results so one can be grabbed by reference later
+func rememberPreviewed(results []searchResult) {
+	previewedMutex.Lock()
+	defer previewedMutex.Unlock()
+
+	now := time.Now()
+	for key, release := range previewed {
+		if now.Sub(release.seenAt) > previewedTTL {
+			delete(previewed, key)
+		}
+	}
+
+	for _, sr := range results {
+		release := previewedRelease{searchResult: sr, seenAt: now}
+		for _, key := range previewKeys(sr.result.Guid, sr.result.InfoHash, sr.result.Link, sr.result.MagnetUri) {
+			previewed[key] = release
+		}
+	}
+}
+
+func previewKeys(guid, infoHash string, links ...string) []string {
+	var keys []string
+	if guid != "" {
+		keys = append(keys, "guid:"+guid)
+	}
+	if infoHash != "" {
+		keys = append(keys, "hash:"+strings.ToLower(infoHash))
+	}
+	for _, link := range links {
+		if link != "" {
+			keys = append(keys, "link:"+link)
+		}
+	}
+	return keys
+}
+
+func findPreviewed(ref ReleaseRef) (searchResult, bool) {
+	previewedMutex.Lock()
+	defer previewedMutex.Unlock()
+
+	for _, key := range previewKeys(ref.GUID, ref.InfoHash, ref.Link) {
+		if release, ok := previewed[key]; ok && time.Since(release.seenAt) <= previewedTTL {
+			return release.searchResult, true
+		}
+	}
+	return searchResult{}, false
+}
+
+// resolveRelease finds the release a manual grab refers to. Previewed releases keep their
+// indexer details and score; a bare link or info hash is grabbed as given.
+func resolveRelease(ref ReleaseRef) (searchResult, error) {
+	if sr, ok := findPreviewed(ref); ok {
+		if sr.result.Size == 0 {
+			return sr, fmt.Errorf("%w: zero-size torrent", ErrInvalidRelease)
+		}
+		return sr, nil
+	}
+
+	title := ref.Title
+	switch {
+	case ref.Link != "":
+		if title == "" {
+			title = ref.Link
+		}
+		result := &jackett.Result{Title: title, Link: ref.Link, InfoHash: ref.InfoHash}
+		if strings.HasPrefix(ref.Link, "magnet:") {
+			result.Link, result.MagnetUri = "", ref.Link
+		}
+		return searchResult{result: result}, nil
+
+	case ref.InfoHash != "":
+		if !infoHashPattern.MatchString(ref.InfoHash) {
+			return searchResult{}, fmt.Errorf("%w: malformed info hash %q", ErrInvalidRelease, ref.InfoHash)
+		}
+		if title == "" {
+			title = ref.InfoHash
+		}
+		magnet := fmt.Sprintf("magnet:?xt=urn:btih:%s&dn=%s", ref.InfoHash, url.QueryEscape(title))
+		return searchResult{result: &jackett.Result{Title: title, InfoHash: ref.InfoHash, MagnetUri: magnet}}, nil
+	}
+
+	return searchResult{}, ErrReleaseNotFound
+}
+
+// GrabRelease sends a specific release to Deluge, bypassing the scorer, and records the
+// grab against the request it was picked for. It's bound by the same deadline as a search.
+func GrabRelease(ctx context.Context, mediaType string, query string, tmdbID int, ref ReleaseRef) (*Candidate, error) {
+	if ref.GUID == "" && ref.InfoHash == "" && ref.Link == "" {
+		return nil, fmt.Errorf("%w: a guid, info hash or link is required", ErrInvalidRelease)
+	}
+	ctx, cancel := withSearchDeadline(ctx)
+	defer cancel()
+
+	candidate, err := resolveRelease(ref)
+	if err != nil {
+		return nil, err
+	}
+	candidate.mediaType = mediaType
+	candidate.query = query
+	candidate.tmdbID = tmdbID
+	candidate.manual = true
+
+	link, err := releaseLink(candidate.result)
+	if err != nil {
+		return nil, err
+	}
+	logger.WriteInfo(fmt.Sprintf("Manually grabbing %s for %s", candidate.result.Title, query))
+	if err := sendToDeluge(ctx, candidate, link); err != nil {
+		return nil, searchError(ctx, query, err)
+	}
+	releaseGrabbed(mediaType, tmdbID, query, candidate.result.Title)
+
+	grabbed := newCandidate(candidate)
+	return &grabbed, nil
+}
@@ -0,0 +1,78 @@
+package jackett
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	jackett "github.com/webtor-io/go-jackett"
+)
+
+func TestResolveRelease(t *testing.T) {
+	t.Cleanup(func() {
+		previewedMutex.Lock()
+		previewed = make(map[string]previewedRelease)
+		previewedMutex.Unlock()
+	})
+	const hash = "0123456789abcdef0123456789abcdef01234567"
+	rememberPreviewed([]searchResult{
+		{result: &jackett.Result{Guid: "guid-bluray", Title: "The.Quiet.Orbit.2017.1080p.BluRay", Link: "http://tracker/bluray.torrent", InfoHash: hash, Size: 9 << 30}},
+		{result: &jackett.Result{Guid: "guid-empty", Title: "The.Quiet.Orbit.2017.1080p.WEB", Link: "http://tracker/empty.torrent"}},
+	})
+
+	tests := []struct {
+		name      string
+		ref       ReleaseRef
+		title     string
+		link      string
+		magnet    string
+		wantError error
+	}{
+		{"previewed guid", ReleaseRef{GUID: "guid-bluray"}, "The.Quiet.Orbit.2017.1080p.BluRay", "http://tracker/bluray.torrent", "", nil},
+		{"previewed info hash", ReleaseRef{InfoHash: "0123456789ABCDEF0123456789ABCDEF01234567"}, "The.Quiet.Orbit.2017.1080p.BluRay", "http://tracker/bluray.torrent", "", nil},
+		{"bare magnet", ReleaseRef{Link: "magnet:?xt=urn:btih:" + hash, Title: "Night Harbor S01"}, "Night Harbor S01", "", "magnet:?xt=urn:btih:" + hash, nil},
+		{"bare torrent link", ReleaseRef{Link: "http://tracker/other.torrent"}, "http://tracker/other.torrent", "http://tracker/other.torrent", "", nil},
+		{"info hash", ReleaseRef{InfoHash: "fedcba9876543210fedcba9876543210fedcba98"}, "fedcba9876543210fedcba9876543210fedcba98", "", "magnet:?xt=urn:btih:fedcba9876543210fedcba9876543210fedcba98&dn=fedcba9876543210fedcba9876543210fedcba98", nil},
+		{"malformed info hash", ReleaseRef{InfoHash: "not-a-hash"}, "", "", "", ErrInvalidRelease},
+		{"zero-size preview", ReleaseRef{GUID: "guid-empty"}, "", "", "", ErrInvalidRelease},
+		{"unknown guid", ReleaseRef{GUID: "guid-missing"}, "", "", "", ErrReleaseNotFound},
+	}
+
+	for _, test := range tests {
+		sr, err := resolveRelease(test.ref)
+		if test.wantError != nil {
+			if !errors.Is(err, test.wantError) {
+				t.Errorf("%s: expected %v, got %v", test.name, test.wantError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if sr.result.Title != test.title || sr.result.Link != test.link || sr.result.MagnetUri != test.magnet {
+			t.Errorf("%s: expected %q at %q/%q, got %q at %q/%q", test.name, test.title, test.link, test.magnet,
+				sr.result.Title, sr.result.Link, sr.result.MagnetUri)
+		}
+	}
+}
+
+func TestGrabReleaseRecordsPreviewedRelease(t *testing.T) {
+	_, client := setupHarness(t, nil)
+	t.Cleanup(func() {
+		previewedMutex.Lock()
+		previewed = make(map[string]previewedRelease)
+		previewedMutex.Unlock()
+	})
+	rememberPreviewed([]searchResult{
+		{result: &jackett.Result{Guid: "guid-bluray", Title: "The.Quiet.Orbit.2017.1080p.BluRay", Link: "http://tracker/bluray.torrent", Size: 9 << 30}},
+	})
+
+	grabbed, err := GrabRelease(context.Background(), MediaMovie, "The Quiet Orbit", 0, ReleaseRef{GUID: "guid-bluray"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if links := client.links(); len(links) != 1 || links[0] != "http://tracker/bluray.torrent" || grabbed.Title != "The.Quiet.Orbit.2017.1080p.BluRay" {
+		t.Errorf("expected the previewed BluRay to be added, got %v and %+v", links, grabbed)
+	}
+}
@@ -0,0 +1,255 @@
+package jackett
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jackett "github.com/webtor-io/go-jackett"
+	"high-seas/src/deluge"
+	"high-seas/src/torrent"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the releases chosen")
+
+// fakeIndexer replays recorded Torznab fixtures for the queries a test expects. It
+// answers both Jackett's JSON results API and the Torznab endpoint, so text and ID
+// searches read the same fixtures. Unknown queries get an empty feed.
+type fakeIndexer struct {
+	t        *testing.T
+	server   *httptest.Server
+	fixtures map[string]string
+	// indexers is the fixture listing the configured indexers
+	indexers string
+	// files are the file lists of the .torrent links a test inspects; others fail to download
+	files map[string][]torrent.File
+}
+
+func newFakeIndexer(t *testing.T, fixtures map[string]string) *fakeIndexer {
+	f := &fakeIndexer{t: t, fixtures: make(map[string]string), indexers: "indexers.xml", files: make(map[string][]torrent.File)}
+	for query, fixture := range fixtures {
+		f.fixtures[strings.ToLower(query)] = fixture
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2.0/indexers/all/results", f.serveJSON)
+	mux.HandleFunc("/api/v2.0/indexers/", f.serveTorznab)
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// feed loads the fixture recorded for a query, pointing its links at the fake server
+func (f *fakeIndexer) feed(query string) []byte {
+	fixture, ok := f.fixtures[strings.ToLower(query)]
+	if !ok {
+		return []byte(`<rss version="2.0"><channel></channel></rss>`)
+	}
+	return f.load(fixture)
+}
+
+func (f *fakeIndexer) load(fixture string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", "torznab", fixture))
+	if err != nil {
+		f.t.Errorf("reading fixture: %v", err)
+		return nil
+	}
+	return []byte(strings.ReplaceAll(string(data), "{{server}}", f.server.URL))
+}
+
+func (f *fakeIndexer) serveJSON(w http.ResponseWriter, r *http.Request) {
+	results, err := parseTorznabResponse(f.feed(r.URL.Query().Get("Query")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jackett.FetchResponse{Results: results})
+}
+
+func (f *fakeIndexer) serveTorznab(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.URL.Path, "/results/torznab/api") {
+		http.NotFound(w, r)
+		return
+	}
+
+	params := r.URL.Query()
+	w.Header().Set("Content-Type", "application/rss+xml")
+	switch params.Get("t") {
+	case "indexers":
+		w.Write(f.load(f.indexers))
+	case "caps":
+		w.Write(f.load("caps.xml"))
+	default:
+		w.Write(f.feed(params.Get("q")))
+	}
+}
+
+// fetch returns a .torrent with the file list recorded for the link
+func (f *fakeIndexer) fetch(ctx context.Context, link string) (*torrent.MetaInfo, error) {
+	files, ok := f.files[link]
+	if !ok {
+		return nil, fmt.Errorf("torrent download responded with status %d", http.StatusNotFound)
+	}
+	return &torrent.MetaInfo{Name: path.Base(link), InfoHash: fakeHash(link), Files: files}, nil
+}
+
+// fakeDeluge records every link sent to the download client, rejecting the ones told to
+type fakeDeluge struct {
+	mutex   sync.Mutex
+	added   []string
+	options map[string]deluge.AddOptions
+	reject  map[string]error
+	// late links are added but answered after the deadline
+	late map[string]bool
+	// free is the space Deluge reports for every download path
+	free int64
+}
+
+func (d *fakeDeluge) add(ctx context.Context, link string, options deluge.AddOptions) (string, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if err, ok := d.reject[link]; ok {
+		return "", err
+	}
+	d.added = append(d.added, link)
+	d.options[link] = options
+	if d.late[link] {
+		return "", &deluge.TimeoutError{Operation: "add torrent", Timeout: time.Second, Err: context.DeadlineExceeded}
+	}
+	return fakeHash(link), nil
+}
+
+// status reports the added torrents among the hashes
+func (d *fakeDeluge) status(ctx context.Context, hashes []string) (map[string]deluge.Torrent, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	torrents := make(map[string]deluge.Torrent)
+	for _, link := range d.added {
+		for _, hash := range hashes {
+			if fakeHash(link) == hash {
+				torrents[hash] = deluge.Torrent{Hash: hash, State: "Downloading"}
+			}
+		}
+	}
+	return torrents, nil
+}
+
+func (d *fakeDeluge) freeSpace(ctx context.Context, path string) (int64, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.free, nil
+}
+
+// fakeHash is the info hash the fake Deluge reports for a link
+func fakeHash(link string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(link)))
+}
+
+func (d *fakeDeluge) links() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]string(nil), d.added...)
+}
+
+// setupHarness points the package at a fake indexer and download client with delays,
+// rate limits and caching turned off, restoring everything when the test ends
+func setupHarness(t *testing.T, fixtures map[string]string) (*fakeIndexer, *fakeDeluge) {
+	indexer := newFakeIndexer(t, fixtures)
+	client := &fakeDeluge{options: make(map[string]deluge.AddOptions), reject: make(map[string]error), late: make(map[string]bool), free: 1 << 50}
+
+	host, serverPort, err := net.SplitHostPort(strings.TrimPrefix(indexer.server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getIndexerLimiter()
+	oldIP, oldPort, oldDelay, oldTTL, oldLimiter, oldAdd, oldFetch, oldFreeSpace := ip, port, searchDelay, resultCacheTTL, globalLimiter, addTorrent, fetchMetaInfo, freeSpace
+	oldStatus := torrentStatus
+
+	ip, port = host, serverPort
+	searchDelay = 0
+	resultCacheTTL = 0
+	globalLimiter = newIndexerLimiter(0, 1, "")
+	addTorrent = client.add
+	fetchMetaInfo = indexer.fetch
+	freeSpace = client.freeSpace
+	torrentStatus = client.status
+	resetDiscovery()
+	resetWanted()
+	resetBlocklist()
+	resetRSS()
+
+	t.Cleanup(func() {
+		ip, port, searchDelay, resultCacheTTL, globalLimiter, addTorrent, fetchMetaInfo, freeSpace = oldIP, oldPort, oldDelay, oldTTL, oldLimiter, oldAdd, oldFetch, oldFreeSpace
+		torrentStatus = oldStatus
+		resetDiscovery()
+		resetWanted()
+		resetBlocklist()
+		resetRSS()
+	})
+	return indexer, client
+}
+
+func resetDiscovery() {
+	capabilitiesLock.Lock()
+	defer capabilitiesLock.Unlock()
+	capabilities = nil
+	capsAttemptedAt = time.Time{}
+}
+
+func resetWanted() {
+	wantedMutex.Lock()
+	defer wantedMutex.Unlock()
+	wanted = make(map[string]*wantedItem)
+	nextWantedID = 1
+}
+
+func resetRSS() {
+	rssSeenMutex.Lock()
+	defer rssSeenMutex.Unlock()
+	rssSeen = make(map[string]time.Time)
+}
+
+func resetBlocklist() {
+	blocklistMutex.Lock()
+	defer blocklistMutex.Unlock()
+	blockedHashes = make(map[string]bool)
+	blockedTitles = make(map[string]bool)
+}
+
+// checkGolden compares the links sent to Deluge with testdata/golden/<name>.golden
+func checkGolden(t *testing.T, name string, indexer *fakeIndexer, client *fakeDeluge) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".golden")
+	got := strings.ReplaceAll(strings.Join(client.links(), "\n")+"\n", indexer.server.URL, "{{server}}")
+
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("releases grabbed don't match %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package jackett
+
+import (
+	"encoding/json"
+
+	"high-seas/src/db"
+	"high-seas/src/downloads"
+	"high-seas/src/history"
+	"high-seas/src/logger"
+)
+
+// recordGrab adds a grab attempt to history along with the score breakdown
+// that selected it, so a wrong grab can be explained after the fact. Successful
+// grabs are tracked in Deluge by hash, falling back to the inspected .torrent's or
+// the indexer's info hash when Deluge already had the torrent.
+func recordGrab(candidate searchResult, link string, hash string, err error) {
+	result := candidate.result
+	if result == nil {
+		return
+	}
+
+	entry := db.GrabHistory{
+		MediaType: candidate.mediaType,
+		Query:     candidate.query,
+		TMDb:      candidate.tmdbID,
+		Title:     result.Title,
+		Indexer:   result.Tracker,
+		GUID:      result.Guid,
+		InfoHash:  result.InfoHash,
+		Link:      link,
+		Size:      result.Size,
+		Seeders:   result.Seeders,
+		Score:     candidate.score,
+		Status:    history.StatusGrabbed,
+		Manual:    candidate.manual,
+		Episodes:  candidate.episodes.String(),
+		Quality:   candidate.quality,
+	}
+
+	if candidate.breakdown != nil {
+		data, marshalErr := json.Marshal(candidate.breakdown)
+		if marshalErr != nil {
+			logger.WriteError("Failed to marshal score breakdown", marshalErr)
+		} else {
+			entry.Breakdown = data
+		}
+	}
+
+	if err != nil {
+		entry.Status = history.StatusFailed
+		entry.Error = err.Error()
+	} else if candidate.matchedTitle != "" {
+		// Search with the title that worked first next time
+		rememberAlias(aliasKind(candidate.mediaType), candidate.tmdbID, candidate.matchedTitle)
+	}
+
+	entry = history.Record(entry)
+	if err != nil {
+		return
+	}
+	if hash == "" && candidate.meta != nil {
+		hash = candidate.meta.InfoHash
+	}
+	if hash == "" {
+		hash = result.InfoHash
+	}
+	downloads.Track(hash, entry)
+}
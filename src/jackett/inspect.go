@@ -0,0 +1,141 @@
+package jackett
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"high-seas/src/db"
+	"high-seas/src/logger"
+	"high-seas/src/torrent"
+)
+
+var (
+	// unsafeExtensions are files no media release should contain
+	unsafeExtensions = map[string]bool{
+		".exe": true, ".msi": true, ".bat": true, ".cmd": true, ".com": true, ".scr": true, ".pif": true,
+		".lnk": true, ".vbs": true, ".js": true, ".ps1": true, ".jar": true, ".dll": true, ".apk": true,
+	}
+	// archivePattern matches archives, including the .r00 and .001 parts of split ones
+	archivePattern = regexp.MustCompile(`(?i)\.(rar|zip|7z|r\d{2}|\d{3})$`)
+	// passwordPattern matches the files that come with password-protected archives
+	passwordPattern = regexp.MustCompile(`(?i)pass(word|wd)`)
+
+	// fetchMetaInfo downloads and parses a .torrent; tests swap in a fake
+	fetchMetaInfo = torrent.Fetch
+
+	// errArchivedRelease rejects releases packed in archives with no password, which
+	// can't be imported but aren't worth blocklisting
+	errArchivedRelease = errors.New("archived release without video files")
+)
+
+// inspect downloads a release's .torrent once, keeping it on the result. Magnets carry no
+// file list, so a magnet is inspected through the indexer's .torrent link when there is one.
+// It returns nil when the file list can't be had.
+func (sr *searchResult) inspect(ctx context.Context) *torrent.MetaInfo {
+	if sr.inspected || sr.result == nil {
+		return sr.meta
+	}
+	sr.inspected = true
+
+	link := sr.result.Link
+	if link == "" {
+		logger.WriteInfo(fmt.Sprintf("%s is a bare magnet, adding it without inspecting its files", sr.result.Title))
+		return nil
+	}
+
+	meta, err := fetchMetaInfo(ctx, link)
+	if err != nil {
+		var magnet *torrent.MagnetRedirect
+		if !errors.As(err, &magnet) {
+			logger.WriteWarning(fmt.Sprintf("Couldn't read the file list of %s, trusting its title: %v", sr.result.Title, err))
+		}
+		return nil
+	}
+	sr.meta = meta
+	return meta
+}
+
+// fileCoverage returns the episodes the release's video files are named for, empty when
+// they carry no episode markers or the file list can't be had
+func (sr *searchResult) fileCoverage(ctx context.Context, seasons []int) episodeSet {
+	meta := sr.inspect(ctx)
+	if meta == nil {
+		return episodeSet{}
+	}
+	return coverageFromFiles(meta.Files, seasons)
+}
+
+// checkRelease inspects a release's files before it's added, rejecting unsafe or unusable
+// contents and show releases missing the episodes they're grabbed for, then makes sure
+// there's room for it. Releases with unsafe contents are blocklisted; plain archives are
+// only passed over.
+func checkRelease(ctx context.Context, sr *searchResult) error {
+	if meta := sr.inspect(ctx); meta != nil {
+		err := checkContents(meta.Files)
+		if errors.Is(err, errArchivedRelease) {
+			return fmt.Errorf("%w: %w", ErrInvalidRelease, err)
+		}
+		if err != nil {
+			blockRelease(db.BlockedRelease{
+				MediaType: sr.mediaType,
+				Query:     sr.query,
+				TMDb:      sr.tmdbID,
+				Title:     sr.result.Title,
+				InfoHash:  meta.InfoHash,
+				Reason:    err.Error(),
+			})
+			return fmt.Errorf("%w: %w", ErrInvalidRelease, err)
+		}
+
+		if len(sr.episodes) > 0 {
+			covers := coverageFromFiles(meta.Files, nil)
+			// File names without episode markers leave the title to go by
+			if missing := sr.episodes.missing(covers); len(covers) > 0 && len(missing) > 0 {
+				return fmt.Errorf("%w: files don't contain %s", ErrInvalidRelease, newEpisodeSet(missing))
+			}
+		}
+	}
+
+	return checkDiskSpace(ctx, sr)
+}
+
+// checkContents rejects file lists with executables or shortcuts, password-protected
+// archives, archives without loose video files, or nothing but samples
+func checkContents(files []torrent.File) error {
+	var videos, samples, archives int
+	hasPassword := false
+	for _, file := range files {
+		name := strings.ToLower(path.Base(file.Path))
+		extension := path.Ext(name)
+
+		switch {
+		case unsafeExtensions[extension]:
+			return fmt.Errorf("contains %s", path.Base(file.Path))
+		case videoExtensions[extension] && strings.Contains(name, "sample"):
+			samples++
+		case videoExtensions[extension]:
+			videos++
+		case archivePattern.MatchString(name):
+			archives++
+		}
+		if passwordPattern.MatchString(name) {
+			hasPassword = true
+		}
+	}
+
+	switch {
+	case archives > 0 && hasPassword:
+		return errors.New("password-protected archive")
+	case archives > 0 && videos == 0:
+		return errArchivedRelease
+	case videos == 0 && samples > 0:
+		return errors.New("only samples")
+	case videos == 0:
+		return errors.New("no video files")
+	}
+	return nil
+}
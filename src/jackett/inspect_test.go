@@ -0,0 +1,73 @@
+package jackett
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	jackett "github.com/webtor-io/go-jackett"
+	"high-seas/src/torrent"
+)
+
+func TestMakeMovieQueryRejectsReleaseWithExecutable(t *testing.T) {
+	indexer, client := setupHarness(t, map[string]string{
+		`"The Quiet Orbit" 1080p`: "movie_quiet_orbit.xml",
+	})
+	bluray := indexer.server.URL + "/download/quiet-orbit-2017-bluray.torrent"
+	indexer.files[bluray] = []torrent.File{
+		{Path: "The.Quiet.Orbit.2017.1080p.BluRay.x264-GRP/The.Quiet.Orbit.2017.1080p.BluRay.x264-GRP.mkv", Length: 9 << 30},
+		{Path: "The.Quiet.Orbit.2017.1080p.BluRay.x264-GRP/Codec.Setup.exe", Length: 1 << 20},
+	}
+
+	// The BluRay is the only 1080p release of the right movie, so nothing is left to grab
+	if err := MakeMovieQuery(context.Background(), "The Quiet Orbit", 0, 2017, "1080p"); err == nil {
+		t.Error("expected the search to fail without a safe release")
+	}
+	if links := client.links(); len(links) != 0 {
+		t.Errorf("expected nothing to be added, got %v", links)
+	}
+	if !isBlocked(&jackett.Result{Title: "The.Quiet.Orbit.2017.1080p.BluRay.x264-GRP"}) {
+		t.Error("the release with an executable should be blocklisted")
+	}
+}
+
+func TestCheckContents(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  []string
+		reject bool
+	}{
+		{"episode with subtitles", []string{"Show.S01E01.mkv", "Show.S01E01.en.srt"}, false},
+		{"shortcut", []string{"Show.S01E01.mkv", "Show.S01E01.lnk"}, true},
+		{"archived", []string{"show.s01e01.rar", "show.s01e01.r00", "show.s01e01.nfo"}, true},
+		{"password file", []string{"Movie.mkv", "extras.zip", "Password.txt"}, true},
+		{"only a sample", []string{"Movie.sample.mkv", "Movie.nfo"}, true},
+	}
+
+	for _, test := range tests {
+		var files []torrent.File
+		for _, name := range test.files {
+			files = append(files, torrent.File{Path: "Release/" + name, Length: 1})
+		}
+		if err := checkContents(files); (err != nil) != test.reject {
+			t.Errorf("%s: expected rejection %v, got %v", test.name, test.reject, err)
+		}
+	}
+}
+
+func TestCheckReleasePassesOverPlainArchives(t *testing.T) {
+	indexer, _ := setupHarness(t, nil)
+	link := indexer.server.URL + "/download/quiet-orbit-2017-bluray.torrent"
+	indexer.files[link] = []torrent.File{
+		{Path: "The.Quiet.Orbit.2017.1080p.BluRay.x264-GRP/the.quiet.orbit.2017.rar", Length: 9 << 30},
+		{Path: "The.Quiet.Orbit.2017.1080p.BluRay.x264-GRP/the.quiet.orbit.2017.r00", Length: 1 << 30},
+	}
+
+	sr := &searchResult{mediaType: MediaMovie, result: &jackett.Result{Title: "The.Quiet.Orbit.2017.1080p.BluRay.x264-GRP", Link: link}}
+	if err := checkRelease(context.Background(), sr); !errors.Is(err, ErrInvalidRelease) {
+		t.Fatalf("expected the archived release to be rejected, got %v", err)
+	}
+	if isBlocked(sr.result) {
+		t.Error("an archive without a password shouldn't be blocklisted")
+	}
+}
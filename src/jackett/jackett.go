@@ -2,12 +2,15 @@ package jackett
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	jackett "github.com/webtor-io/go-jackett"
 	"high-seas/src/deluge"
 	"high-seas/src/logger"
+	"high-seas/src/torrent"
 	"high-seas/src/utils"
 	"math"
+	"net/url"
 	"regexp"
 	"sort"
 	"strings"
@@ -25,87 +28,196 @@ const (
 	QUALITY_WEIGHT       = 0.2  // Kept the same
 	SIZE_WEIGHT          = 0.1  // Kept the same
 	MIN_ACCEPTABLE_SCORE = 0.5  // Reduced from 0.6
+
+	ID_MATCH_BONUS             = 0.3 // Reduced from 0.5 since we now have title matching
+	MIN_ACCEPTABLE_ANIME_SCORE = 0.3
 )
 
 // Common constants and categories
-const (
+var (
+	// searchDelay spaces out consecutive queries; a variable so tests can skip it
 	searchDelay = 500 * time.Millisecond
+	// addTorrent sends a link to the download client; tests swap in a fake
+	addTorrent = deluge.AddTorrent
+	// torrentStatus asks the download client which torrents it has; tests swap in a fake
+	torrentStatus = deluge.TorrentsStatus
+	// torrentOptions are the save paths, labels and flags each media type is added with,
+	// from DELUGE_MOVIE_PATH, DELUGE_SHOW_LABEL and so on
+	torrentOptions = map[string]deluge.AddOptions{
+		MediaMovie:      deluge.OptionsFromEnv("movie"),
+		MediaShow:       deluge.OptionsFromEnv("show"),
+		MediaAnimeMovie: deluge.OptionsFromEnv("anime_movie"),
+		MediaAnimeShow:  deluge.OptionsFromEnv("anime_show"),
+	}
 )
 
+// Search patterns for anime, formatted with the query (and quality for movies)
 var (
-	animeMovieCategories  = []uint{2000, 2010, 100001}
-	animeSeriesCategories = []uint{100060, 140679, 5070}
+	animeTimeBatchPatterns = []string{
+		`[Anime Time] %s (Series+Movies) 2011 [Dual Audio][BD][1080p][HEVC 10bit x265][AAC][Eng Sub] [Batch]`,
+		// Slightly more generic fallbacks but still maintaining Anime Time format
+		`[Anime Time] %s [Dual Audio][BD][1080p][HEVC 10bit x265][AAC][Eng Sub] [Batch]`,
+		`[Anime Time] %s [Batch]`,
+	}
+	fallbackBatchPatterns = []string{
+		`[AnimeSkulls] %s (2011) [Batch] [Dual Audio][1080p][HEVC 10bit x265]`,
+		`%s complete series`,
+		`%s batch`,
+	}
+	animeMoviePatterns = []string{
+		"%s %s [1080p]",
+		"[Anime] %s %s",
+		"%s [BD] %s",
+	}
 )
 
 type searchResult struct {
-	result *jackett.Result
-	score  float64
+	result    *jackett.Result
+	score     float64
+	breakdown *ScoreBreakdown
+	mediaType string
+	query     string
+	tmdbID    int
+	quality   string
+
+	// matchedTitle is the title variant the release name matched, if any
+	matchedTitle string
+	// episodes are the requested episodes a show release was grabbed for
+	episodes episodeSet
+	// manual is set when a user picked the release rather than the scorer
+	manual bool
+	// meta is the release's .torrent, once inspect has fetched it
+	meta      *torrent.MetaInfo
+	inspected bool
 }
 
 // Make sure MakeMovieQuery uses the same pattern as MakeShowQuery
-func MakeMovieQuery(query string, tmdbID int, quality string) error {
-	ctx := context.Background()
+func MakeMovieQuery(ctx context.Context, query string, tmdbID int, year int, quality string) error {
+	ctx, cancel := withSearchDeadline(ctx)
+	defer cancel()
 	j := jackett.NewJackett(&jackett.Settings{
 		ApiURL: fmt.Sprintf("http://%s:%s/", ip, port),
 		ApiKey: fmt.Sprintf("%s", apiKey),
 	})
 
-	// Try multiple search strategies for better results
-	searchStrategies := []string{
-		fmt.Sprintf("\"%s\" %s", query, quality), // Exact title with quotes
-		fmt.Sprintf("%s %s", query, quality),      // Regular search
-		query, // Title only as fallback
+	ensureCapabilities(ctx)
+	identity := resolveMovieIdentity(query, tmdbID, year)
+	if err := awaitMovieRelease(MediaMovie, query, identity, quality); err != nil {
+		return err
 	}
 
-	logger.WriteInfo(fmt.Sprintf("Searching for movie: %s", query))
+	// Try multiple search strategies for better results
+	searchStrategies := movieSearchQueries(query, quality, identity)
 
-	for i, queryString := range searchStrategies {
-		logger.WriteInfo(fmt.Sprintf("Movie search strategy %d: %s", i+1, queryString))
+	logger.WriteInfo(fmt.Sprintf("Searching for movie: %s (year %d, IMDb %s, titles %q)",
+		query, identity.year, identity.imdbID, identity.titles))
+
+	for i, searchQuery := range searchStrategies {
+		if ctx.Err() != nil {
+			break
+		}
+		logger.WriteInfo(fmt.Sprintf("Movie search strategy %d: %s", i+1, searchQuery))
 		
-		resp, err := j.Fetch(ctx, &jackett.FetchRequest{
-			Categories: []uint{2000, 2010, 2020, 2030, 2040, 2050, 2060, 2070, 2080}, // Movie categories
-			Query:      queryString,
-		})
+		resp, err := fetchResults(ctx, j, searchQuery)
 		if err != nil {
-			logger.WriteError(fmt.Sprintf("Strategy %d failed", i+1), err)
+			logFetchError(fmt.Sprintf("Strategy %d failed", i+1), err)
 			continue
 		}
 
-		results := processMovieResults(resp.Results, tmdbID, quality, query)
+		results := processMovieResults(resp, identity, quality, query)
 		if len(results) > 0 {
-			if addTorrentToDeluge(results[0].result) {
+			if addTorrentToDeluge(ctx, results[0]) {
+				fulfilMovie(MediaMovie, tmdbID, query)
 				return nil
 			}
 		}
 		
 		// Add delay between strategies
-		time.Sleep(500 * time.Millisecond)
+		if pause(ctx, searchDelay) != nil {
+			break
+		}
+	}
+
+	// Keep watching the RSS feeds for a release
+	wantMovie(MediaMovie, query, identity, quality)
+	return searchError(ctx, query, fmt.Errorf("%w for movie: %s", ErrNoMatches, query))
+}
+
+// movieSearchQueries returns the queries tried for a movie, most specific first.
+// An ID search comes first when we know the movie's IMDb or TMDb ID and the indexers
+// accept it, then each title variant is searched in turn.
+func movieSearchQueries(query, quality string, identity mediaIdentity) []searchQuery {
+	var queries []searchQuery
+	if identity.imdbID != "" && supportsParams(torznabMovie, "imdbid") {
+		queries = append(queries, searchQuery{searchType: torznabMovie, imdbID: identity.imdbID, categories: searchCategories(MediaMovie)})
+	} else if identity.tmdbID > 0 && supportsParams(torznabMovie, "tmdbid") {
+		queries = append(queries, searchQuery{searchType: torznabMovie, tmdbID: identity.tmdbID, categories: searchCategories(MediaMovie)})
+	}
+
+	titles := identity.titles
+	if len(titles) == 0 {
+		titles = []string{query}
+	}
+	for _, title := range titles {
+		queries = append(queries, textQueries(searchCategories(MediaMovie),
+			fmt.Sprintf("\"%s\" %s", title, quality), // Exact title with quotes
+			fmt.Sprintf("%s %s", title, quality),     // Regular search
+			title,                                    // Title only as fallback
+		)...)
+	}
+	return queries
+}
+
+// evaluateMovieResults scores every movie result, keeping rejected ones with their reason
+func evaluateMovieResults(results []jackett.Result, identity mediaIdentity, quality string, exactTitle string) []searchResult {
+	evaluated := make([]searchResult, 0, len(results))
+
+	for _, result := range results {
+		var breakdown *ScoreBreakdown
+
+		// Reject results that aren't this movie by ID, title or year
+		matchedTitle, reason := identityRejection(&result, identity, exactTitle, isExactMovieMatch)
+		if reason == "" && isBlocked(&result) {
+			reason = "blocklisted release"
+		}
+		if reason != "" {
+			breakdown = newScoreBreakdown(MIN_ACCEPTABLE_SCORE)
+			breakdown.reject(reason)
+		} else {
+			breakdown = calculateScore(&result, identity, quality)
+		}
+		breakdown.finalize()
+
+		evaluated = append(evaluated, searchResult{
+			result:       &result,
+			score:        breakdown.Total,
+			breakdown:    breakdown,
+			mediaType:    MediaMovie,
+			query:        exactTitle,
+			tmdbID:       identity.tmdbID,
+			quality:      quality,
+			matchedTitle: matchedTitle,
+		})
 	}
 
-	return fmt.Errorf("no suitable matches found for movie: %s", query)
+	return evaluated
 }
 
 // Specialized function for processing movie results with better validation
-func processMovieResults(results []jackett.Result, tmdbID int, quality string, exactTitle string) []searchResult {
+func processMovieResults(results []jackett.Result, identity mediaIdentity, quality string, exactTitle string) []searchResult {
 	var scoredResults []searchResult
 
 	logger.WriteInfo(fmt.Sprintf("Processing %d movie results", len(results)))
 
-	for _, result := range results {
-		// Skip results that don't match the exact movie title
-		if !isExactMovieMatch(result.Title, exactTitle) {
-			logger.WriteInfo(fmt.Sprintf("Skipping non-matching movie title: %s", result.Title))
+	for _, candidate := range evaluateMovieResults(results, identity, quality, exactTitle) {
+		if !candidate.breakdown.Accepted {
+			logger.WriteInfo(fmt.Sprintf("Skipping movie result: %s (%s)", candidate.result.Title, candidate.breakdown.Rejection))
 			continue
 		}
 
-		score := calculateScore(&result, tmdbID, quality)
-		if score >= MIN_ACCEPTABLE_SCORE {
-			scoredResults = append(scoredResults, searchResult{
-				result: &result,
-				score:  score,
-			})
-			logger.WriteInfo(fmt.Sprintf("Added movie candidate: %s (Score: %.2f)", result.Title, score))
-		}
+		scoredResults = append(scoredResults, candidate)
+		logger.WriteInfo(fmt.Sprintf("Added movie candidate: %s (Score: %.2f) [%s]",
+			candidate.result.Title, candidate.score, candidate.breakdown))
 	}
 
 	// Sort by score first, then by seeders
@@ -147,20 +259,31 @@ func isExactMovieMatch(resultTitle, exactTitle string) bool {
 	remainder := strings.TrimSpace(strings.TrimPrefix(cleanResultTitle, cleanExactTitle))
 	
 	if remainder != "" {
-		// Movie-specific spinoff/sequel indicators
-		movieSpinoffIndicators := []string{
+		// Sequel markers sit between the title and the release year, so only that
+		// part is checked; the year itself would otherwise look like a sequel number
+		movieSequelIndicators := []string{
 			"ii", "iii", "iv", "v", "vi", "vii", "viii", "ix", "x",
 			"2", "3", "4", "5", "6", "7", "8", "9",
-			"part 2", "part 3", "part ii", "part iii",
+			"part 2", "part 3", "part ii", "part iii", "part two", "part three",
 			"sequel", "prequel", "origins", "begins", "returns",
 			"reloaded", "revolution", "resurrection", "redemption",
+		}
+
+		segment := sequelSegment(resultTitle, exactTitle)
+		for _, indicator := range movieSequelIndicators {
+			if containsWords(segment, indicator) {
+				return false
+			}
+		}
+
+		// Edition tags can appear anywhere after the title
+		movieEditionIndicators := []string{
 			"extended cut", "director cut", "unrated", "theatrical",
 			"special edition", "ultimate edition", "collectors edition",
 		}
-		
-		lowerRemainder := strings.ToLower(remainder)
-		for _, indicator := range movieSpinoffIndicators {
-			if strings.Contains(lowerRemainder, indicator) {
+
+		for _, indicator := range movieEditionIndicators {
+			if containsWords(remainder, indicator) {
 				return false
 			}
 		}
@@ -169,201 +292,346 @@ func isExactMovieMatch(resultTitle, exactTitle string) bool {
 	return true
 }
 
-func MakeShowQuery(query string, seasons []int, tmdbID int, quality string) error {
-	ctx := context.Background()
+// MakeShowQuery grabs the selected seasons of a show, preferring packs and falling back to
+// single episodes. The season counts come from TMDb when it knows the show.
+func MakeShowQuery(ctx context.Context, query string, seasons []int, selection ShowSelection, tmdbID int, year int, quality string) error {
+	ctx, cancel := withSearchDeadline(ctx)
+	defer cancel()
 	j := jackett.NewJackett(&jackett.Settings{
 		ApiURL: fmt.Sprintf("http://%s:%s/", ip, port),
 		ApiKey: fmt.Sprintf("%s", apiKey),
 	})
 
+	ensureCapabilities(ctx)
+	identity := resolveShowIdentity(query, tmdbID, year)
+	seasons, selected, err := selectEpisodes(query, identity, seasons, selection)
+	if err != nil {
+		return err
+	}
+	selected = skipGrabbed(MediaShow, query, identity, selected)
+	monitored := !selection.partial()
+
 	totalSeasons := len(seasons)
-	logger.WriteInfo(fmt.Sprintf("Starting search for %s with %d total seasons", query, totalSeasons))
+	logger.WriteInfo(fmt.Sprintf("Starting search for %s with %d total seasons (year %d, TVDB %d, titles %q)",
+		query, totalSeasons, identity.year, identity.tvdbID, identity.titles))
 
 	// Print out the episode counts for each season for debugging
 	for i, count := range seasons {
 		logger.WriteInfo(fmt.Sprintf("Season %d has %d episodes", i+1, count))
 	}
 
-	// Step 1: Try complete series bundle
-	if searchFullSeriesBundle(ctx, j, query, seasons, tmdbID, quality) {
-		return nil
+	// Episodes that haven't aired are left for the wanted list to search for when they do
+	aired, upcoming := identity.release.splitAired(selected)
+	logUpcoming(query, upcoming, identity.release)
+	if len(aired) == 0 {
+		wantShow(MediaShow, query, identity, quality, seasons, selected, upcoming, nil, monitored)
+		return notReleased(query, upcoming)
 	}
 
-	// Step 2: Try season bundles or individual episodes
-	currentSeason := 1
-	for currentSeason <= totalSeasons {
-		// Try to find season pack first
-		if found := searchCompleteSeason(ctx, j, query, currentSeason, seasons[currentSeason-1], tmdbID, quality); !found {
-			// If season pack not found, search episode by episode
-			logger.WriteInfo(fmt.Sprintf("No season pack found for season %d, searching %d individual episodes",
-				currentSeason, seasons[currentSeason-1]))
-			searchSeasonEpisodesByOne(ctx, j, query, currentSeason, tmdbID, quality, seasons[currentSeason-1])
-		}
-		currentSeason++
+	wanted := newEpisodeSet(aired)
+	planner := newEpisodePlanner(wanted, episodeSet{}, nil)
+
+	// Step 1: Try complete series bundles, which may also be multi-season packs like S01-S03
+	planner.add(gatherShowPacks(ctx, j, query, identity, quality, seasons, wanted, func(title string) []searchQuery {
+		return seriesBundleQueries(title, seasons, quality)
+	}))
+	grabPlannedPacks(ctx, planner, seasons)
+
+	// Step 2: Try season packs for every season that still has gaps
+	if gaps := planner.gaps(); len(gaps) > 0 {
+		planner.add(gatherShowPacks(ctx, j, query, identity, quality, seasons, wanted, func(title string) []searchQuery {
+			var packQueries []searchQuery
+			for _, season := range seasonsWithGaps(gaps) {
+				packQueries = append(packQueries, seasonPackQueries(title, season, quality, identity)...)
+			}
+			return packQueries
+		}))
+		grabPlannedPacks(ctx, planner, seasons)
 	}
 
-	return nil
+	// Step 3: Fall back to individual episodes for whatever the packs didn't cover
+	if gaps := planner.gaps(); len(gaps) > 0 {
+		logger.WriteInfo(fmt.Sprintf("Packs cover %d/%d episodes, searching %d individual episodes",
+			len(wanted)-len(gaps), len(wanted), len(gaps)))
+		searchMissingEpisodes(ctx, j, query, identity, quality, seasons, planner)
+	}
+
+	if gaps := planner.gaps(); len(gaps) > 0 {
+		logger.WriteWarning(fmt.Sprintf("Missing episodes for %s: %s", query, newEpisodeSet(gaps)))
+	}
+
+	// RSS sync picks up the missing episodes, and new ones as they air unless only part of
+	// the show was asked for
+	wantShow(MediaShow, query, identity, quality, seasons, selected, append(planner.gaps(), upcoming...), nil, monitored)
+	var nothingGrabbed error
+	if len(planner.covered) == 0 && len(planner.gaps()) > 0 {
+		nothingGrabbed = fmt.Errorf("%w for show: %s", ErrNoMatches, query)
+	}
+	return searchError(ctx, query, nothingGrabbed)
 }
 
-func searchCompleteSeason(ctx context.Context, j *jackett.Jackett, query string, season, episodeCount, tmdbID int, quality string) bool {
+// seasonPackQueries returns the queries tried when looking for a full season pack,
+// starting with a season search by ID when the show's IDs are known
+func seasonPackQueries(query string, season int, quality string, identity mediaIdentity) []searchQuery {
+	var queries []searchQuery
+	if idQuery, ok := showIDQuery(identity, season, 0); ok {
+		queries = append(queries, idQuery)
+	}
+
 	seasonFormat := fmt.Sprintf("S%02d", season)
-	searchQueries := []string{
+	return append(queries, textQueries(searchCategories(MediaShow),
 		fmt.Sprintf("%s %s season %s", query, seasonFormat, quality),
 		fmt.Sprintf("%s complete %s %s", query, seasonFormat, quality),
 		fmt.Sprintf("%s %s complete %s", query, seasonFormat, quality),
-	}
+	)...)
+}
 
-	for _, queryString := range searchQueries {
-		logger.WriteInfo(fmt.Sprintf("Searching for complete season %d (%d episodes) with query: %s",
-			season, episodeCount, queryString))
+// seriesBundleQueries returns the queries tried when looking for a complete series pack.
+// These stay text-only: an ID search returns every episode and the largest would win.
+func seriesBundleQueries(query string, seasons []int, quality string) []searchQuery {
+	return textQueries(searchCategories(MediaShow),
+		fmt.Sprintf("%s complete series %s", query, quality),
+		fmt.Sprintf("%s season 1-%d %s", query, len(seasons), quality),
+	)
+}
 
-		resp, err := j.Fetch(ctx, &jackett.FetchRequest{
-			Categories: []uint{5000, 5020, 5030, 5040, 5045},
-			Query:      queryString,
-		})
-		if err != nil {
-			continue
-		}
+// gatherShowPacks runs pack queries for each title variant in turn and returns every
+// accepted release that covers at least one wanted episode. Later titles are only
+// searched when the earlier ones found nothing.
+func gatherShowPacks(ctx context.Context, j *jackett.Jackett, query string, identity mediaIdentity, quality string, seasons []int, wanted episodeSet, queriesFor func(title string) []searchQuery) []*coverageCandidate {
+	var candidates []*coverageCandidate
+	searchedIDs := false
 
-		results := processResults(resp.Results, tmdbID, quality, query)
-		if len(results) > 0 {
-			seasonResults := filterSeasonPacks(results, season, episodeCount)
-			if len(seasonResults) > 0 {
-				bestResult := selectBestResult(seasonResults)
-				if bestResult != nil && addTorrentToDeluge(bestResult) {
-					logger.WriteInfo(fmt.Sprintf("Successfully added complete season %d (Size: %.2f GB)",
-						season, float64(bestResult.Size)/1024/1024/1024))
-					return true
+	for _, title := range showTitles(query, identity) {
+		var accepted []searchResult
+
+		for _, searchQuery := range queriesFor(title) {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			// ID searches don't depend on the title, so run them once
+			if searchQuery.hasIDs() {
+				if searchedIDs {
+					continue
 				}
+				searchedIDs = true
 			}
+
+			logger.WriteInfo(fmt.Sprintf("Searching for packs with query: %s", searchQuery))
+
+			resp, err := fetchResults(ctx, j, searchQuery)
+			if err != nil {
+				logFetchError("Pack query failed", err)
+				continue
+			}
+
+			accepted = append(accepted, processResults(resp, identity, quality, query)...)
+		}
+
+		candidates = newCoverageCandidates(accepted, seasons, wanted)
+		if len(candidates) > 0 {
+			break
 		}
 	}
 
-	return false
+	logger.WriteInfo(fmt.Sprintf("Found %d pack candidates covering wanted episodes", len(candidates)))
+	return candidates
 }
 
-// New function to filter for actual season packs
-func filterSeasonPacks(results []searchResult, season, expectedEpisodeCount int) []searchResult {
-	var seasonPacks []searchResult
-	seasonStr := fmt.Sprintf("S%02d", season)
-	episodePattern := regexp.MustCompile(fmt.Sprintf("S%02dE\\d+", season))
+// showTitles returns the title variants to search for, falling back to the client's query
+func showTitles(query string, identity mediaIdentity) []string {
+	if len(identity.titles) == 0 {
+		return []string{query}
+	}
+	return identity.titles
+}
 
-	for _, result := range results {
-		title := strings.ToLower(result.result.Title)
-		// Check if it contains the season number and doesn't contain episode numbers
-		if strings.Contains(title, strings.ToLower(seasonStr)) && !episodePattern.MatchString(title) {
-			// Look for season pack indicators
-			if strings.Contains(title, "complete") ||
-				strings.Contains(title, "season") ||
-				!strings.Contains(title, "e") { // No episode marker
-				seasonPacks = append(seasonPacks, result)
+// grabPlannedPacks grabs releases in planner order, checking a pack's file list before
+// trusting its title so partial packs only count for the episodes they contain
+func grabPlannedPacks(ctx context.Context, planner *episodePlanner, seasons []int) {
+	for candidate := planner.next(); candidate != nil && ctx.Err() == nil; candidate = planner.next() {
+		if candidate.isPack() && !candidate.verified {
+			candidate.verified = true
+			if verifyPackCoverage(ctx, candidate, seasons, planner.wanted) {
+				// Coverage changed, so let the planner pick again
+				continue
 			}
 		}
-	}
 
-	return seasonPacks
+		candidate.episodes = candidate.covers
+		if addTorrentToDeluge(ctx, candidate.searchResult) {
+			logger.WriteInfo(fmt.Sprintf("Successfully added %s covering %s (Size: %.2f GB)",
+				candidate.result.Title, candidate.covers, float64(candidate.result.Size)/1024/1024/1024))
+			planner.markGrabbed(candidate)
+		} else {
+			planner.drop(candidate)
+		}
+	}
 }
 
-func searchFullSeriesBundle(ctx context.Context, j *jackett.Jackett, query string, seasons []int, tmdbID int, quality string) bool {
-	searchQueries := []string{
-		fmt.Sprintf("%s complete series %s", query, quality),
-		fmt.Sprintf("%s season 1-%d %s", query, len(seasons), quality),
+// verifyPackCoverage replaces title-parsed coverage with the torrent's file list when the
+// .torrent can be downloaded. It reports whether the coverage changed.
+func verifyPackCoverage(ctx context.Context, candidate *coverageCandidate, seasons []int, wanted episodeSet) bool {
+	contains := candidate.fileCoverage(ctx, seasons)
+	fromFiles := contains.intersect(wanted)
+	if len(fromFiles) == 0 {
+		// File names carry no episode markers, so the title is all we have
+		return false
+	}
+	if len(fromFiles) == len(candidate.covers) && len(fromFiles.intersect(candidate.covers)) == len(fromFiles) {
+		return false
 	}
 
-	for _, queryString := range searchQueries {
-		logger.WriteInfo(fmt.Sprintf("Searching for complete series with query: %s", queryString))
+	logger.WriteInfo(fmt.Sprintf("File list of %s covers %s, not %s as its title suggests",
+		candidate.result.Title, fromFiles, candidate.covers))
+	candidate.covers = fromFiles
+	candidate.extra = len(contains) - len(fromFiles)
+	return true
+}
 
-		resp, err := j.Fetch(ctx, &jackett.FetchRequest{
-			Categories: []uint{5000, 5020, 5030, 5040, 5045},
-			Query:      queryString,
-		})
-		if err != nil {
+// searchMissingEpisodes searches for the episodes the packs didn't cover on the worker pool,
+// then grabs in episode order. A multi-episode release grabbed along the way fills the
+// later gaps it covers too.
+func searchMissingEpisodes(ctx context.Context, j *jackett.Jackett, query string, identity mediaIdentity, quality string, seasons []int, planner *episodePlanner) {
+	gaps := planner.gaps()
+	successCount := 0
+
+	found := searchEpisodes(ctx, gaps, func(ctx context.Context, gap episodeKey) []searchResult {
+		return searchShowEpisode(ctx, j, query, identity, quality, seasons, planner.wanted, gap)
+	})
+
+	for i, gap := range gaps {
+		if ctx.Err() != nil {
+			logger.WriteWarning(fmt.Sprintf("Episode search for %s cancelled: %v", query, ctx.Err()))
+			return
+		}
+		if planner.covered[gap] {
 			continue
 		}
 
-		results := processResults(resp.Results, tmdbID, quality, query)
-		if len(results) > 0 {
-			bestResult := selectBestResult(results)
-			if bestResult != nil && addTorrentToDeluge(bestResult) {
-				logger.WriteInfo("Successfully added complete series")
-				return true
+		if len(found[i]) == 0 {
+			logger.WriteWarning(fmt.Sprintf("No results found for %s", gap))
+			continue
+		}
+
+		// Fall back to the next release if Deluge rejects the best one
+		for _, result := range found[i] {
+			result.episodes = parseCoverage(result.result.Title, seasons).intersect(planner.wanted)
+			// The file list knows better than the title which episodes are inside
+			if fromFiles := result.fileCoverage(ctx, seasons); len(fromFiles) > 0 {
+				result.episodes = fromFiles.intersect(planner.wanted)
+				if !result.episodes[gap] {
+					logger.WriteInfo(fmt.Sprintf("Skipping %s, its files don't contain %s", result.result.Title, gap))
+					continue
+				}
+			}
+			if addTorrentToDeluge(ctx, result) {
+				successCount++
+				planner.markGrabbed(&coverageCandidate{searchResult: result, covers: result.episodes})
+				logger.WriteInfo(fmt.Sprintf("Successfully added %s (%d/%d)", gap, successCount, len(gaps)))
+				break
 			}
 		}
 	}
 
-	return false
+	logger.WriteInfo(fmt.Sprintf("Found %d individual releases for %d missing episodes", successCount, len(gaps)))
 }
 
-// Modified searchSeasonEpisodesByOne to ensure we get every episode
-func searchSeasonEpisodesByOne(ctx context.Context, j *jackett.Jackett, query string, season, tmdbID int, quality string, episodeCount int) bool {
-	logger.WriteInfo(fmt.Sprintf("Searching for %d individual episodes of season %d", episodeCount, season))
-	successCount := 0
-	var missingEpisodes []int
-
-	for episode := 1; episode <= episodeCount; episode++ {
-		episodeFormat := fmt.Sprintf("S%02dE%02d", season, episode)
-		// Add quotes around the show title to ensure exact matching
-		queryString := fmt.Sprintf("\"%s\" %s %s", query, episodeFormat, quality)
+// searchShowEpisode returns the accepted releases containing one episode, largest first,
+// from the first query that finds any
+func searchShowEpisode(ctx context.Context, j *jackett.Jackett, query string, identity mediaIdentity, quality string, seasons []int, wanted episodeSet, gap episodeKey) []searchResult {
+	// Search by ID first, then each title variant with quotes to ensure exact matching
+	var episodeQueries []searchQuery
+	if idQuery, ok := showIDQuery(identity, gap.season, gap.episode); ok {
+		episodeQueries = append(episodeQueries, idQuery)
+	}
+	for _, title := range showTitles(query, identity) {
+		episodeQueries = append(episodeQueries, textQueries(searchCategories(MediaShow),
+			fmt.Sprintf("\"%s\" %s %s", title, gap, quality))...)
+	}
 
-		logger.WriteInfo(fmt.Sprintf("Searching for episode: %s", queryString))
+	for _, searchQuery := range episodeQueries {
+		logger.WriteInfo(fmt.Sprintf("Searching for episode: %s", searchQuery))
 
-		resp, err := j.Fetch(ctx, &jackett.FetchRequest{
-			Categories: []uint{5000, 5020, 5030, 5040, 5045},
-			Query:      queryString,
-		})
+		resp, err := fetchResults(ctx, j, searchQuery)
 		if err != nil {
-			missingEpisodes = append(missingEpisodes, episode)
+			if ctx.Err() != nil {
+				return nil
+			}
+			logFetchError(fmt.Sprintf("Search for %s failed", gap), err)
 			continue
 		}
 
-		results := processResults(resp.Results, tmdbID, quality, query)
-		if len(results) > 0 {
-			bestResult := selectBestResult(results)
-			if bestResult != nil && addTorrentToDeluge(bestResult) {
-				successCount++
-				logger.WriteInfo(fmt.Sprintf("Successfully added %s (%d/%d)",
-					episodeFormat, successCount, episodeCount))
-			} else {
-				missingEpisodes = append(missingEpisodes, episode)
+		// Only releases that actually contain this episode are useful here
+		var matching []searchResult
+		for _, candidate := range newCoverageCandidates(processResults(resp, identity, quality, query), seasons, wanted) {
+			if candidate.covers[gap] {
+				matching = append(matching, candidate.searchResult)
 			}
-		} else {
-			logger.WriteWarning(fmt.Sprintf("No results found for %s", episodeFormat))
-			missingEpisodes = append(missingEpisodes, episode)
 		}
 
-		time.Sleep(500 * time.Millisecond)
+		if len(matching) > 0 {
+			sort.SliceStable(matching, func(i, j int) bool {
+				return matching[i].result.Size > matching[j].result.Size
+			})
+			return matching
+		}
 	}
 
-	if len(missingEpisodes) > 0 {
-		logger.WriteWarning(fmt.Sprintf("Missing episodes for season %d: %v", season, missingEpisodes))
+	return nil
+}
+
+// evaluateShowResults scores every show result, keeping rejected ones with their reason
+func evaluateShowResults(results []jackett.Result, identity mediaIdentity, quality string, exactTitle string) []searchResult {
+	evaluated := make([]searchResult, 0, len(results))
+
+	for _, result := range results {
+		var breakdown *ScoreBreakdown
+
+		// Reject results that aren't this show by ID, title, year or region
+		matchedTitle, reason := identityRejection(&result, identity, exactTitle, isExactShowMatch)
+		if reason == "" && isBlocked(&result) {
+			reason = "blocklisted release"
+		}
+		if reason != "" {
+			breakdown = newScoreBreakdown(MIN_ACCEPTABLE_SCORE)
+			breakdown.reject(reason)
+		} else {
+			breakdown = calculateScore(&result, identity, quality)
+		}
+		breakdown.finalize()
+
+		evaluated = append(evaluated, searchResult{
+			result:       &result,
+			score:        breakdown.Total,
+			breakdown:    breakdown,
+			mediaType:    MediaShow,
+			query:        exactTitle,
+			tmdbID:       identity.tmdbID,
+			quality:      quality,
+			matchedTitle: matchedTitle,
+		})
 	}
 
-	logger.WriteInfo(fmt.Sprintf("Found %d/%d episodes for season %d",
-		successCount, episodeCount, season))
-	return successCount > 0
+	return evaluated
 }
 
 // Modify processResults to include more logging
-func processResults(results []jackett.Result, tmdbID int, quality string, exactTitle string) []searchResult {
+func processResults(results []jackett.Result, identity mediaIdentity, quality string, exactTitle string) []searchResult {
 	var scoredResults []searchResult
 
 	logger.WriteInfo(fmt.Sprintf("Processing %d results", len(results)))
 
-	for _, result := range results {
-		// Skip results that don't match the exact show title
-		if !isExactShowMatch(result.Title, exactTitle) {
-			logger.WriteInfo(fmt.Sprintf("Skipping non-matching title: %s", result.Title))
+	for _, candidate := range evaluateShowResults(results, identity, quality, exactTitle) {
+		if !candidate.breakdown.Accepted {
+			logger.WriteInfo(fmt.Sprintf("Skipping result: %s (%s)", candidate.result.Title, candidate.breakdown.Rejection))
 			continue
 		}
 
-		score := calculateScore(&result, tmdbID, quality)
-		if score >= MIN_ACCEPTABLE_SCORE {
-			scoredResults = append(scoredResults, searchResult{
-				result: &result,
-				score:  score,
-			})
-			logger.WriteInfo(fmt.Sprintf("Added to candidates: %s (Score: %.2f)", result.Title, score))
-		}
+		scoredResults = append(scoredResults, candidate)
+		logger.WriteInfo(fmt.Sprintf("Added to candidates: %s (Score: %.2f) [%s]",
+			candidate.result.Title, candidate.score, candidate.breakdown))
 	}
 
 	// Sort by size in descending order
@@ -395,8 +663,10 @@ func isExactShowMatch(resultTitle, exactTitle string) bool {
 		return false
 	}
 
-	// Additional validation to avoid spinoffs/sequels
+	// Additional validation to avoid spinoffs/sequels. The show's year is dropped
+	// first so "Doctor Who 2005" isn't mistaken for a numbered sequel.
 	remainder := strings.TrimSpace(strings.TrimPrefix(cleanResultTitle, cleanExactTitle))
+	remainder = strings.TrimSpace(releaseYearPattern.ReplaceAllString(remainder, ""))
 	
 	// If there's a remainder, check if it's likely a spinoff/sequel
 	if remainder != "" {
@@ -487,7 +757,7 @@ func cleanExactTitle(title string) string {
 	return strings.TrimSpace(title)
 }
 
-func selectBestResult(results []searchResult) *jackett.Result {
+func selectBestResult(results []searchResult) *searchResult {
 	if len(results) == 0 {
 		return nil
 	}
@@ -497,38 +767,41 @@ func selectBestResult(results []searchResult) *jackett.Result {
 		return results[i].result.Size > results[j].result.Size
 	})
 
-	return results[0].result
+	return &results[0]
 }
 
-func calculateScore(result *jackett.Result, tmdbID int, quality string) float64 {
-	score := 0.0
+func calculateScore(result *jackett.Result, identity mediaIdentity, quality string) *ScoreBreakdown {
+	breakdown := newScoreBreakdown(MIN_ACCEPTABLE_SCORE)
 
 	// Title match score (NEW)
-	titleScore := calculateTitleMatch(result.Title)
-	score += titleScore * TITLE_MATCH_WEIGHT
+	titleScore, titleHits := calculateTitleMatch(result.Title)
+	breakdown.addComponent("title_match", titleScore, TITLE_MATCH_WEIGHT, "")
+	for _, hit := range titleHits {
+		breakdown.addRuleHit(hit.Rule, hit.Adjustment)
+	}
 
-	// TMDb match bonus (reduced since we now have title matching)
-	if result.TMDb > 0 && int(result.TMDb) == tmdbID {
-		score += 0.3 // Reduced from 0.5 since we now have title matching
+	// ID match bonus (reduced since we now have title matching)
+	if matchedID, _ := matchExternalIDs(result, identity); matchedID != "" {
+		breakdown.addComponent("id_bonus", 1.0, ID_MATCH_BONUS, matchedID)
 	}
 
 	// Seeders score
 	seedersScore := math.Min(float64(result.Seeders)/500.0, 1.0)
-	score += seedersScore * SEEDERS_WEIGHT
+	breakdown.addComponent("seeders", seedersScore, SEEDERS_WEIGHT, fmt.Sprintf("%d seeders", result.Seeders))
 
 	// Quality match
 	qualityScore := calculateQualityMatch(result.Title, quality)
-	score += qualityScore * QUALITY_WEIGHT
+	breakdown.addComponent("quality", qualityScore, QUALITY_WEIGHT, fmt.Sprintf("wanted %s", quality))
 
 	// Size score
 	sizeScore := calculateSizeScore(result.Size, quality)
-	score += sizeScore * SIZE_WEIGHT
+	breakdown.addComponent("size", sizeScore, SIZE_WEIGHT, fmt.Sprintf("%.2f GB", float64(result.Size)/1024/1024/1024))
 
-	return score
+	return breakdown
 }
 
-// New function to calculate title match score
-func calculateTitleMatch(title string) float64 {
+// New function to calculate title match score, returning the rules that penalised it
+func calculateTitleMatch(title string) (float64, []RuleHit) {
 	// Remove common strings that don't affect title matching
 	cleanTitle := strings.ToLower(title)
 	removeStrings := []string{
@@ -549,22 +822,26 @@ func calculateTitleMatch(title string) float64 {
 
 	// Basic scoring criteria
 	score := 1.0
+	var hits []RuleHit
 
 	// Penalize for suspicious patterns
 	if strings.Contains(cleanTitle, "sample") {
 		score -= 0.3
+		hits = append(hits, RuleHit{Rule: "sample", Adjustment: -0.3})
 	}
 
 	if strings.Contains(cleanTitle, "trailer") {
 		score -= 0.5
+		hits = append(hits, RuleHit{Rule: "trailer", Adjustment: -0.5})
 	}
 
 	// Penalize for obviously wrong content
 	if strings.Contains(cleanTitle, "ost") || strings.Contains(cleanTitle, "soundtrack") {
 		score -= 0.8
+		hits = append(hits, RuleHit{Rule: "soundtrack", Adjustment: -0.8})
 	}
 
-	return math.Max(0.0, score) // Ensure score doesn't go negative
+	return math.Max(0.0, score), hits // Ensure score doesn't go negative
 }
 
 func calculateQualityMatch(title, targetQuality string) float64 {
@@ -614,7 +891,8 @@ func calculateSizeScore(size uint, quality string) float64 {
 	return 0.5
 }
 
-func addTorrentToDeluge(result *jackett.Result) bool {
+func addTorrentToDeluge(ctx context.Context, candidate searchResult) bool {
+	result := candidate.result
 	if result == nil {
 		logger.WriteError("No valid result to add to Deluge", nil)
 		return false
@@ -625,171 +903,255 @@ func addTorrentToDeluge(result *jackett.Result) bool {
 	logger.WriteInfo(fmt.Sprintf("Size: %.2f GB", float64(result.Size)/1024/1024/1024))
 	logger.WriteInfo(fmt.Sprintf("Seeders: %d", result.Seeders))
 
-	err := deluge.AddTorrent(result.Link)
-	if err != nil {
-		if strings.Contains(err.Error(), "Torrent already in session") {
-			// If we get "already in session", consider it a success since it means
-			// we already have this episode
-			logger.WriteInfo(fmt.Sprintf("Torrent already exists in Deluge: %s", result.Title))
-			return true
+	return sendToDeluge(ctx, candidate, result.Link) == nil
+}
+
+// sendToDeluge checks a release and adds it by link, recording the grab. A release Deluge
+// already has counts as grabbed.
+func sendToDeluge(ctx context.Context, candidate searchResult, link string) error {
+	result := candidate.result
+	if err := checkRelease(ctx, &candidate); err != nil {
+		logger.WriteWarning(fmt.Sprintf("Skipping %s: %v", result.Title, err))
+		recordGrab(candidate, link, "", err)
+		return err
+	}
+
+	hash, err := addTorrent(ctx, link, torrentOptions[candidate.mediaType])
+	var timeout *deluge.TimeoutError
+	if errors.As(err, &timeout) {
+		// Deluge may have finished the add after we stopped waiting for it
+		if known := releaseHash(candidate, link); known != "" && inDeluge(ctx, known) {
+			logger.WriteInfo(fmt.Sprintf("%s was added although Deluge answered late", result.Title))
+			hash, err = known, nil
 		}
-		logger.WriteError(fmt.Sprintf("Failed to add torrent to Deluge for %s. Error: %v", result.Title, err), err)
-		return false
+	}
+	if err != nil && strings.Contains(err.Error(), "Torrent already in session") {
+		// We already have this release, which is as good as adding it
+		logger.WriteInfo(fmt.Sprintf("Torrent already exists in Deluge: %s", result.Title))
+		err = nil
+	}
+	if err != nil {
+		logger.WriteError(fmt.Sprintf("Failed to add %s to Deluge", result.Title), err)
+		recordGrab(candidate, link, hash, err)
+		return err
 	}
 
 	logger.WriteInfo(fmt.Sprintf("Successfully sent to Deluge: %s", result.Title))
-	return true
+	recordGrab(candidate, link, hash, nil)
+	return nil
 }
 
-func tryAddTorrentWithFallback(results []searchResult) bool {
+// releaseHash returns a release's info hash from its .torrent, its indexer or its magnet
+// link, or "" when none of them has it
+func releaseHash(candidate searchResult, link string) string {
+	if candidate.meta != nil && candidate.meta.InfoHash != "" {
+		return strings.ToLower(candidate.meta.InfoHash)
+	}
+	if candidate.result.InfoHash != "" {
+		return strings.ToLower(candidate.result.InfoHash)
+	}
+	if magnet, err := url.Parse(link); err == nil && magnet.Scheme == "magnet" {
+		for _, xt := range magnet.Query()["xt"] {
+			if hash := strings.TrimPrefix(xt, "urn:btih:"); hash != xt && len(hash) == 40 {
+				return strings.ToLower(hash)
+			}
+		}
+	}
+	return ""
+}
+
+// inDeluge reports whether Deluge has a torrent, false when it can't be asked
+func inDeluge(ctx context.Context, hash string) bool {
+	torrents, err := torrentStatus(ctx, []string{hash})
+	if err != nil {
+		logger.WriteWarning(fmt.Sprintf("Couldn't check whether Deluge has %s: %v", hash, err))
+		return false
+	}
+	_, ok := torrents[hash]
+	return ok
+}
+
+func tryAddTorrentWithFallback(ctx context.Context, results []searchResult) bool {
 	for _, result := range results {
-		if addTorrentToDeluge(result.result) {
+		if addTorrentToDeluge(ctx, result) {
 			return true
 		}
 		// Wait a bit before trying the next result
-		time.Sleep(1 * time.Second)
+		if pause(ctx, time.Second) != nil {
+			return false
+		}
 	}
 	return false
 }
 
 // MakeAnimeMovieQuery handles searching and downloading anime movies with improved validation
-func MakeAnimeMovieQuery(query string, tmdbID int, quality string) error {
-	ctx := context.Background()
+func MakeAnimeMovieQuery(ctx context.Context, query string, tmdbID int, quality string) error {
+	ctx, cancel := withSearchDeadline(ctx)
+	defer cancel()
 	j := jackett.NewJackett(&jackett.Settings{
 		ApiURL: fmt.Sprintf("http://%s:%s/", ip, port),
 		ApiKey: fmt.Sprintf("%s", apiKey),
 	})
 
-	// Try with specific anime movie categories
-	queryString := fmt.Sprintf("%s %s", query, quality)
-	logger.WriteInfo(fmt.Sprintf("Searching for anime movie: %s", queryString))
+	ensureCapabilities(ctx)
 
-	// First attempt with strict anime movie categories
-	if err := searchAnimeMovie(ctx, j, queryString, tmdbID, quality); err == nil {
-		return nil
+	// Anime movies are often released under their romaji or original title
+	identity := resolveMovieIdentity(query, tmdbID, 0)
+	titles := identity.titles
+	if err := awaitMovieRelease(MediaAnimeMovie, query, identity, quality); err != nil {
+		return err
 	}
 
-	// Fallback to broader categories if needed
-	fallbackCategories := [][]uint{
-		{2000, 2010, 100001}, // Anime-specific
-		{2000, 2010, 2020},   // General movies
-	}
+	for _, title := range titles {
+		// Try with specific anime movie categories
+		queryString := fmt.Sprintf("%s %s", title, quality)
+		logger.WriteInfo(fmt.Sprintf("Searching for anime movie: %s", queryString))
 
-	for _, categories := range fallbackCategories {
-		resp, err := j.Fetch(ctx, &jackett.FetchRequest{
-			Categories: categories,
-			Query:      queryString,
-		})
-		if err != nil {
-			continue
+		// First attempt with strict anime movie categories
+		if err := searchAnimeMovie(ctx, j, title, query, titles, tmdbID, quality); err == nil {
+			fulfilMovie(MediaAnimeMovie, tmdbID, query)
+			return nil
 		}
 
-		results := processAnimeResults(resp.Results, tmdbID, quality, query)
-		if len(results) > 0 {
-			// Try each result until we find one that works
-			for _, result := range results {
-				if validateAndAddAnimeTorrent(result.result) {
-					return nil
+		// Fallback to broader categories if needed
+		fallbackCategories := [][]uint{
+			searchCategories(MediaAnimeMovie), // Anime-specific
+			searchCategories(MediaMovie),      // General movies
+		}
+
+		for _, categories := range fallbackCategories {
+			resp, err := fetchResults(ctx, j, searchQuery{searchType: torznabSearch, text: queryString, categories: categories})
+			if err != nil {
+				continue
+			}
+
+			results := processAnimeResults(resp, tmdbID, quality, query, titles, MediaAnimeMovie)
+			if len(results) > 0 {
+				// Try each result until we find one that works
+				for _, result := range results {
+					if validateAndAddAnimeTorrent(ctx, result) {
+						fulfilMovie(MediaAnimeMovie, tmdbID, query)
+						return nil
+					}
+					if pause(ctx, searchDelay) != nil {
+						return searchError(ctx, query, nil)
+					}
 				}
-				time.Sleep(searchDelay)
 			}
 		}
 	}
 
-	return fmt.Errorf("no valid anime movie downloads found for: %s", query)
+	wantMovie(MediaAnimeMovie, query, identity, quality)
+	return searchError(ctx, query, fmt.Errorf("%w for anime movie: %s", ErrNoMatches, query))
 }
 
-func MakeAnimeShowQuery(query string, seasons []int, tmdbID int, quality string) error {
-	ctx := context.Background()
+func MakeAnimeShowQuery(ctx context.Context, query string, seasons []int, selection ShowSelection, tmdbID int, quality string) error {
+	ctx, cancel := withSearchDeadline(ctx)
+	defer cancel()
 	j := jackett.NewJackett(&jackett.Settings{
 		ApiURL: fmt.Sprintf("http://%s:%s/", ip, port),
 		ApiKey: fmt.Sprintf("%s", apiKey),
 	})
 
-	totalEpisodes := 0
-	for _, episodeCount := range seasons {
-		totalEpisodes += episodeCount
+	ensureCapabilities(ctx)
+
+	// Anime is often released under its romaji or original title
+	identity := resolveShowIdentity(query, tmdbID, 0)
+	titles := identity.titles
+	seasons, selected, err := selectEpisodes(query, identity, seasons, selection)
+	if err != nil {
+		return err
 	}
+	selected = skipGrabbed(MediaAnimeShow, query, identity, selected)
+	monitored := !selection.partial()
+	mapping := resolveAnimeMapping(tmdbID, seasons)
+
+	logger.WriteInfo(fmt.Sprintf("Starting search for anime series: %s with %d episodes selected (titles %q)",
+		query, len(selected), titles))
 
-	logger.WriteInfo(fmt.Sprintf("Starting search for anime series: %s with %d total episodes",
-		query, totalEpisodes))
+	aired, upcoming := identity.release.splitAired(selected)
+	logUpcoming(query, upcoming, identity.release)
+	if len(aired) == 0 {
+		wantShow(MediaAnimeShow, query, identity, quality, seasons, selected, upcoming, mapping, monitored)
+		return notReleased(query, upcoming)
+	}
 
-	// Try batch downloads first
-	if found := tryAnimeBatchDownloads(ctx, j, query, tmdbID, quality); found {
+	// Try batch downloads first, unless only part of the series was asked for
+	if !selection.partial() && tryAnimeBatchDownloads(ctx, j, query, titles, tmdbID, quality) {
+		wantShow(MediaAnimeShow, query, identity, quality, seasons, selected, upcoming, mapping, monitored)
 		return nil
 	}
+	if ctx.Err() != nil {
+		return searchError(ctx, query, nil)
+	}
 
 	// If batch download fails, try episode by episode
-	return searchAnimeEpisodesByOne(ctx, j, query, tmdbID, quality, seasons)
+	missing, err := searchAnimeEpisodesByOne(ctx, j, query, titles, tmdbID, quality, aired, mapping)
+	if err != nil {
+		return err
+	}
+	wantShow(MediaAnimeShow, query, identity, quality, seasons, selected, append(missing, upcoming...), mapping, monitored)
+	if len(missing) == len(aired) {
+		return fmt.Errorf("%w for anime series: %s", ErrNoMatches, query)
+	}
+	return nil
 }
 
 func isAnimeTimeRelease(title string) bool {
 	return strings.Contains(title, "[Anime Time]")
 }
 
-func tryAnimeBatchDownloads(ctx context.Context, j *jackett.Jackett, query string, tmdbID int, quality string) bool {
-	// Exact Anime Time pattern matching the format
-	animeTimePatterns := []string{
-		`[Anime Time] %s (Series+Movies) 2011 [Dual Audio][BD][1080p][HEVC 10bit x265][AAC][Eng Sub] [Batch]`,
-		// Slightly more generic fallbacks but still maintaining Anime Time format
-		`[Anime Time] %s [Dual Audio][BD][1080p][HEVC 10bit x265][AAC][Eng Sub] [Batch]`,
-		`[Anime Time] %s [Batch]`,
-	}
-
+func tryAnimeBatchDownloads(ctx context.Context, j *jackett.Jackett, query string, titles []string, tmdbID int, quality string) bool {
 	// Try Anime Time patterns first
-	for _, pattern := range animeTimePatterns {
-		queryString := fmt.Sprintf(pattern, query)
-		logger.WriteInfo(fmt.Sprintf("Trying Anime Time batch search with query: %s", queryString))
-
-		resp, err := j.Fetch(ctx, &jackett.FetchRequest{
-			Categories: animeSeriesCategories,
-			Query:      queryString,
-		})
-
-		if err == nil && len(resp.Results) > 0 {
-			results := processAnimeResults(resp.Results, tmdbID, quality, query)
-			for _, result := range results {
-				if isAnimeTimeRelease(result.result.Title) && addTorrentMagnetToDeluge(result.result) {
-					logger.WriteInfo(fmt.Sprintf("Successfully added Anime Time batch: %s", result.result.Title))
-					return true
+	for _, title := range titles {
+		for _, pattern := range animeTimeBatchPatterns {
+			queryString := fmt.Sprintf(pattern, title)
+			logger.WriteInfo(fmt.Sprintf("Trying Anime Time batch search with query: %s", queryString))
+
+			resp, err := fetchResults(ctx, j, searchQuery{searchType: torznabSearch, text: queryString, categories: searchCategories(MediaAnimeShow)})
+
+			if err == nil && len(resp) > 0 {
+				results := processAnimeResults(resp, tmdbID, quality, query, titles, MediaAnimeShow)
+				for _, result := range results {
+					if isAnimeTimeRelease(result.result.Title) && addTorrentMagnetToDeluge(ctx, result) {
+						logger.WriteInfo(fmt.Sprintf("Successfully added Anime Time batch: %s", result.result.Title))
+						return true
+					}
 				}
 			}
-		}
 
-		time.Sleep(searchDelay)
+			if pause(ctx, searchDelay) != nil {
+				return false
+			}
+		}
 	}
 
 	// Fallback patterns if Anime Time isn't found
-	fallbackPatterns := []string{
-		`[AnimeSkulls] %s (2011) [Batch] [Dual Audio][1080p][HEVC 10bit x265]`,
-		`%s complete series`,
-		`%s batch`,
-	}
+	for _, title := range titles {
+		for _, pattern := range fallbackBatchPatterns {
+			queryString := fmt.Sprintf(pattern, title)
+			logger.WriteInfo(fmt.Sprintf("Trying fallback batch search with query: %s", queryString))
 
-	for _, pattern := range fallbackPatterns {
-		queryString := fmt.Sprintf(pattern, query)
-		logger.WriteInfo(fmt.Sprintf("Trying fallback batch search with query: %s", queryString))
+			resp, err := fetchResults(ctx, j, searchQuery{searchType: torznabSearch, text: queryString, categories: searchCategories(MediaAnimeShow)})
 
-		resp, err := j.Fetch(ctx, &jackett.FetchRequest{
-			Categories: animeSeriesCategories,
-			Query:      queryString,
-		})
-
-		if err != nil {
-			continue
-		}
+			if err != nil {
+				continue
+			}
 
-		results := processAnimeResults(resp.Results, tmdbID, quality, query)
-		if len(results) > 0 {
-			for _, result := range results {
-				if addTorrentMagnetToDeluge(result.result) {
-					logger.WriteInfo(fmt.Sprintf("Successfully added batch: %s", result.result.Title))
-					return true
+			results := processAnimeResults(resp, tmdbID, quality, query, titles, MediaAnimeShow)
+			if len(results) > 0 {
+				for _, result := range results {
+					if addTorrentMagnetToDeluge(ctx, result) {
+						logger.WriteInfo(fmt.Sprintf("Successfully added batch: %s", result.result.Title))
+						return true
+					}
 				}
 			}
-		}
 
-		time.Sleep(searchDelay)
+			if pause(ctx, searchDelay) != nil {
+				return false
+			}
+		}
 	}
 
 	return false
@@ -819,106 +1181,95 @@ func calculateAnimeSize(size uint, quality string) float64 {
 	return 0.5 // Default score for unknown quality
 }
 
-func searchAnimeEpisodesByOne(ctx context.Context, j *jackett.Jackett, query string, tmdbID int, quality string, seasons []int) error {
-	logger.WriteInfo(fmt.Sprintf("Starting season-based anime search for %d seasons", len(seasons)))
+// searchAnimeEpisodesByOne grabs the best release for each of the given episodes and
+// returns the ones it couldn't find
+func searchAnimeEpisodesByOne(ctx context.Context, j *jackett.Jackett, query string, titles []string, tmdbID int, quality string, keys []episodeKey, mapping *animeMapping) ([]episodeKey, error) {
+	logger.WriteInfo(fmt.Sprintf("Starting episode-based anime search for %d episodes", len(keys)))
 
-	// Search by season and episode instead of sequential episodes
-	for seasonNum := 1; seasonNum <= len(seasons); seasonNum++ {
-		episodeCount := seasons[seasonNum-1]
-		logger.WriteInfo(fmt.Sprintf("Searching season %d with %d episodes", seasonNum, episodeCount))
+	wanted := newEpisodeSet(keys)
+	covered := episodeSet{}
 
-		for episode := 1; episode <= episodeCount; episode++ {
-			// Anime Time episode patterns with proper season formatting
-			animeTimePatterns := []string{
-				`[Anime Time] %s S%02dE%02d [Dual Audio][BD][1080p][HEVC 10bit x265][AAC][Eng Sub]`,
-				`[Anime Time] %s - S%02dE%02d [Dual Audio][BD][1080p][HEVC 10bit x265][AAC][Eng Sub]`,
-				`[Anime Time] %s Season %d Episode %02d [Dual Audio][BD][1080p][HEVC 10bit x265][AAC][Eng Sub]`,
-			}
+	found := searchEpisodes(ctx, keys, func(ctx context.Context, key episodeKey) []searchResult {
+		return searchAnimeEpisode(ctx, j, query, titles, tmdbID, quality, key, mapping)
+	})
 
-			// Fallback patterns with season support
-			fallbackPatterns := []string{
-				`[AnimeSkulls] %s S%02dE%02d [1080p] [Dual.Audio] [x265] [RD]`,
-				`%s S%02dE%02d`,
-				`%s Season %d Episode %d`,
+	// Grab in episode order so the outcome doesn't depend on which search finished first
+	for i, key := range keys {
+		if ctx.Err() != nil {
+			return nil, searchError(ctx, query, nil)
+		}
+		// A batch or multi-episode release grabbed earlier may already cover this one
+		if covered[key] {
+			continue
+		}
+
+		grabbed := false
+		for _, result := range found[i] {
+			result.episodes = parseAnimeCoverage(result.result.Title, titles, mapping).intersect(wanted)
+			if addTorrentMagnetToDeluge(ctx, result) {
+				covered.union(result.episodes)
+				logger.WriteInfo(fmt.Sprintf("Successfully added %s: %s", key, result.result.Title))
+				grabbed = true
+				break
 			}
+		}
 
-			var foundEpisode bool
+		if !grabbed {
+			logger.WriteWarning(fmt.Sprintf("No valid results found for %s", key))
+		}
+	}
 
-			// Try Anime Time patterns first
-			for _, pattern := range animeTimePatterns {
-				var queryString string
-				if strings.Contains(pattern, "Season %d Episode") {
-					queryString = fmt.Sprintf(pattern, query, seasonNum, episode)
-				} else {
-					queryString = fmt.Sprintf(pattern, query, seasonNum, episode)
-				}
-				logger.WriteInfo(fmt.Sprintf("Searching Anime Time for S%02dE%02d using query: %s",
-					seasonNum, episode, queryString))
-
-				resp, err := j.Fetch(ctx, &jackett.FetchRequest{
-					Categories: animeSeriesCategories,
-					Query:      queryString,
-				})
-
-				if err == nil {
-					results := processAnimeResults(resp.Results, tmdbID, quality, query)
-					for _, result := range results {
-						if isAnimeTimeRelease(result.result.Title) && addTorrentMagnetToDeluge(result.result) {
-							logger.WriteInfo(fmt.Sprintf("Successfully added Anime Time S%02dE%02d: %s",
-								seasonNum, episode, result.result.Title))
-							foundEpisode = true
-							break
-						}
-					}
-				}
+	return wanted.missing(covered), nil
+}
 
-				if foundEpisode {
-					break
-				}
-				time.Sleep(searchDelay)
-			}
-			// If Anime Time release not found, try fallback patterns
-			if !foundEpisode {
-				for _, pattern := range fallbackPatterns {
-					var queryString string
-					if strings.Contains(pattern, "Season %d Episode") {
-						queryString = fmt.Sprintf(pattern, query, seasonNum, episode)
-					} else {
-						queryString = fmt.Sprintf(pattern, query, seasonNum, episode)
-					}
-					logger.WriteInfo(fmt.Sprintf("Searching fallback for S%02dE%02d using query: %s",
-						seasonNum, episode, queryString))
+// searchAnimeEpisode returns the releases covering one episode, best first. Anime Time
+// releases are tried first, then anything else that covers the episode.
+func searchAnimeEpisode(ctx context.Context, j *jackett.Jackett, query string, titles []string, tmdbID int, quality string, key episodeKey, mapping *animeMapping) []searchResult {
+	var animeTimeQueries, fallbackQueries []string
+	for _, title := range titles {
+		animeTime, fallback := animeEpisodeQueries(title, mapping, key)
+		animeTimeQueries = append(animeTimeQueries, animeTime...)
+		fallbackQueries = append(fallbackQueries, fallback...)
+	}
+	logger.WriteInfo(fmt.Sprintf("Searching for %s (absolute %d)", key, mapping.absolute(key)))
 
-					resp, err := j.Fetch(ctx, &jackett.FetchRequest{
-						Categories: animeSeriesCategories,
-						Query:      queryString,
-					})
+	stages := []struct {
+		queries       []string
+		animeTimeOnly bool
+	}{
+		{animeTimeQueries, true},
+		{fallbackQueries, false},
+	}
 
-					if err != nil {
-						continue
-					}
+	for _, stage := range stages {
+		for _, queryString := range stage.queries {
+			logger.WriteInfo(fmt.Sprintf("Searching for %s using query: %s", key, queryString))
 
-					results := processAnimeResults(resp.Results, tmdbID, quality, query)
-					if len(results) > 0 {
-						for _, result := range results {
-							if addTorrentMagnetToDeluge(result.result) {
-								logger.WriteInfo(fmt.Sprintf("Successfully added S%02dE%02d: %s",
-									seasonNum, episode, result.result.Title))
-								foundEpisode = true
-								break
-							}
-						}
-					}
+			resp, err := fetchResults(ctx, j, searchQuery{searchType: torznabSearch, text: queryString, categories: searchCategories(MediaAnimeShow)})
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				continue
+			}
 
-					if foundEpisode {
-						break
-					}
-					time.Sleep(searchDelay)
+			var matching []searchResult
+			for _, result := range processAnimeResults(resp, tmdbID, quality, query, titles, MediaAnimeShow) {
+				if stage.animeTimeOnly && !isAnimeTimeRelease(result.result.Title) {
+					continue
 				}
+
+				// Releases are numbered in several ways, so check this one really is the episode
+				releaseCoverage := parseAnimeCoverage(result.result.Title, titles, mapping)
+				if !releaseCoverage[key] {
+					logger.WriteInfo(fmt.Sprintf("Skipping %s: covers %s, not %s", result.result.Title, releaseCoverage, key))
+					continue
+				}
+				matching = append(matching, result)
 			}
 
-			if !foundEpisode {
-				logger.WriteWarning(fmt.Sprintf("No valid results found for S%02dE%02d", seasonNum, episode))
+			if len(matching) > 0 {
+				return matching
 			}
 		}
 	}
@@ -926,37 +1277,31 @@ func searchAnimeEpisodesByOne(ctx context.Context, j *jackett.Jackett, query str
 	return nil
 }
 
-func searchAnimeMovie(ctx context.Context, j *jackett.Jackett, query string, tmdbID int, quality string) error {
+func searchAnimeMovie(ctx context.Context, j *jackett.Jackett, title string, query string, titles []string, tmdbID int, quality string) error {
 	// Try different search patterns
-	searchPatterns := []string{
-		"%s %s [1080p]",
-		"[Anime] %s %s",
-		"%s [BD] %s",
-	}
-
-	for _, pattern := range searchPatterns {
-		formattedQuery := fmt.Sprintf(pattern, query, quality)
-		resp, err := j.Fetch(ctx, &jackett.FetchRequest{
-			Categories: animeMovieCategories,
-			Query:      formattedQuery,
-		})
+	for _, pattern := range animeMoviePatterns {
+		formattedQuery := fmt.Sprintf(pattern, title, quality)
+		resp, err := fetchResults(ctx, j, searchQuery{searchType: torznabSearch, text: formattedQuery, categories: searchCategories(MediaAnimeMovie)})
 		if err != nil {
 			continue
 		}
 
-		results := processAnimeResults(resp.Results, tmdbID, quality, query)
+		results := processAnimeResults(resp, tmdbID, quality, query, titles, MediaAnimeMovie)
 		for _, result := range results {
-			if validateAndAddAnimeTorrent(result.result) {
+			if validateAndAddAnimeTorrent(ctx, result) {
 				return nil
 			}
 		}
-		time.Sleep(searchDelay)
+		if err := pause(ctx, searchDelay); err != nil {
+			return err
+		}
 	}
 
 	return fmt.Errorf("no suitable matches found")
 }
 
-func validateAndAddAnimeTorrent(result *jackett.Result) bool {
+func validateAndAddAnimeTorrent(ctx context.Context, candidate searchResult) bool {
+	result := candidate.result
 	if result == nil {
 		return false
 	}
@@ -972,69 +1317,88 @@ func validateAndAddAnimeTorrent(result *jackett.Result) bool {
 		return false
 	}
 
-	// Prefer magnet links but fall back to regular links if needed
+	downloadLink, err := releaseLink(result)
+	if err != nil {
+		logger.WriteWarning(fmt.Sprintf("Skipping %s: %v", result.Title, err))
+		return false
+	}
+
+	return sendToDeluge(ctx, candidate, downloadLink) == nil
+}
+
+// releaseLink checks a release can be downloaded and returns its link, preferring the
+// magnet link and falling back to the .torrent link
+func releaseLink(result *jackett.Result) (string, error) {
 	downloadLink := result.MagnetUri
 	if downloadLink == "" {
 		downloadLink = result.Link
 		if downloadLink == "" {
-			logger.WriteWarning(fmt.Sprintf("No valid download link found for: %s", result.Title))
-			return false
+			return "", fmt.Errorf("%w: no download link", ErrInvalidRelease)
 		}
 	}
 
-	// Additional validation for magnet links
-	if strings.HasPrefix(downloadLink, "magnet:") {
-		if !strings.Contains(downloadLink, "xt=urn:btih:") {
-			logger.WriteWarning(fmt.Sprintf("Invalid magnet link format for: %s", result.Title))
-			return false
-		}
+	if strings.HasPrefix(downloadLink, "magnet:") && !strings.Contains(downloadLink, "xt=urn:btih:") {
+		return "", fmt.Errorf("%w: magnet link has no info hash", ErrInvalidRelease)
 	}
+	return downloadLink, nil
+}
 
-	// Try to add the torrent
-	err := deluge.AddTorrent(downloadLink)
-	if err != nil {
-		if strings.Contains(err.Error(), "Torrent already in session") {
-			logger.WriteInfo(fmt.Sprintf("Torrent already exists in Deluge: %s", result.Title))
-			return true
+// evaluateAnimeResults scores every anime result, keeping rejected ones with their reason
+func evaluateAnimeResults(results []jackett.Result, tmdbID int, quality string, query string, titles []string, mediaType string) []searchResult {
+	evaluated := make([]searchResult, 0, len(results))
+
+	for _, result := range results {
+		var breakdown *ScoreBreakdown
+
+		// Reject invalid results
+		if result.Size == 0 {
+			breakdown = newScoreBreakdown(MIN_ACCEPTABLE_ANIME_SCORE)
+			breakdown.reject("zero-size release")
+		} else if result.MagnetUri == "" && result.Link == "" {
+			breakdown = newScoreBreakdown(MIN_ACCEPTABLE_ANIME_SCORE)
+			breakdown.reject("no download link")
+		} else if isBlocked(&result) {
+			breakdown = newScoreBreakdown(MIN_ACCEPTABLE_ANIME_SCORE)
+			breakdown.reject("blocklisted release")
+		} else {
+			breakdown = calculateAnimeScore(&result, tmdbID, quality)
+
+			// Additional scoring for preferred release groups
+			if groupScore, group := getAnimeReleaseGroupScore(result.Title); groupScore > 0 {
+				breakdown.addComponent("release_group", groupScore, 1.0, group)
+			}
 		}
-		logger.WriteError(fmt.Sprintf("Failed to add torrent: %s, Error: %v", result.Title, err), err)
-		return false
+		breakdown.finalize()
+
+		evaluated = append(evaluated, searchResult{
+			result:       &result,
+			score:        breakdown.Total,
+			breakdown:    breakdown,
+			mediaType:    mediaType,
+			query:        query,
+			tmdbID:       tmdbID,
+			quality:      quality,
+			matchedTitle: titleInRelease(result.Title, titles),
+		})
 	}
 
-	// Verify the torrent was added successfully
-	logger.WriteInfo(fmt.Sprintf("Successfully added to Deluge: %s", result.Title))
-	return true
+	return evaluated
 }
 
-func processAnimeResults(results []jackett.Result, tmdbID int, quality string, query string) []searchResult {
+func processAnimeResults(results []jackett.Result, tmdbID int, quality string, query string, titles []string, mediaType string) []searchResult {
 	var scoredResults []searchResult
 	logger.WriteInfo(fmt.Sprintf("Processing %d anime results", len(results)))
 
-	for _, result := range results {
-		// Skip invalid results
-		if result.Size == 0 {
-			logger.WriteInfo(fmt.Sprintf("Skipping zero-size result: %s", result.Title))
-			continue
-		}
-
-		if result.MagnetUri == "" && result.Link == "" {
-			logger.WriteInfo(fmt.Sprintf("Skipping result with no download link: %s", result.Title))
+	for _, candidate := range evaluateAnimeResults(results, tmdbID, quality, query, titles, mediaType) {
+		result := candidate.result
+		if !candidate.breakdown.Accepted {
+			logger.WriteInfo(fmt.Sprintf("Skipping anime result: %s (%s)", result.Title, candidate.breakdown.Rejection))
 			continue
 		}
 
-		score := calculateAnimeScore(&result, tmdbID, quality)
-
-		// Additional scoring for preferred release groups
-		score += getAnimeReleaseGroupScore(result.Title)
-
-		if score >= 0.3 {
-			scoredResults = append(scoredResults, searchResult{
-				result: &result,
-				score:  score,
-			})
-			logger.WriteInfo(fmt.Sprintf("Added candidate: %s (Score: %.2f, Size: %.2f GB, Seeders: %d)",
-				result.Title, score, float64(result.Size)/1024/1024/1024, result.Seeders))
-		}
+		scoredResults = append(scoredResults, candidate)
+		logger.WriteInfo(fmt.Sprintf("Added candidate: %s (Score: %.2f, Size: %.2f GB, Seeders: %d) [%s]",
+			result.Title, candidate.score, float64(result.Size)/1024/1024/1024, result.Seeders, candidate.breakdown))
 	}
 
 	// Sort results by score and then seeders
@@ -1048,7 +1412,7 @@ func processAnimeResults(results []jackett.Result, tmdbID int, quality string, q
 	return scoredResults
 }
 
-func getAnimeReleaseGroupScore(title string) float64 {
+func getAnimeReleaseGroupScore(title string) (float64, string) {
 	title = strings.ToLower(title)
 
 	// Preferred release groups and their scores
@@ -1062,40 +1426,46 @@ func getAnimeReleaseGroupScore(title string) float64 {
 
 	for group, score := range releaseGroups {
 		if strings.Contains(title, group) {
-			return score
+			return score, group
 		}
 	}
 
-	return 0.0
+	return 0.0, ""
 }
 
-func calculateAnimeScore(result *jackett.Result, tmdbID int, quality string) float64 {
-	score := 0.0
+func calculateAnimeScore(result *jackett.Result, tmdbID int, quality string) *ScoreBreakdown {
+	breakdown := newScoreBreakdown(MIN_ACCEPTABLE_ANIME_SCORE)
 
 	// Base score
-	score += 0.1
+	breakdown.addComponent("base", 1.0, 0.1, "")
 
 	// Quality and format preferences
+	formatScore := 0.0
 	if strings.Contains(strings.ToLower(result.Title), "1080p") {
-		score += 0.2
+		formatScore += 0.2
+		breakdown.addRuleHit("1080p", 0.2)
 	}
 	if strings.Contains(strings.ToLower(result.Title), "x265") ||
 		strings.Contains(strings.ToLower(result.Title), "hevc") {
-		score += 0.1
+		formatScore += 0.1
+		breakdown.addRuleHit("x265", 0.1)
 	}
 	if strings.Contains(strings.ToLower(result.Title), "dual.audio") ||
 		strings.Contains(strings.ToLower(result.Title), "dual audio") {
-		score += 0.2
+		formatScore += 0.2
+		breakdown.addRuleHit("dual_audio", 0.2)
 	}
+	breakdown.addComponent("quality", formatScore, 1.0, "format preferences")
 
 	// Seeder score (if available)
 	seedersScore := math.Min(float64(result.Seeders)/50.0, 1.0)
-	score += seedersScore * 0.2
+	breakdown.addComponent("seeders", seedersScore, 0.2, fmt.Sprintf("%d seeders", result.Seeders))
 
-	return score
+	return breakdown
 }
 
-func addTorrentMagnetToDeluge(result *jackett.Result) bool {
+func addTorrentMagnetToDeluge(ctx context.Context, candidate searchResult) bool {
+	result := candidate.result
 	if result == nil {
 		logger.WriteError("No valid result to add to Deluge", nil)
 		return false
@@ -1111,18 +1481,5 @@ func addTorrentMagnetToDeluge(result *jackett.Result) bool {
 	logger.WriteInfo(fmt.Sprintf("Size: %.2f GB", float64(result.Size)/1024/1024/1024))
 	logger.WriteInfo(fmt.Sprintf("Seeders: %d", result.Seeders))
 
-	err := deluge.AddTorrent(result.MagnetUri)
-	if err != nil {
-		if strings.Contains(err.Error(), "Torrent already in session") {
-			// If we get "already in session", consider it a success since it means
-			// we already have this episode
-			logger.WriteInfo(fmt.Sprintf("Torrent already exists in Deluge: %s", result.Title))
-			return true
-		}
-		logger.WriteError(fmt.Sprintf("Failed to add magnet to Deluge for %s. Error: %v", result.Title, err), err)
-		return false
-	}
-
-	logger.WriteInfo(fmt.Sprintf("Successfully sent to Deluge: %s", result.Title))
-	return true
+	return sendToDeluge(ctx, candidate, result.MagnetUri) == nil
 }
@@ -0,0 +1,251 @@
+package jackett
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	jackett "github.com/webtor-io/go-jackett"
+	"high-seas/src/logger"
+	"high-seas/src/tmdb"
+)
+
+// YEAR_TOLERANCE is how far a parsed release year may drift from the TMDb year,
+// covering festival releases and late-December premieres
+const YEAR_TOLERANCE = 1
+
+var (
+	releaseYearPattern   = regexp.MustCompile(`\b(19[0-9]{2}|20[0-9]{2})\b`)
+	episodeMarkerPattern = regexp.MustCompile(`(?i)\bs\d{1,2}(e\d{1,3})?\b|\bseason\b|\bcomplete\b`)
+)
+
+// Release-title country tags mapped to TMDb origin_country codes
+var countryTags = map[string]string{
+	"us": "US",
+	"uk": "GB",
+	"au": "AU",
+	"nz": "NZ",
+	"ca": "CA",
+}
+
+// mediaIdentity is what we know about the requested title beyond its name
+type mediaIdentity struct {
+	tmdbID        int
+	year          int
+	imdbID        string
+	tvdbID        int
+	originCountry []string
+	titles        []string
+	release       releaseSchedule
+
+	// Shows only: TMDb's episode count per regular season (index 0 is season 1) and specials
+	seasons  []int
+	specials int
+}
+
+// imdbNumber returns the numeric part of the IMDb ID, as indexers report it
+func (id mediaIdentity) imdbNumber() uint {
+	number, err := strconv.ParseUint(strings.TrimPrefix(id.imdbID, "tt"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(number)
+}
+
+// resolveMovieIdentity fills in the release year and date, IMDb ID and title variants from TMDb when possible
+func resolveMovieIdentity(query string, tmdbID, year int) mediaIdentity {
+	identity := mediaIdentity{tmdbID: tmdbID, year: year, titles: searchTitles(aliasKindMovie, tmdbID, query, nil)}
+	if tmdbID <= 0 || !tmdb.Configured() {
+		return identity
+	}
+
+	info, err := tmdb.GetMovie(tmdbID)
+	if err != nil {
+		logger.WriteError(fmt.Sprintf("Failed to look up TMDb movie %d, matching by title only", tmdbID), err)
+		return identity
+	}
+
+	identity.titles = searchTitles(aliasKindMovie, tmdbID, query, info.Titles())
+	identity.imdbID = info.ExternalIDs.IMDbID
+	identity.release = movieSchedule(info)
+	if identity.year == 0 {
+		identity.year = info.Year()
+	}
+	return identity
+}
+
+// resolveShowIdentity fills in the first air year, seasons, episodes aired, TVDB/IMDb IDs, origin country and title variants from TMDb
+func resolveShowIdentity(query string, tmdbID, year int) mediaIdentity {
+	identity := mediaIdentity{tmdbID: tmdbID, year: year, titles: searchTitles(aliasKindShow, tmdbID, query, nil)}
+	if tmdbID <= 0 || !tmdb.Configured() {
+		return identity
+	}
+
+	info, err := tmdb.GetShow(tmdbID)
+	if err != nil {
+		logger.WriteError(fmt.Sprintf("Failed to look up TMDb show %d, matching by title only", tmdbID), err)
+		return identity
+	}
+
+	identity.titles = searchTitles(aliasKindShow, tmdbID, query, info.Titles())
+	identity.imdbID = info.ExternalIDs.IMDbID
+	identity.tvdbID = info.ExternalIDs.TVDBID
+	identity.originCountry = info.OriginCountry
+	identity.release = showSchedule(info)
+	identity.seasons = info.EpisodeCounts()
+	identity.specials = info.SpecialsCount()
+	if identity.year == 0 {
+		identity.year = info.Year()
+	}
+	return identity
+}
+
+// matchExternalIDs compares the IDs an indexer reported against the requested title.
+// It returns the ID that matched, or a rejection reason when an ID contradicts it.
+func matchExternalIDs(result *jackett.Result, identity mediaIdentity) (string, string) {
+	if result.TMDb > 0 && identity.tmdbID > 0 {
+		if int(result.TMDb) == identity.tmdbID {
+			return fmt.Sprintf("TMDb %d", result.TMDb), ""
+		}
+		return "", fmt.Sprintf("TMDb ID %d does not match %d", result.TMDb, identity.tmdbID)
+	}
+
+	if result.Imdb > 0 && identity.imdbNumber() > 0 {
+		if result.Imdb == identity.imdbNumber() {
+			return fmt.Sprintf("IMDb %s", identity.imdbID), ""
+		}
+		return "", fmt.Sprintf("IMDb ID tt%07d does not match %s", result.Imdb, identity.imdbID)
+	}
+
+	if result.TVDBId > 0 && identity.tvdbID > 0 {
+		if int(result.TVDBId) == identity.tvdbID {
+			return fmt.Sprintf("TVDB %d", result.TVDBId), ""
+		}
+		return "", fmt.Sprintf("TVDB ID %d does not match %d", result.TVDBId, identity.tvdbID)
+	}
+
+	return "", ""
+}
+
+// identityRejection decides whether a release is the requested title, returning the title
+// variant it matched. A matching external ID is trusted over the release name; otherwise
+// one of the titles, the release year and the region must agree.
+func identityRejection(result *jackett.Result, identity mediaIdentity, exactTitle string, titleMatches func(string, string) bool) (string, string) {
+	titles := identity.titles
+	if len(titles) == 0 {
+		titles = []string{exactTitle}
+	}
+
+	matchedTitle := ""
+	for _, title := range titles {
+		if titleMatches(result.Title, title) {
+			matchedTitle = title
+			break
+		}
+	}
+
+	matchedID, conflict := matchExternalIDs(result, identity)
+	if conflict != "" {
+		return "", conflict
+	}
+	if matchedID != "" {
+		return matchedTitle, ""
+	}
+
+	if matchedTitle == "" {
+		return "", fmt.Sprintf("title does not match any of %q", titles)
+	}
+	if reason := releaseYearRejection(result.Title, matchedTitle, identity.year); reason != "" {
+		return "", reason
+	}
+	return matchedTitle, countryRejection(result.Title, matchedTitle, identity.originCountry)
+}
+
+// titleRemainder returns the cleaned words that follow the requested title in a release name
+func titleRemainder(resultTitle, exactTitle string) string {
+	cleanResult := cleanTitleForComparison(resultTitle)
+	cleanExact := cleanExactTitle(exactTitle)
+	return strings.TrimSpace(strings.TrimPrefix(cleanResult, cleanExact))
+}
+
+// parseReleaseYear finds the year that follows the requested title, or 0 if there is none.
+// Only the text after the title is considered so titles like "2012" or "1917" aren't mistaken for years.
+func parseReleaseYear(resultTitle, exactTitle string) int {
+	remainder := titleRemainder(resultTitle, exactTitle)
+
+	// For episodes and packs the show year sits before the season marker
+	if loc := episodeMarkerPattern.FindStringIndex(remainder); loc != nil {
+		remainder = remainder[:loc[0]]
+	}
+
+	match := releaseYearPattern.FindString(remainder)
+	if match == "" {
+		return 0
+	}
+	year, _ := strconv.Atoi(match)
+	return year
+}
+
+// releaseYearRejection returns why a release's year rules it out, or "" if it doesn't
+func releaseYearRejection(resultTitle, exactTitle string, wantYear int) string {
+	if wantYear <= 0 {
+		return ""
+	}
+
+	year := parseReleaseYear(resultTitle, exactTitle)
+	if year == 0 {
+		return ""
+	}
+
+	if year < wantYear-YEAR_TOLERANCE || year > wantYear+YEAR_TOLERANCE {
+		return fmt.Sprintf("release year %d is not within %d of %d", year, YEAR_TOLERANCE, wantYear)
+	}
+	return ""
+}
+
+// countryRejection rejects releases tagged for a different regional version of a show,
+// e.g. "The Office UK" when the US show was requested
+func countryRejection(resultTitle, exactTitle string, originCountry []string) string {
+	if len(originCountry) == 0 {
+		return ""
+	}
+
+	words := strings.Fields(titleRemainder(resultTitle, exactTitle))
+	if len(words) == 0 {
+		return ""
+	}
+
+	country, ok := countryTags[words[0]]
+	if !ok {
+		return ""
+	}
+
+	for _, origin := range originCountry {
+		if strings.EqualFold(origin, country) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("release is tagged %s but the show is from %s", strings.ToUpper(words[0]), strings.Join(originCountry, "/"))
+}
+
+// sequelSegment returns the part of a release title between the requested title and
+// its release year, which is where sequel and spinoff markers appear. Without a year
+// only the first two words are used so audio tags like "5 1" aren't read as sequels.
+func sequelSegment(resultTitle, exactTitle string) string {
+	remainder := titleRemainder(resultTitle, exactTitle)
+	if loc := releaseYearPattern.FindStringIndex(remainder); loc != nil {
+		return strings.TrimSpace(remainder[:loc[0]])
+	}
+
+	words := strings.Fields(remainder)
+	if len(words) > 2 {
+		words = words[:2]
+	}
+	return strings.Join(words, " ")
+}
+
+// containsWords reports whether phrase appears in text on word boundaries
+func containsWords(text, phrase string) bool {
+	return strings.Contains(" "+text+" ", " "+phrase+" ")
+}
@@ -0,0 +1,53 @@
+package jackett
+
+import (
+	"strings"
+	"testing"
+
+	jackett "github.com/webtor-io/go-jackett"
+)
+
+func TestIdentityRejection(t *testing.T) {
+	movie := mediaIdentity{tmdbID: 550, year: 2017, imdbID: "tt0137523"}
+	show := mediaIdentity{tmdbID: 2316, year: 2005, tvdbID: 73244, originCountry: []string{"US"}}
+
+	tests := []struct {
+		name     string
+		result   jackett.Result
+		identity mediaIdentity
+		title    string
+		show     bool
+		// reject is a fragment of the expected rejection, empty when the release is accepted
+		reject string
+	}{
+		{"same year", jackett.Result{Title: "The Quiet Orbit 2017 1080p BluRay x264"}, movie, "The Quiet Orbit", false, ""},
+		{"year after", jackett.Result{Title: "The Quiet Orbit 2018 1080p BluRay x264"}, movie, "The Quiet Orbit", false, ""},
+		{"year before", jackett.Result{Title: "The Quiet Orbit 2016 1080p BluRay x264"}, movie, "The Quiet Orbit", false, ""},
+		{"year out of tolerance", jackett.Result{Title: "The Quiet Orbit 2019 1080p BluRay x264"}, movie, "The Quiet Orbit", false, "release year 2019"},
+		{"no year", jackett.Result{Title: "The Quiet Orbit 1080p BluRay x264"}, movie, "The Quiet Orbit", false, ""},
+		{"matching imdb", jackett.Result{Title: "The Quiet Orbit 2009 1080p", Imdb: 137523}, movie, "The Quiet Orbit", false, ""},
+		{"mismatched imdb", jackett.Result{Title: "The Quiet Orbit 2017 1080p", Imdb: 1234567}, movie, "The Quiet Orbit", false, "IMDb ID tt1234567 does not match tt0137523"},
+		{"mismatched tmdb", jackett.Result{Title: "The Quiet Orbit 2017 1080p", TMDb: 551}, movie, "The Quiet Orbit", false, "TMDb ID 551 does not match 550"},
+		{"matching tvdb", jackett.Result{Title: "The Office S01E01 1080p", TVDBId: 73244}, show, "The Office", true, ""},
+		{"mismatched tvdb", jackett.Result{Title: "The Office S01E01 1080p", TVDBId: 78107}, show, "The Office", true, "TVDB ID 78107 does not match 73244"},
+		{"own country tag", jackett.Result{Title: "The Office US S01E01 1080p"}, show, "The Office", true, ""},
+		{"foreign country tag", jackett.Result{Title: "The Office UK S01E01 1080p"}, show, "The Office", true, "tagged UK but the show is from US"},
+		{"other title", jackett.Result{Title: "Night Harbor S01E01 1080p"}, show, "The Office", true, "title does not match"},
+	}
+
+	for _, tt := range tests {
+		matches := isExactMovieMatch
+		if tt.show {
+			matches = isExactShowMatch
+		}
+		matched, reason := identityRejection(&tt.result, tt.identity, tt.title, matches)
+		switch {
+		case tt.reject == "" && reason != "":
+			t.Errorf("%s: expected %q to be accepted, got %q", tt.name, tt.result.Title, reason)
+		case tt.reject == "" && matched != tt.title:
+			t.Errorf("%s: expected %q to match %q, got %q", tt.name, tt.result.Title, tt.title, matched)
+		case tt.reject != "" && !strings.Contains(reason, tt.reject):
+			t.Errorf("%s: expected %q to be rejected with %q, got %q", tt.name, tt.result.Title, tt.reject, reason)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package jackett
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"high-seas/src/logger"
+	"high-seas/src/tmdb"
+	"high-seas/src/utils"
+)
+
+// animeMappingsFile holds per-series overrides for fansub season splits, keyed by TMDb ID
+var animeMappingsFile = utils.EnvVar("ANIME_MAPPINGS_FILE", "anime-mappings.json")
+
+var (
+	animeBracketPattern       = regexp.MustCompile(`\[[^\]]*\]`)
+	animeSeasonMarkerPattern  = regexp.MustCompile(`\bs(\d{1,2})\b|\bseason (\d{1,2})\b|\b(\d{1,2})(?:st|nd|rd|th) season\b`)
+	animeSeasonEpisodePattern = regexp.MustCompile(`\b(?:s(\d{1,2})|season (\d{1,2})|(\d{1,2})(?:st|nd|rd|th) season) ?- ?(\d{1,4})(?:v\d)?\b`)
+	animeRangePattern         = regexp.MustCompile(`\b(\d{1,4}) ?(?:-|~|to) ?(\d{1,4})\b`)
+	animeEpisodePattern       = regexp.MustCompile(`(?:^| )(?:- ?|ep ?|episode |e)(\d{1,4})(?:v\d)?\b`)
+	animeBatchPattern         = regexp.MustCompile(`\bbatch\b|\bcomplete\b`)
+	animePunctuationPattern   = regexp.MustCompile(`[^\w\s~-]`)
+)
+
+// Episode search patterns for anime, formatted with the query, fansub season and episode
+var (
+	animeTimeEpisodePatterns = []string{
+		`[Anime Time] %s S%02dE%02d [Dual Audio][BD][1080p][HEVC 10bit x265][AAC][Eng Sub]`,
+		`[Anime Time] %s - S%02dE%02d [Dual Audio][BD][1080p][HEVC 10bit x265][AAC][Eng Sub]`,
+		`[Anime Time] %s Season %d Episode %02d [Dual Audio][BD][1080p][HEVC 10bit x265][AAC][Eng Sub]`,
+	}
+	fallbackEpisodePatterns = []string{
+		`[AnimeSkulls] %s S%02dE%02d [1080p] [Dual.Audio] [x265] [RD]`,
+		`%s S%02dE%02d`,
+		`%s Season %d Episode %d`,
+	}
+)
+
+// AnimeMappingOverride describes how fansubs split a series when it differs from TMDb
+type AnimeMappingOverride struct {
+	// Seasons is the episode count of each fansub season, index 0 being season 1
+	Seasons []int `json:"seasons"`
+	// AbsoluteOffset is added to our absolute numbers when releases continue the
+	// numbering of an earlier series
+	AbsoluteOffset int `json:"absolute_offset"`
+}
+
+var (
+	animeOverrides     map[int]AnimeMappingOverride
+	animeOverridesOnce sync.Once
+)
+
+// loadAnimeOverrides reads the override file once; a missing file just means no overrides
+func loadAnimeOverrides() map[int]AnimeMappingOverride {
+	animeOverridesOnce.Do(func() {
+		animeOverrides = make(map[int]AnimeMappingOverride)
+
+		data, err := os.ReadFile(animeMappingsFile)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logger.WriteError(fmt.Sprintf("Failed to read anime mappings from %s", animeMappingsFile), err)
+			}
+			return
+		}
+
+		var byID map[string]AnimeMappingOverride
+		if err := json.Unmarshal(data, &byID); err != nil {
+			logger.WriteError(fmt.Sprintf("Failed to parse anime mappings from %s", animeMappingsFile), err)
+			return
+		}
+
+		for key, override := range byID {
+			tmdbID, err := strconv.Atoi(key)
+			if err != nil {
+				logger.WriteWarning(fmt.Sprintf("Ignoring anime mapping with non-numeric TMDb ID %q", key))
+				continue
+			}
+			animeOverrides[tmdbID] = override
+		}
+		logger.WriteInfo(fmt.Sprintf("Loaded %d anime mapping overrides", len(animeOverrides)))
+	})
+
+	return animeOverrides
+}
+
+// animeMapping converts between TMDb season/episode numbers, fansub season/episode
+// numbers and absolute episode numbers for one series
+type animeMapping struct {
+	tmdbSeasons    []int
+	fansubSeasons  []int
+	absoluteOffset int
+}
+
+// resolveAnimeMapping builds the mapping from TMDb's episode lists, falling back to the
+// requested season counts, and applies any override for the series
+func resolveAnimeMapping(tmdbID int, seasons []int) *animeMapping {
+	mapping := &animeMapping{tmdbSeasons: seasons}
+
+	if tmdbID > 0 && tmdb.Configured() {
+		info, err := tmdb.GetShow(tmdbID)
+		if err != nil {
+			logger.WriteError(fmt.Sprintf("Failed to look up TMDb episode lists for %d, using requested seasons", tmdbID), err)
+		} else if counts := info.EpisodeCounts(); len(counts) > 0 {
+			mapping.tmdbSeasons = counts
+		}
+	}
+
+	mapping.fansubSeasons = mapping.tmdbSeasons
+	if override, ok := loadAnimeOverrides()[tmdbID]; ok {
+		if len(override.Seasons) > 0 {
+			mapping.fansubSeasons = override.Seasons
+		}
+		mapping.absoluteOffset = override.AbsoluteOffset
+		logger.WriteInfo(fmt.Sprintf("Using anime mapping override for TMDb %d: seasons %v, offset %d",
+			tmdbID, mapping.fansubSeasons, mapping.absoluteOffset))
+	}
+
+	return mapping
+}
+
+// absoluteFrom numbers an episode across all seasons with the given counts, 0 if out of range
+func absoluteFrom(counts []int, key episodeKey) int {
+	if key.season < 1 || key.season > len(counts) || key.episode < 1 || key.episode > counts[key.season-1] {
+		return 0
+	}
+	absolute := key.episode
+	for season := 1; season < key.season; season++ {
+		absolute += counts[season-1]
+	}
+	return absolute
+}
+
+// keyFrom finds the season and episode of an absolute number within the given counts
+func keyFrom(counts []int, absolute int) (episodeKey, bool) {
+	if absolute < 1 {
+		return episodeKey{}, false
+	}
+	for i, count := range counts {
+		if absolute <= count {
+			return episodeKey{season: i + 1, episode: absolute}, true
+		}
+		absolute -= count
+	}
+	return episodeKey{}, false
+}
+
+// absolute returns the absolute number releases use for a TMDb episode, 0 if unknown
+func (m *animeMapping) absolute(key episodeKey) int {
+	absolute := absoluteFrom(m.tmdbSeasons, key)
+	if absolute == 0 {
+		return 0
+	}
+	return absolute + m.absoluteOffset
+}
+
+// fansub returns the fansub season and episode for a TMDb episode
+func (m *animeMapping) fansub(key episodeKey) (episodeKey, bool) {
+	return keyFrom(m.fansubSeasons, absoluteFrom(m.tmdbSeasons, key))
+}
+
+// fromAbsolute maps a release's absolute number back to a TMDb episode
+func (m *animeMapping) fromAbsolute(absolute int) (episodeKey, bool) {
+	return keyFrom(m.tmdbSeasons, absolute-m.absoluteOffset)
+}
+
+// fromFansub maps a release's fansub season and episode back to a TMDb episode
+func (m *animeMapping) fromFansub(key episodeKey) (episodeKey, bool) {
+	return keyFrom(m.tmdbSeasons, absoluteFrom(m.fansubSeasons, key))
+}
+
+// normalizeAnimeTitle lowercases a release title and drops group, resolution and hash tags
+// along with the first series title it contains, leaving the numbering
+func normalizeAnimeTitle(title string, titles []string) string {
+	normalized := strings.ToLower(title)
+	normalized = animeBracketPattern.ReplaceAllString(normalized, " ")
+	normalized = animePunctuationPattern.ReplaceAllString(strings.ReplaceAll(normalized, "_", " "), " ")
+	normalized = strings.Join(strings.Fields(normalized), " ")
+
+	for _, seriesTitle := range titles {
+		cleanTitle := strings.Join(strings.Fields(cleanExactTitle(seriesTitle)), " ")
+		if cleanTitle != "" && strings.Contains(normalized, cleanTitle) {
+			normalized = strings.Replace(normalized, cleanTitle, " ", 1)
+			break
+		}
+	}
+	return strings.TrimSpace(normalized)
+}
+
+// parseAnimeCoverage works out which TMDb episodes an anime release covers. It understands
+// SxxEyy numbering in fansub seasons, "S2 - 05" style relative numbering, absolute numbers
+// like "One Piece - 1071", absolute ranges in batches, and whole-season batches.
+func parseAnimeCoverage(title string, titles []string, m *animeMapping) episodeSet {
+	normalized := normalizeAnimeTitle(title, titles)
+	covered := episodeSet{}
+
+	add := func(season, episode int) {
+		var key episodeKey
+		var ok bool
+		if season > 0 {
+			key, ok = m.fromFansub(episodeKey{season: season, episode: episode})
+		} else {
+			key, ok = m.fromAbsolute(episode)
+		}
+		if ok {
+			covered[key] = true
+		}
+	}
+
+	// "Show S2 - 05", "Show 2nd Season - 05"
+	if match := animeSeasonEpisodePattern.FindStringSubmatch(normalized); match != nil {
+		episode, _ := strconv.Atoi(match[4])
+		add(firstNumber(match[1:4]), episode)
+		return covered
+	}
+
+	// Standard SxxEyy numbering, read in fansub seasons
+	if episodeRangePattern.MatchString(normalized) || crossEpisodePattern.MatchString(normalized) {
+		for key := range parseCoverage(normalized, m.fansubSeasons) {
+			add(key.season, key.episode)
+		}
+		return covered
+	}
+
+	season := 0
+	if match := animeSeasonMarkerPattern.FindStringSubmatch(normalized); match != nil {
+		season = firstNumber(match[1:])
+	}
+
+	// Batches numbered "01-12" or "0001-1071", relative to the season when one is named
+	for _, match := range animeRangePattern.FindAllStringSubmatch(normalized, -1) {
+		from, _ := strconv.Atoi(match[1])
+		to, _ := strconv.Atoi(match[2])
+		if from >= to || (from >= 1900 && to <= 2099) {
+			continue
+		}
+		for episode := from; episode <= to; episode++ {
+			add(season, episode)
+		}
+		return covered
+	}
+
+	// Single episodes, "Show - 1071" or "Show Episode 5"
+	if match := animeEpisodePattern.FindStringSubmatch(normalized); match != nil {
+		episode, _ := strconv.Atoi(match[1])
+		add(season, episode)
+		return covered
+	}
+
+	// Whole fansub season, or the whole series for unnumbered batches
+	if season > 0 {
+		for episode := 1; season <= len(m.fansubSeasons) && episode <= m.fansubSeasons[season-1]; episode++ {
+			add(season, episode)
+		}
+	} else if animeBatchPattern.MatchString(strings.ToLower(title)) {
+		covered.union(wantedEpisodes(m.tmdbSeasons))
+	}
+	return covered
+}
+
+// animeEpisodeQueries returns the Anime Time and fallback queries for a TMDb episode, using
+// the fansub season numbering and the absolute number releases are usually titled with
+func animeEpisodeQueries(query string, m *animeMapping, key episodeKey) ([]string, []string) {
+	fansub, ok := m.fansub(key)
+	if !ok {
+		fansub = key
+	}
+	absolute := m.absolute(key)
+
+	var animeTime, fallback []string
+	for _, pattern := range animeTimeEpisodePatterns {
+		animeTime = append(animeTime, fmt.Sprintf(pattern, query, fansub.season, fansub.episode))
+	}
+	if absolute > 0 {
+		fallback = append(fallback, fmt.Sprintf("%s - %02d", query, absolute))
+	}
+	for _, pattern := range fallbackEpisodePatterns {
+		fallback = append(fallback, fmt.Sprintf(pattern, query, fansub.season, fansub.episode))
+	}
+
+	return animeTime, fallback
+}
+
+// firstNumber returns the first non-empty capture as a number
+func firstNumber(captures []string) int {
+	for _, capture := range captures {
+		if capture != "" {
+			n, _ := strconv.Atoi(capture)
+			return n
+		}
+	}
+	return 0
+}
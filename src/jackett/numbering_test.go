@@ -0,0 +1,100 @@
+package jackett
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestAbsoluteAndKeyFrom(t *testing.T) {
+	counts := []int{12, 13, 10}
+	tests := []struct {
+		key      episodeKey
+		absolute int
+	}{
+		{episodeKey{1, 1}, 1},
+		{episodeKey{1, 12}, 12},
+		{episodeKey{2, 1}, 13},
+		{episodeKey{3, 10}, 35},
+	}
+
+	for _, test := range tests {
+		if got := absoluteFrom(counts, test.key); got != test.absolute {
+			t.Errorf("absoluteFrom(%s) = %d, want %d", test.key, got, test.absolute)
+		}
+		if got, ok := keyFrom(counts, test.absolute); !ok || got != test.key {
+			t.Errorf("keyFrom(%d) = %s, want %s", test.absolute, got, test.key)
+		}
+	}
+
+	for _, key := range []episodeKey{{0, 1}, {1, 13}, {4, 1}, {2, 0}} {
+		if got := absoluteFrom(counts, key); got != 0 {
+			t.Errorf("absoluteFrom(%s) = %d, want 0 when out of range", key, got)
+		}
+	}
+	for _, absolute := range []int{0, -3, 36} {
+		if _, ok := keyFrom(counts, absolute); ok {
+			t.Errorf("keyFrom(%d) should be out of range", absolute)
+		}
+	}
+}
+
+func TestParseAnimeCoverage(t *testing.T) {
+	titles := []string{"Kaiju Academy"}
+	// TMDb lists one 24 episode season that fansubs split in two, numbered after a 12 episode prequel
+	mapping := &animeMapping{tmdbSeasons: []int{24}, fansubSeasons: []int{12, 12}, absoluteOffset: 12}
+
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"[SubsPlease] Kaiju Academy S2 - 03 (1080p) [ABCD1234].mkv", "S01E15"},
+		{"[SubsPlease] Kaiju Academy 2nd Season - 03 (1080p)", "S01E15"},
+		{"[Anime Time] Kaiju Academy S02E03 [1080p]", "S01E15"},
+		{"[Erai-raws] Kaiju Academy - 15 [1080p]", "S01E03"},
+		// Bracketed tags are dropped, leaving the season to go by
+		{"[Judas] Kaiju Academy (Season 2) [13-24] [1080p]", "S01E13-E24"},
+		{"[Judas] Kaiju Academy S2 01-12 [Batch]", "S01E13-E24"},
+		{"[Judas] Kaiju Academy 13~24 [1080p]", "S01E01-E12"},
+		{"[Anime Time] Kaiju Academy Season 1 [Dual Audio]", "S01E01-E12"},
+		{"[Anime Time] Kaiju Academy [Dual Audio][1080p] [Batch]", "S01E01-E24"},
+		// A year range isn't a batch of episodes
+		{"[Group] Kaiju Academy 2019-2021", ""},
+	}
+
+	for _, test := range tests {
+		if got := parseAnimeCoverage(test.title, titles, mapping).String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.title, got, test.want)
+		}
+	}
+}
+
+func TestResolveAnimeMappingReadsOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anime-mappings.json")
+	if err := os.WriteFile(path, []byte(`{"4242": {"seasons": [12, 12], "absolute_offset": 12}, "bad": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldFile := animeMappingsFile
+	animeMappingsFile = path
+	animeOverrides, animeOverridesOnce = nil, sync.Once{}
+	t.Cleanup(func() {
+		animeMappingsFile = oldFile
+		animeOverrides, animeOverridesOnce = nil, sync.Once{}
+	})
+
+	mapping := resolveAnimeMapping(4242, []int{24})
+	if len(mapping.fansubSeasons) != 2 || mapping.absoluteOffset != 12 {
+		t.Fatalf("override not applied: %+v", mapping)
+	}
+	if fansub, ok := mapping.fansub(episodeKey{1, 14}); !ok || fansub != (episodeKey{2, 2}) {
+		t.Errorf("expected S01E14 to be fansub S02E02, got %s", fansub)
+	}
+	if got := mapping.absolute(episodeKey{1, 14}); got != 26 {
+		t.Errorf("expected absolute 26 after the offset, got %d", got)
+	}
+
+	if other := resolveAnimeMapping(7, []int{10}); other.absoluteOffset != 0 || len(other.fansubSeasons) != 1 {
+		t.Errorf("series without an override should keep TMDb's seasons, got %+v", other)
+	}
+}
@@ -0,0 +1,247 @@
+package jackett
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	jackett "github.com/webtor-io/go-jackett"
+	"high-seas/src/logger"
+)
+
+// Candidate is a scored indexer release returned by preview searches
+type Candidate struct {
+	Title     string          `json:"title"`
+	Indexer   string          `json:"indexer"`
+	GUID      string          `json:"guid"`
+	Link      string          `json:"link,omitempty"`
+	MagnetURI string          `json:"magnet_uri,omitempty"`
+	InfoHash  string          `json:"info_hash,omitempty"`
+	Size      uint            `json:"size"`
+	Seeders   uint            `json:"seeders"`
+	Score     float64         `json:"score"`
+	Accepted  bool            `json:"accepted"`
+	Coverage  string          `json:"coverage,omitempty"`
+	Breakdown *ScoreBreakdown `json:"breakdown"`
+
+	// MatchedTitle is the title variant the release name matched
+	MatchedTitle string `json:"matched_title,omitempty"`
+}
+
+func newCandidate(sr searchResult) Candidate {
+	return Candidate{
+		Title:     sr.result.Title,
+		Indexer:   sr.result.Tracker,
+		GUID:      sr.result.Guid,
+		Link:      sr.result.Link,
+		MagnetURI: sr.result.MagnetUri,
+		InfoHash:  sr.result.InfoHash,
+		Size:      sr.result.Size,
+		Seeders:   sr.result.Seeders,
+		Score:     sr.score,
+		Accepted:  sr.breakdown != nil && sr.breakdown.Accepted,
+		Breakdown: sr.breakdown,
+
+		MatchedTitle: sr.matchedTitle,
+	}
+}
+
+// candidateSet merges evaluated results from several queries, dropping duplicates
+type candidateSet struct {
+	seen       map[string]bool
+	candidates []Candidate
+}
+
+func newCandidateSet() *candidateSet {
+	return &candidateSet{seen: make(map[string]bool)}
+}
+
+func (cs *candidateSet) add(results []searchResult) {
+	rememberPreviewed(results)
+	for _, sr := range results {
+		key := sr.result.Guid
+		if key == "" {
+			key = sr.result.Link + sr.result.MagnetUri
+		}
+		if cs.seen[key] {
+			continue
+		}
+		cs.seen[key] = true
+		cs.candidates = append(cs.candidates, newCandidate(sr))
+	}
+}
+
+// sorted returns accepted candidates first, best score first
+func (cs *candidateSet) sorted() []Candidate {
+	candidates := cs.candidates
+	if candidates == nil {
+		candidates = []Candidate{}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Accepted != candidates[j].Accepted {
+			return candidates[i].Accepted
+		}
+		if candidates[i].Score == candidates[j].Score {
+			return candidates[i].Seeders > candidates[j].Seeders
+		}
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	return candidates
+}
+
+// previewSearch runs every query without grabbing and collects the scored results
+func previewSearch(ctx context.Context, queries []searchQuery, evaluate func([]jackett.Result) []searchResult) ([]Candidate, error) {
+	ctx, cancel := withSearchDeadline(ctx)
+	defer cancel()
+	j := jackett.NewJackett(&jackett.Settings{
+		ApiURL: fmt.Sprintf("http://%s:%s/", ip, port),
+		ApiKey: fmt.Sprintf("%s", apiKey),
+	})
+
+	candidates := newCandidateSet()
+	var lastErr error
+	succeeded := 0
+
+	for _, searchQuery := range queries {
+		if ctx.Err() != nil {
+			return nil, searchError(ctx, "preview", nil)
+		}
+		logger.WriteInfo(fmt.Sprintf("Preview search with query: %s", searchQuery))
+
+		results, err := fetchResults(ctx, j, searchQuery)
+		if err != nil {
+			logFetchError(fmt.Sprintf("Preview query failed: %s", searchQuery), err)
+			if !searchQuery.hasIDs() {
+				lastErr = err
+			}
+			continue
+		}
+
+		succeeded++
+		candidates.add(evaluate(results))
+	}
+
+	if succeeded == 0 && lastErr != nil {
+		return nil, fmt.Errorf("all preview queries failed: %w", lastErr)
+	}
+
+	return candidates.sorted(), nil
+}
+
+// PreviewMovieQuery returns the scored candidates MakeMovieQuery would choose from
+func PreviewMovieQuery(ctx context.Context, query string, tmdbID int, year int, quality string) ([]Candidate, error) {
+	ensureCapabilities(ctx)
+	identity := resolveMovieIdentity(query, tmdbID, year)
+	return previewSearch(ctx, movieSearchQueries(query, quality, identity), func(results []jackett.Result) []searchResult {
+		return evaluateMovieResults(results, identity, quality, query)
+	})
+}
+
+// PreviewShowQuery returns the scored series and season pack candidates for the selected
+// seasons of a show
+func PreviewShowQuery(ctx context.Context, query string, seasons []int, selection ShowSelection, tmdbID int, year int, quality string) ([]Candidate, error) {
+	ensureCapabilities(ctx)
+	identity := resolveShowIdentity(query, tmdbID, year)
+	seasons, wanted, err := selectEpisodes(query, identity, seasons, selection)
+	if err != nil {
+		return nil, err
+	}
+
+	var queries []searchQuery
+	for _, title := range showTitles(query, identity) {
+		queries = append(queries, seriesBundleQueries(title, seasons, quality)...)
+		for _, season := range seasonsWithGaps(wanted.missing(nil)) {
+			queries = append(queries, seasonPackQueries(title, season, quality, identity)...)
+		}
+	}
+
+	candidates, err := previewSearch(ctx, uniqueQueries(queries), func(results []jackett.Result) []searchResult {
+		return evaluateShowResults(results, identity, quality, query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Show which wanted episodes each release would cover
+	for i := range candidates {
+		candidates[i].Coverage = parseCoverage(candidates[i].Title, seasons).intersect(wanted).String()
+	}
+	return candidates, nil
+}
+
+// PreviewAnimeMovieQuery returns the scored candidates for an anime movie
+func PreviewAnimeMovieQuery(ctx context.Context, query string, tmdbID int, quality string) ([]Candidate, error) {
+	ensureCapabilities(ctx)
+	titles := resolveMovieIdentity(query, tmdbID, 0).titles
+
+	var queries []string
+	for _, title := range titles {
+		for _, pattern := range animeMoviePatterns {
+			queries = append(queries, fmt.Sprintf(pattern, title, quality))
+		}
+	}
+
+	return previewSearch(ctx, textQueries(searchCategories(MediaAnimeMovie), queries...), func(results []jackett.Result) []searchResult {
+		return evaluateAnimeResults(results, tmdbID, quality, query, titles, MediaAnimeMovie)
+	})
+}
+
+// PreviewAnimeShowQuery returns the scored candidates for the selected episodes of an
+// anime series: batches for the whole series, single episodes when only part of it was asked for
+func PreviewAnimeShowQuery(ctx context.Context, query string, seasons []int, selection ShowSelection, tmdbID int, quality string) ([]Candidate, error) {
+	ensureCapabilities(ctx)
+	identity := resolveShowIdentity(query, tmdbID, 0)
+	titles := identity.titles
+	seasons, wanted, err := selectEpisodes(query, identity, seasons, selection)
+	if err != nil {
+		return nil, err
+	}
+	mapping := resolveAnimeMapping(tmdbID, seasons)
+
+	var queries []string
+	for _, title := range titles {
+		if selection.partial() {
+			for _, key := range wanted.missing(nil) {
+				animeTime, fallback := animeEpisodeQueries(title, mapping, key)
+				queries = append(queries, animeTime...)
+				queries = append(queries, fallback...)
+			}
+			continue
+		}
+		for _, pattern := range animeTimeBatchPatterns {
+			queries = append(queries, fmt.Sprintf(pattern, title))
+		}
+		for _, pattern := range fallbackBatchPatterns {
+			queries = append(queries, fmt.Sprintf(pattern, title))
+		}
+	}
+
+	candidates, err := previewSearch(ctx, textQueries(searchCategories(MediaAnimeShow), queries...), func(results []jackett.Result) []searchResult {
+		return evaluateAnimeResults(results, tmdbID, quality, query, titles, MediaAnimeShow)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Show which selected episodes each release would cover
+	for i := range candidates {
+		candidates[i].Coverage = parseAnimeCoverage(candidates[i].Title, titles, mapping).intersect(wanted).String()
+	}
+	return candidates, nil
+}
+
+// uniqueQueries drops repeated queries, such as the ID search generated for every title
+func uniqueQueries(queries []searchQuery) []searchQuery {
+	seen := make(map[string]bool)
+	var unique []searchQuery
+	for _, q := range queries {
+		if seen[q.String()] {
+			continue
+		}
+		seen[q.String()] = true
+		unique = append(unique, q)
+	}
+	return unique
+}
@@ -0,0 +1,162 @@
+package jackett
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"high-seas/src/logger"
+	"high-seas/src/utils"
+)
+
+// allIndexers is the Jackett aggregate indexer, which fans a query out to every indexer
+const allIndexers = "all"
+
+var (
+	// Requests per second and burst for any indexer without its own limit; a rate of 0 disables limiting
+	defaultIndexerRate  = utils.EnvVar("INDEXER_RATE_LIMIT", "2")
+	defaultIndexerBurst = utils.EnvVarInt("INDEXER_RATE_BURST", 4)
+	// Per-indexer overrides as "id=rate[:burst],...", e.g. "nyaasi=0.5:1,1337x=1"
+	indexerRateOverrides = utils.EnvVar("INDEXER_RATE_LIMITS", "")
+)
+
+// tokenBucket allows rate requests per second on average with bursts of up to burst requests
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait blocks until a token is available or the context is done
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b.rate <= 0 {
+		return ctx.Err()
+	}
+
+	for {
+		b.mutex.Lock()
+		now := time.Now()
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mutex.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mutex.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// indexerLimiter keeps one token bucket per indexer
+type indexerLimiter struct {
+	mutex        sync.Mutex
+	buckets      map[string]*tokenBucket
+	configured   []string
+	defaultRate  float64
+	defaultBurst int
+}
+
+var (
+	globalLimiter     *indexerLimiter
+	globalLimiterOnce sync.Once
+)
+
+// getIndexerLimiter returns the shared limiter built from the environment
+func getIndexerLimiter() *indexerLimiter {
+	globalLimiterOnce.Do(func() {
+		rate, err := strconv.ParseFloat(defaultIndexerRate, 64)
+		if err != nil {
+			logger.WriteWarning(fmt.Sprintf("Invalid INDEXER_RATE_LIMIT %q, using 2 requests/second", defaultIndexerRate))
+			rate = 2
+		}
+		globalLimiter = newIndexerLimiter(rate, defaultIndexerBurst, indexerRateOverrides)
+	})
+	return globalLimiter
+}
+
+func newIndexerLimiter(rate float64, burst int, overrides string) *indexerLimiter {
+	limiter := &indexerLimiter{
+		buckets:      make(map[string]*tokenBucket),
+		defaultRate:  rate,
+		defaultBurst: burst,
+	}
+
+	for _, entry := range strings.Split(overrides, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, limit, ok := strings.Cut(entry, "=")
+		rateText, burstText, hasBurst := strings.Cut(limit, ":")
+		indexerRate, err := strconv.ParseFloat(rateText, 64)
+		if !ok || err != nil {
+			logger.WriteWarning(fmt.Sprintf("Ignoring invalid indexer rate limit %q", entry))
+			continue
+		}
+		indexerBurst := burst
+		if hasBurst {
+			if parsed, err := strconv.Atoi(burstText); err == nil {
+				indexerBurst = parsed
+			}
+		}
+
+		id = strings.TrimSpace(id)
+		limiter.buckets[id] = newTokenBucket(indexerRate, indexerBurst)
+		limiter.configured = append(limiter.configured, id)
+	}
+
+	return limiter
+}
+
+func (l *indexerLimiter) bucket(indexer string) *tokenBucket {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b, ok := l.buckets[indexer]
+	if !ok {
+		b = newTokenBucket(l.defaultRate, l.defaultBurst)
+		l.buckets[indexer] = b
+	}
+	return b
+}
+
+// wait takes a token for the indexer. A query to the aggregate indexer reaches every
+// indexer, so it also takes a token from each indexer with its own limit.
+func (l *indexerLimiter) wait(ctx context.Context, indexer string) error {
+	if err := l.bucket(indexer).wait(ctx); err != nil {
+		return err
+	}
+	if indexer != allIndexers {
+		return nil
+	}
+
+	for _, id := range l.configured {
+		if err := l.bucket(id).wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package jackett
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		waits int
+		min   time.Duration
+	}{
+		{"within the burst", 10, 3, 3, 0},
+		{"past the burst", 20, 2, 4, 80 * time.Millisecond},
+		{"zero burst still allows one", 20, 0, 2, 40 * time.Millisecond},
+		{"unlimited", 0, 1, 50, 0},
+	}
+
+	for _, test := range tests {
+		bucket := newTokenBucket(test.rate, test.burst)
+		start := time.Now()
+		for i := 0; i < test.waits; i++ {
+			if err := bucket.wait(context.Background()); err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+		}
+		elapsed := time.Since(start)
+		if elapsed < test.min || (test.min == 0 && elapsed > 100*time.Millisecond) {
+			t.Errorf("%s: %d waits took %s, want at least %s", test.name, test.waits, elapsed, test.min)
+		}
+	}
+}
+
+func TestTokenBucketStopsOnCancel(t *testing.T) {
+	bucket := newTokenBucket(0.01, 1)
+	if err := bucket.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bucket.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the wait to end with the context, got %v", err)
+	}
+}
+
+func TestIndexerLimiterOverrides(t *testing.T) {
+	limiter := newIndexerLimiter(2, 4, "nyaasi=0.5:1, broken, 1337x=3")
+
+	if got := limiter.bucket("nyaasi"); got.rate != 0.5 || got.burst != 1 {
+		t.Errorf("unexpected nyaasi bucket %+v", got)
+	}
+	if got := limiter.bucket("1337x"); got.rate != 3 || got.burst != 4 {
+		t.Errorf("expected 1337x to keep the default burst, got %+v", got)
+	}
+	if got := limiter.bucket("other"); got.rate != 2 {
+		t.Errorf("expected the default rate for other indexers, got %+v", got)
+	}
+	if len(limiter.configured) != 2 {
+		t.Errorf("invalid overrides should be ignored, got %v", limiter.configured)
+	}
+}
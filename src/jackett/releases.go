@@ -0,0 +1,94 @@
+package jackett
+
+import (
+	"fmt"
+	"time"
+
+	"high-seas/src/logger"
+	"high-seas/src/tmdb"
+)
+
+// releaseSchedule is what TMDb says is out of a title so far and when more will be
+type releaseSchedule struct {
+	// Shows only: the latest episode aired, zero when unknown
+	lastAired episodeKey
+	// A movie's digital or physical release, or a show's next episode, zero when unknown
+	next time.Time
+}
+
+func movieSchedule(info *tmdb.MovieInfo) releaseSchedule {
+	return releaseSchedule{next: info.HomeRelease()}
+}
+
+func showSchedule(info *tmdb.ShowInfo) releaseSchedule {
+	var schedule releaseSchedule
+	if last := info.LastEpisodeToAir; last != nil && last.SeasonNumber > 0 {
+		schedule.lastAired = episodeKey{last.SeasonNumber, last.EpisodeNumber}
+	}
+	if next := info.NextEpisodeToAir; next != nil {
+		schedule.next = tmdb.ParseDate(next.AirDate)
+	}
+	return schedule
+}
+
+// released reports whether the next release date has passed, or isn't known
+func (r releaseSchedule) released(now time.Time) bool {
+	return !r.next.After(now)
+}
+
+// aired reports whether an episode has aired, assuming it has when TMDb doesn't say
+func (r releaseSchedule) aired(key episodeKey) bool {
+	if r.lastAired.season == 0 {
+		return true
+	}
+	return key.season < r.lastAired.season ||
+		(key.season == r.lastAired.season && key.episode <= r.lastAired.episode)
+}
+
+// splitAired separates the episodes that have aired from those still to come, in order
+func (r releaseSchedule) splitAired(episodes episodeSet) (aired, upcoming []episodeKey) {
+	for _, key := range episodes.missing(nil) {
+		if r.aired(key) {
+			aired = append(aired, key)
+		} else {
+			upcoming = append(upcoming, key)
+		}
+	}
+	return aired, upcoming
+}
+
+func releaseDay(date time.Time) string {
+	return date.Format("2006-01-02")
+}
+
+// logUpcoming notes the episodes a search skips because they haven't aired
+func logUpcoming(query string, upcoming []episodeKey, release releaseSchedule) {
+	if len(upcoming) == 0 {
+		return
+	}
+	next := "unknown"
+	if !release.next.IsZero() {
+		next = releaseDay(release.next)
+	}
+	logger.WriteInfo(fmt.Sprintf("Skipping %d unaired episodes of %s, next airing %s", len(upcoming), query, next))
+}
+
+// awaitMovieRelease puts a movie that isn't out on digital or disc yet on the wanted list
+// instead of searching, so the search doesn't turn up CAMs
+func awaitMovieRelease(mediaType string, query string, identity mediaIdentity, quality string) error {
+	if identity.release.released(time.Now()) {
+		return nil
+	}
+	logger.WriteInfo(fmt.Sprintf("%s isn't released until %s, searching for it then", query, releaseDay(identity.release.next)))
+	wantMovie(mediaType, query, identity, quality)
+	return fmt.Errorf("%w: %s until %s", ErrNotReleased, query, releaseDay(identity.release.next))
+}
+
+// notReleased is the outcome of a show search with no aired episodes to look for: the
+// upcoming ones are on the wanted list, or nothing was left to grab
+func notReleased(query string, upcoming []episodeKey) error {
+	if len(upcoming) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d episodes of %s", ErrNotReleased, len(upcoming), query)
+}
@@ -0,0 +1,84 @@
+package jackett
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"high-seas/src/db"
+)
+
+func TestWantedShowWaitsForUnairedEpisodes(t *testing.T) {
+	_, client := setupHarness(t, nil)
+	nextAiring := time.Now().Add(72 * time.Hour)
+	identity := mediaIdentity{release: releaseSchedule{lastAired: episodeKey{1, 3}, next: nextAiring}}
+
+	wantShow(MediaShow, "Night Harbor", identity, "1080p", []int{3, 3}, wantedEpisodes([]int{3, 3}), wantedEpisodes([]int{3, 3}).missing(nil), nil, true)
+	item, _ := findWanted(1)
+	wantedMutex.Lock()
+	next, attempts := item.nextSearchAt, item.attempts
+	wantedMutex.Unlock()
+
+	// Every aired episode is missing, so the backoff comes before the next airing
+	if want := time.Now().Add(wantedRetryMin); next.After(want) || attempts != 1 {
+		t.Errorf("expected a retry within %s, got %s after %d attempts", wantedRetryMin, next, attempts)
+	}
+
+	markEpisodesGrabbed(item.key, wantedEpisodes([]int{3}))
+	episodesSearched(item.key, identity.release, []episodeKey{{2, 1}, {2, 2}, {2, 3}})
+	wantedMutex.Lock()
+	next = item.nextSearchAt
+	wantedMutex.Unlock()
+	if !next.Equal(nextAiring) {
+		t.Errorf("expected the next search when S02E01 airs at %s, got %s", nextAiring, next)
+	}
+
+	err := searchWanted(context.Background(), item)
+	if !errors.Is(err, ErrNotReleased) {
+		t.Errorf("expected ErrNotReleased before anything airs, got %v", err)
+	}
+	if got := client.links(); len(got) != 0 {
+		t.Errorf("unaired episodes shouldn't be searched for, grabbed %v", got)
+	}
+}
+
+func TestUnreleasedMovieWaitsForRelease(t *testing.T) {
+	setupHarness(t, nil)
+	release := time.Now().Add(30 * 24 * time.Hour)
+	identity := mediaIdentity{release: releaseSchedule{next: release}}
+
+	if err := awaitMovieRelease(MediaMovie, "The Quiet Orbit", identity, "1080p"); !errors.Is(err, ErrNotReleased) {
+		t.Fatalf("expected ErrNotReleased, got %v", err)
+	}
+
+	records := WantedList()
+	if len(records) != 1 || records[0].NextSearchAt == nil || !records[0].NextSearchAt.Equal(release) {
+		t.Fatalf("expected a search scheduled on release, got %+v", records)
+	}
+	if records[0].Attempts != 0 {
+		t.Errorf("waiting for release shouldn't count as an attempt, got %d", records[0].Attempts)
+	}
+}
+
+func TestStoredWantedItemResolvesLazily(t *testing.T) {
+	releaseAt := time.Now().Add(72 * time.Hour)
+	item := fromRecord(db.WantedItem{
+		ID: 4, Key: "anime_show:0:kaiju academy", MediaType: MediaAnimeShow, Query: "Kaiju Academy", Year: 2022,
+		Seasons: []int{12, 12}, Missing: []string{"S02E01"}, LastAired: "S01E12", ReleaseAt: &releaseAt,
+	})
+
+	// Loading makes no lookups, so the item is known by its stored details only
+	if item.mapping != nil || item.identity.year != 2022 {
+		t.Fatalf("expected an unresolved item, got mapping %v and year %d", item.mapping, item.identity.year)
+	}
+	if item.release.lastAired != (episodeKey{1, 12}) || !item.release.next.Equal(releaseAt) {
+		t.Errorf("expected the stored schedule, got %+v", item.release)
+	}
+
+	// Without TMDb the lookup keeps the stored schedule and falls back to the stored seasons
+	item.resolve()
+	if item.mapping == nil || item.identity.year != 2022 || !item.release.next.Equal(releaseAt) {
+		t.Errorf("expected a resolved item keeping its schedule, got mapping %v, year %d, schedule %+v", item.mapping, item.identity.year, item.release)
+	}
+}
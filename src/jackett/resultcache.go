@@ -0,0 +1,71 @@
+package jackett
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	jackett "github.com/webtor-io/go-jackett"
+	"high-seas/src/cache"
+	"high-seas/src/metrics"
+	"high-seas/src/utils"
+)
+
+var (
+	// resultCacheTTL is how long indexer responses are reused, 0 to always ask Jackett
+	resultCacheTTL = utils.EnvVarDuration("INDEXER_CACHE_TTL", 5*time.Minute)
+	cacheEnabled   = utils.EnvVarBool("ENABLE_CACHE", true)
+)
+
+type bypassCacheKey struct{}
+
+// WithCacheBypass marks a search as a forced refresh, so indexers are queried even when
+// a recent response is cached. The fresh responses still replace the cached ones.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassCacheKey{}).(bool)
+	return bypass
+}
+
+// resultCacheKey identifies a query by what the indexer sees, ignoring case, spacing and
+// category order
+func resultCacheKey(indexer string, q searchQuery) string {
+	categories := make([]string, len(q.categories))
+	for i, c := range q.categories {
+		categories[i] = fmt.Sprint(c)
+	}
+	sort.Strings(categories)
+
+	text := strings.Join(strings.Fields(strings.ToLower(q.text)), " ")
+	return fmt.Sprintf("jackett:%s:%s:%q:%s:%s:%d:%d:%d:%d", indexer, q.searchType, text,
+		strings.Join(categories, ","), strings.ToLower(q.imdbID), q.tvdbID, q.tmdbID, q.season, q.episode)
+}
+
+// cachedResults returns a recent response for the query, recording the hit or miss
+func cachedResults(ctx context.Context, key string) ([]jackett.Result, bool) {
+	if !cacheEnabled || resultCacheTTL <= 0 || cacheBypassed(ctx) {
+		return nil, false
+	}
+
+	if data, ok := cache.Get(key); ok {
+		if results, ok := data.([]jackett.Result); ok {
+			metrics.GetGlobalMetrics().IncrementCacheHits()
+			return results, true
+		}
+	}
+	metrics.GetGlobalMetrics().IncrementCacheMisses()
+	return nil, false
+}
+
+// storeResults caches a successful response for the query
+func storeResults(key string, results []jackett.Result) {
+	if !cacheEnabled || resultCacheTTL <= 0 {
+		return
+	}
+	cache.SetWithTTL(key, results, resultCacheTTL)
+}
@@ -0,0 +1,55 @@
+package jackett
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	jackett "github.com/webtor-io/go-jackett"
+)
+
+func TestResultCacheKey(t *testing.T) {
+	base := searchQuery{searchType: torznabSearch, text: "Night Harbor S01", categories: []uint{5000, 5040}}
+	tests := []struct {
+		name  string
+		query searchQuery
+		same  bool
+	}{
+		{"case and spacing", searchQuery{searchType: torznabSearch, text: "  night   HARBOR s01 ", categories: []uint{5000, 5040}}, true},
+		{"category order", searchQuery{searchType: torznabSearch, text: "Night Harbor S01", categories: []uint{5040, 5000}}, true},
+		{"other text", searchQuery{searchType: torznabSearch, text: "Night Harbor S02", categories: []uint{5000, 5040}}, false},
+		{"other categories", searchQuery{searchType: torznabSearch, text: "Night Harbor S01", categories: []uint{5000}}, false},
+		{"other search type", searchQuery{searchType: torznabTVSearch, text: "Night Harbor S01", categories: []uint{5000, 5040}}, false},
+		{"with an episode", searchQuery{searchType: torznabSearch, text: "Night Harbor S01", categories: []uint{5000, 5040}, season: 1, episode: 2}, false},
+	}
+
+	for _, test := range tests {
+		if same := resultCacheKey("all", test.query) == resultCacheKey("all", base); same != test.same {
+			t.Errorf("%s: expected same key %v", test.name, test.same)
+		}
+	}
+	if resultCacheKey("all", base) == resultCacheKey("nyaasi", base) {
+		t.Error("keys should differ per indexer")
+	}
+}
+
+func TestCachedResultsExpireAndBypass(t *testing.T) {
+	oldTTL, oldEnabled := resultCacheTTL, cacheEnabled
+	resultCacheTTL, cacheEnabled = 50*time.Millisecond, true
+	t.Cleanup(func() { resultCacheTTL, cacheEnabled = oldTTL, oldEnabled })
+
+	key := resultCacheKey("all", searchQuery{searchType: torznabSearch, text: "cache test " + t.Name()})
+	storeResults(key, []jackett.Result{{Title: "Night.Harbor.S01E01"}})
+
+	if results, ok := cachedResults(context.Background(), key); !ok || len(results) != 1 {
+		t.Fatalf("expected a cached response, got %v %v", results, ok)
+	}
+	if _, ok := cachedResults(WithCacheBypass(context.Background()), key); ok {
+		t.Error("a refresh should skip the cached response")
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	if _, ok := cachedResults(context.Background(), key); ok {
+		t.Error("the response should have expired")
+	}
+}
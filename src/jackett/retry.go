@@ -0,0 +1,133 @@
+package jackett
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	jackett "github.com/webtor-io/go-jackett"
+	"high-seas/src/db"
+	"high-seas/src/logger"
+	"high-seas/src/utils"
+)
+
+// wantedCheckInterval is how often the wanted list is checked for retries that are due, 0 to disable
+var wantedCheckInterval = utils.EnvVarDuration("WANTED_CHECK_INTERVAL", 5*time.Minute)
+
+// StartWantedRetries searches again for wanted items as their retries come due, until the
+// context is done
+func StartWantedRetries(ctx context.Context) {
+	if wantedCheckInterval <= 0 {
+		logger.WriteInfo("Wanted list retries disabled")
+		return
+	}
+
+	logger.WriteInfo(fmt.Sprintf("Checking the wanted list for retries every %s", wantedCheckInterval))
+	ticker := time.NewTicker(wantedCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.WriteInfo("Wanted list retries stopped")
+			return
+		case <-ticker.C:
+			for _, item := range dueWantedItems(time.Now()) {
+				if ctx.Err() != nil {
+					return
+				}
+				if err := searchWanted(ctx, item); err != nil {
+					logger.WriteWarning(fmt.Sprintf("Retry for %s found nothing: %v", item.query, err))
+				}
+			}
+		}
+	}
+}
+
+// SearchWanted searches for a wanted item now, whether or not its retry is due, and
+// returns what's left of it on the list
+func SearchWanted(ctx context.Context, id uint) (*db.WantedItem, error) {
+	item, ok := findWanted(id)
+	if !ok {
+		return nil, ErrWantedNotFound
+	}
+
+	err := searchWanted(ctx, item)
+
+	remaining, ok := findWanted(id)
+	if !ok {
+		return nil, err
+	}
+	wantedMutex.Lock()
+	record := remaining.record()
+	wantedMutex.Unlock()
+	return &record, err
+}
+
+// searchWanted repeats the search for a wanted item. Movies go through the full search,
+// which drops them from the list or schedules the next retry; shows only search for the
+// missing episodes that have aired.
+func searchWanted(ctx context.Context, item *wantedItem) error {
+	item.resolve()
+	logger.WriteInfo(fmt.Sprintf("Retrying search for wanted %s %s (attempt %d)", item.mediaType, item.query, item.attempts+1))
+
+	switch item.mediaType {
+	case MediaMovie:
+		return MakeMovieQuery(ctx, item.query, item.tmdbID, item.identity.year, item.quality)
+	case MediaAnimeMovie:
+		return MakeAnimeMovieQuery(ctx, item.query, item.tmdbID, item.quality)
+	}
+
+	// Episodes may have aired since the last search
+	release := resolveShowIdentity(item.query, item.tmdbID, item.identity.year).release
+	wantedMutex.Lock()
+	if release == (releaseSchedule{}) {
+		release = item.release
+	}
+	aired, upcoming := release.splitAired(item.missing)
+	wantedMutex.Unlock()
+	if len(aired) == 0 {
+		episodesSearched(item.key, release, upcoming)
+		if len(upcoming) > 0 {
+			return fmt.Errorf("%w: %d episodes of %s", ErrNotReleased, len(upcoming), item.query)
+		}
+		return nil
+	}
+
+	ctx, cancel := withSearchDeadline(ctx)
+	defer cancel()
+	j := jackett.NewJackett(&jackett.Settings{
+		ApiURL: fmt.Sprintf("http://%s:%s/", ip, port),
+		ApiKey: fmt.Sprintf("%s", apiKey),
+	})
+	ensureCapabilities(ctx)
+
+	var stillMissing []episodeKey
+	if item.mediaType == MediaAnimeShow {
+		titles := showTitles(item.query, item.identity)
+		left, err := searchAnimeEpisodesByOne(ctx, j, item.query, titles, item.tmdbID, item.quality, aired, item.mapping)
+		if err != nil {
+			return err
+		}
+		grabbed := newEpisodeSet(aired)
+		for _, key := range left {
+			delete(grabbed, key)
+		}
+		markEpisodesGrabbed(item.key, grabbed)
+		stillMissing = left
+	} else {
+		planner := newEpisodePlanner(newEpisodeSet(aired), episodeSet{}, nil)
+		searchMissingEpisodes(ctx, j, item.query, item.identity, item.quality, item.seasons, planner)
+		if err := searchError(ctx, item.query, nil); err != nil {
+			return err
+		}
+		markEpisodesGrabbed(item.key, planner.covered)
+		stillMissing = planner.gaps()
+	}
+
+	episodesSearched(item.key, release, append(stillMissing, upcoming...))
+	if len(stillMissing) > 0 {
+		return fmt.Errorf("%w for %d episodes of %s", ErrNoMatches, len(stillMissing), item.query)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package jackett
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	jackett "github.com/webtor-io/go-jackett"
+	"high-seas/src/logger"
+	"high-seas/src/utils"
+)
+
+var (
+	// rssSyncInterval is how often indexer feeds are checked for wanted releases, 0 to disable
+	rssSyncInterval = utils.EnvVarDuration("RSS_SYNC_INTERVAL", 15*time.Minute)
+	// rssSeenRetention is how long a release is remembered so it's only considered once
+	rssSeenRetention = 48 * time.Hour
+)
+
+var (
+	rssSeen      = make(map[string]time.Time)
+	rssSeenMutex sync.Mutex
+)
+
+// StartRSSSync polls every indexer's recent releases on an interval and grabs the ones
+// that match the wanted list, until the context is done
+func StartRSSSync(ctx context.Context) {
+	if rssSyncInterval <= 0 {
+		logger.WriteInfo("RSS sync disabled")
+		return
+	}
+
+	logger.WriteInfo(fmt.Sprintf("Starting RSS sync every %s", rssSyncInterval))
+	ticker := time.NewTicker(rssSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.WriteInfo("RSS sync stopped")
+			return
+		case <-ticker.C:
+			syncRSS(ctx)
+		}
+	}
+}
+
+// syncRSS reads each indexer's feed once and grabs new releases for wanted items
+func syncRSS(ctx context.Context) {
+	items := wantedItems()
+	if len(items) == 0 {
+		return
+	}
+
+	ensureCapabilities(ctx)
+	feed := searchQuery{searchType: torznabSearch, categories: rssCategories(items)}
+
+	grabbed := 0
+	for _, indexer := range rssIndexers() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Feeds go straight to the indexer; a cached response would only hide new releases
+		results, err := torznabFetch(ctx, indexer, feed)
+		if err != nil {
+			logFetchError(fmt.Sprintf("RSS sync of %s failed", indexer), err)
+			continue
+		}
+
+		for _, result := range unseenReleases(results) {
+			retry := false
+			for _, item := range items {
+				outcome := grabWantedRelease(ctx, result, item)
+				if outcome == rssGrabbed {
+					grabbed++
+					retry = false
+					break
+				}
+				retry = retry || outcome == rssRetry
+			}
+			// A release that couldn't be added for now is looked at again next sync
+			if !retry {
+				markSeen(result)
+			}
+		}
+	}
+
+	pruneSeenReleases()
+	logger.WriteInfo(fmt.Sprintf("RSS sync checked %d wanted items, grabbed %d releases", len(items), grabbed))
+}
+
+// rssIndexers returns the configured indexers to poll, or Jackett's aggregate feed
+// when discovery hasn't listed them
+func rssIndexers() []string {
+	capabilitiesLock.Lock()
+	defer capabilitiesLock.Unlock()
+
+	var indexers []string
+	if capabilities != nil {
+		for _, indexer := range capabilities.Indexers {
+			if indexer.Error == "" {
+				indexers = append(indexers, indexer.ID)
+			}
+		}
+	}
+	if len(indexers) == 0 {
+		return []string{allIndexers}
+	}
+	return indexers
+}
+
+// rssCategories combines the categories of every media type on the wanted list
+func rssCategories(items []*wantedItem) []uint {
+	seen := make(map[uint]bool)
+	var categories []uint
+	for _, item := range items {
+		for _, category := range searchCategories(item.mediaType) {
+			if !seen[category] {
+				seen[category] = true
+				categories = append(categories, category)
+			}
+		}
+	}
+	return categories
+}
+
+// unseenReleases drops releases an earlier sync already grabbed or rejected
+func unseenReleases(results []jackett.Result) []jackett.Result {
+	rssSeenMutex.Lock()
+	defer rssSeenMutex.Unlock()
+
+	var unseen []jackett.Result
+	for _, result := range results {
+		if _, ok := rssSeen[releaseKey(result)]; !ok {
+			unseen = append(unseen, result)
+		}
+	}
+	return unseen
+}
+
+// markSeen remembers a release so later syncs skip it
+func markSeen(result jackett.Result) {
+	rssSeenMutex.Lock()
+	defer rssSeenMutex.Unlock()
+	rssSeen[releaseKey(result)] = time.Now()
+}
+
+func releaseKey(result jackett.Result) string {
+	if result.Guid != "" {
+		return result.Guid
+	}
+	return result.Link + result.MagnetUri
+}
+
+func pruneSeenReleases() {
+	rssSeenMutex.Lock()
+	defer rssSeenMutex.Unlock()
+
+	for key, seenAt := range rssSeen {
+		if time.Since(seenAt) > rssSeenRetention {
+			delete(rssSeen, key)
+		}
+	}
+}
+
+// rssOutcome is what became of a feed release checked against a wanted item
+type rssOutcome int
+
+const (
+	// rssRejected releases aren't for the item or won't do, whenever they're looked at
+	rssRejected rssOutcome = iota
+	rssGrabbed
+	// rssRetry releases fit but couldn't be added for now, e.g. with Deluge down or the disk full
+	rssRetry
+)
+
+// grabWantedRelease grabs a feed release if it's the wanted item, passes the same checks
+// as a search, and covers something still missing
+func grabWantedRelease(ctx context.Context, result jackett.Result, item *wantedItem) rssOutcome {
+	item.resolve()
+	titles := showTitles(item.query, item.identity)
+	if titleInRelease(result.Title, titles) == "" {
+		return rssRejected
+	}
+	releases := []jackett.Result{result}
+
+	var candidate searchResult
+	var covered episodeSet
+	link := result.Link
+	switch item.mediaType {
+	case MediaMovie:
+		candidate = evaluateMovieResults(releases, item.identity, item.quality, item.query)[0]
+		if !candidate.breakdown.Accepted || !movieWanted(item.key) {
+			return rssRejected
+		}
+
+	case MediaAnimeMovie:
+		candidate = evaluateAnimeResults(releases, item.tmdbID, item.quality, item.query, titles, item.mediaType)[0]
+		if !candidate.breakdown.Accepted || !movieWanted(item.key) {
+			return rssRejected
+		}
+		var err error
+		if link, err = releaseLink(&result); err != nil {
+			return rssRejected
+		}
+
+	case MediaShow, MediaAnimeShow:
+		var contains episodeSet
+		if item.mediaType == MediaShow {
+			contains = parseCoverage(result.Title, item.seasons)
+			candidate = evaluateShowResults(releases, item.identity, item.quality, item.query)[0]
+		} else {
+			contains = parseAnimeCoverage(result.Title, titles, item.mapping)
+			candidate = evaluateAnimeResults(releases, item.tmdbID, item.quality, item.query, titles, item.mediaType)[0]
+			link = result.MagnetUri
+		}
+		covered = usefulEpisodes(item.key, contains)
+		if !worthGrabbing(len(covered), len(contains)) || !candidate.breakdown.Accepted || link == "" {
+			return rssRejected
+		}
+		candidate.episodes = covered
+
+	default:
+		return rssRejected
+	}
+
+	if err := sendToDeluge(ctx, candidate, link); err != nil {
+		if errors.Is(err, ErrInvalidRelease) {
+			return rssRejected
+		}
+		return rssRetry
+	}
+
+	if covered != nil {
+		markEpisodesGrabbed(item.key, covered)
+	} else {
+		fulfilMovie(item.mediaType, item.tmdbID, item.query)
+	}
+	logger.WriteInfo(fmt.Sprintf("RSS sync grabbed %s for %s", result.Title, item.query))
+	return rssGrabbed
+}
@@ -0,0 +1,78 @@
+package jackett
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	jackett "github.com/webtor-io/go-jackett"
+)
+
+func TestUnseenReleases(t *testing.T) {
+	setupHarness(t, nil)
+	results := []jackett.Result{
+		{Guid: "guid-1", Title: "Night.Harbor.S01E01"},
+		{Link: "http://tracker/2.torrent", Title: "Night.Harbor.S01E02"},
+		{MagnetUri: "magnet:?xt=urn:btih:3", Title: "Night.Harbor.S01E03"},
+	}
+
+	if got := unseenReleases(results); len(got) != 3 {
+		t.Fatalf("nothing has been seen yet, got %d", len(got))
+	}
+	// Looking at a release doesn't mark it seen
+	if got := unseenReleases(results); len(got) != 3 {
+		t.Fatalf("expected releases to stay unseen until marked, got %d", len(got))
+	}
+
+	markSeen(results[0])
+	markSeen(jackett.Result{Link: "http://tracker/2.torrent"})
+	if got := unseenReleases(results); len(got) != 1 || got[0].Title != "Night.Harbor.S01E03" {
+		t.Errorf("expected only S01E03 left, got %v", got)
+	}
+}
+
+func TestSyncRSSRetriesReleasesThatDidNotFit(t *testing.T) {
+	indexer, client := setupHarness(t, nil)
+	if err := MakeMovieQuery(context.Background(), "The Quiet Orbit", 0, 2017, "1080p"); !errors.Is(err, ErrNoMatches) {
+		t.Fatalf("expected the movie to be wanted, got %v", err)
+	}
+
+	// The feed now has the release, but the download disk is full
+	indexer.fixtures[""] = "movie_quiet_orbit.xml"
+	client.free = 0
+	syncRSS(context.Background())
+	if links := client.links(); len(links) != 0 {
+		t.Fatalf("expected nothing added while the disk is full, got %v", links)
+	}
+
+	client.free = 1 << 50
+	syncRSS(context.Background())
+	bluray := indexer.server.URL + "/download/quiet-orbit-2017-bluray.torrent"
+	if links := client.links(); len(links) != 1 || links[0] != bluray {
+		t.Errorf("expected the BluRay to be grabbed on the next sync, got %v", links)
+	}
+	if movieWanted(wantedKey(MediaMovie, 0, "The Quiet Orbit")) {
+		t.Error("the movie should be off the wanted list")
+	}
+}
+
+func TestGrabWantedReleaseRejectsOtherTitles(t *testing.T) {
+	setupHarness(t, nil)
+	if err := MakeMovieQuery(context.Background(), "The Quiet Orbit", 0, 2017, "1080p"); !errors.Is(err, ErrNoMatches) {
+		t.Fatalf("expected the movie to be wanted, got %v", err)
+	}
+	item, ok := findWantedByKey(wantedKey(MediaMovie, 0, "The Quiet Orbit"))
+	if !ok {
+		t.Fatal("movie isn't on the wanted list")
+	}
+
+	tests := []jackett.Result{
+		{Title: "Night.Harbor.S01E01.1080p", Link: "http://tracker/1.torrent", Size: 1 << 30},
+		{Title: "The Quiet Orbit 2017 HDCAM x264-NOGRP", Link: "http://tracker/2.torrent", Size: 1 << 30},
+	}
+	for _, result := range tests {
+		if got := grabWantedRelease(context.Background(), result, item); got != rssRejected {
+			t.Errorf("%s: expected a rejection, got %v", result.Title, got)
+		}
+	}
+}
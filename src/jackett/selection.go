@@ -0,0 +1,137 @@
+package jackett
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"high-seas/src/logger"
+)
+
+// ErrInvalidSelection is returned when a show request asks for seasons or episodes the show doesn't have
+var ErrInvalidSelection = errors.New("invalid season selection")
+
+// episodeSpecPattern matches one entry of an episode selection: S03, S03E05 or S03E04-E08
+var episodeSpecPattern = regexp.MustCompile(`(?i)^s(\d{1,2})(?:e(\d{1,3})(?:\s*[-–]\s*e?(\d{1,3}))?)?$`)
+
+// ShowSelection is the part of a show a request asks for. With no seasons, episodes or
+// latest season picked, every regular season is searched.
+type ShowSelection struct {
+	// Season numbers to search
+	Seasons []int
+	// Episodes lists seasons, episodes and episode ranges, e.g. "S03E04-E08, S04E01"
+	Episodes string
+	// Latest picks the latest season that has started airing
+	Latest bool
+	// Specials opts in to season 0, which is otherwise skipped
+	Specials bool
+}
+
+// partial reports whether the selection picks some seasons or episodes rather than all
+func (s ShowSelection) partial() bool {
+	return len(s.Seasons) > 0 || strings.TrimSpace(s.Episodes) != "" || s.Latest
+}
+
+// selectEpisodes works out a show's episode counts per season, trusting TMDb over the
+// counts the client sent, and the episodes the selection asks for
+func selectEpisodes(query string, identity mediaIdentity, requested []int, selection ShowSelection) ([]int, episodeSet, error) {
+	seasons := requested
+	if len(identity.seasons) > 0 {
+		if len(requested) > 0 && !slices.Equal(requested, identity.seasons) {
+			logger.WriteWarning(fmt.Sprintf("Requested seasons %v for %s don't match TMDb's %v, using TMDb's",
+				requested, query, identity.seasons))
+		}
+		seasons = identity.seasons
+	}
+
+	specials := selection.Specials || slices.Contains(selection.Seasons, 0)
+	if len(seasons) == 0 && !(specials && identity.specials > 0) {
+		return nil, nil, fmt.Errorf("%w: no seasons known for %s, send seasons or a TMDb ID", ErrInvalidSelection, query)
+	}
+
+	// Season 0 holds the specials, which only TMDb counts
+	episodeCount := func(season int) int {
+		if season == 0 {
+			return identity.specials
+		}
+		return seasons[season-1]
+	}
+
+	wanted := episodeSet{}
+	if !selection.partial() {
+		wanted.union(wantedEpisodes(seasons))
+	}
+	for _, season := range selection.Seasons {
+		if season < 0 || season > len(seasons) {
+			return nil, nil, fmt.Errorf("%w: %s has no season %d", ErrInvalidSelection, query, season)
+		}
+		if season > 0 {
+			wanted.addSeason(season, seasons[season-1])
+		}
+	}
+	if selection.Latest {
+		latest := len(seasons)
+		if identity.release.lastAired.season > 0 && identity.release.lastAired.season < latest {
+			latest = identity.release.lastAired.season
+		}
+		if latest == 0 {
+			return nil, nil, fmt.Errorf("%w: %s has no regular seasons", ErrInvalidSelection, query)
+		}
+		wanted.addSeason(latest, seasons[latest-1])
+	}
+
+	for _, spec := range strings.Split(selection.Episodes, ",") {
+		spec = strings.ReplaceAll(strings.TrimSpace(spec), " ", "")
+		if spec == "" {
+			continue
+		}
+		match := episodeSpecPattern.FindStringSubmatch(spec)
+		if match == nil {
+			return nil, nil, fmt.Errorf("%w: can't read %q, use S03, S03E05 or S03E04-E08", ErrInvalidSelection, spec)
+		}
+
+		season, _ := strconv.Atoi(match[1])
+		if season > len(seasons) {
+			return nil, nil, fmt.Errorf("%w: %s has no season %d", ErrInvalidSelection, query, season)
+		}
+		count := episodeCount(season)
+		if match[2] == "" {
+			wanted.addSeason(season, count)
+			continue
+		}
+
+		first, _ := strconv.Atoi(match[2])
+		last := first
+		if match[3] != "" {
+			last, _ = strconv.Atoi(match[3])
+		}
+		if first < 1 || last < first || last > count {
+			return nil, nil, fmt.Errorf("%w: season %d of %s has %d episodes, not %s", ErrInvalidSelection, season, query, count, spec)
+		}
+		for episode := first; episode <= last; episode++ {
+			wanted[episodeKey{season, episode}] = true
+		}
+	}
+
+	if specials {
+		if identity.specials == 0 {
+			logger.WriteWarning(fmt.Sprintf("TMDb lists no specials for %s", query))
+		}
+		wanted.addSeason(0, identity.specials)
+	}
+	return seasons, wanted, nil
+}
+
+// skipGrabbed drops the selected episodes that were already grabbed for the show, so
+// asking for an overlapping selection again only searches for what's new
+func skipGrabbed(mediaType string, query string, identity mediaIdentity, selected episodeSet) episodeSet {
+	grabbed := grabbedEpisodes(wantedKey(mediaType, identity.tmdbID, query)).intersect(selected)
+	if len(grabbed) == 0 {
+		return selected
+	}
+	logger.WriteInfo(fmt.Sprintf("Skipping %s of %s, grabbed already", grabbed, query))
+	return newEpisodeSet(selected.missing(grabbed))
+}
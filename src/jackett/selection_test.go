@@ -0,0 +1,84 @@
+package jackett
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSelectEpisodesPrefersTMDbSeasons(t *testing.T) {
+	identity := mediaIdentity{seasons: []int{3, 4}, specials: 2}
+
+	// The client counted specials as season 1, shifting every season along
+	seasons, wanted, err := selectEpisodes("Night Harbor", identity, []int{2, 3, 4}, ShowSelection{Seasons: []int{2}, Specials: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seasons) != 2 || seasons[1] != 4 {
+		t.Errorf("expected TMDb's seasons, got %v", seasons)
+	}
+	if got := wanted.String(); got != "S00E01-E02 S02E01-E04" {
+		t.Errorf("unexpected selection %s", got)
+	}
+
+	if _, _, err := selectEpisodes("Night Harbor", identity, nil, ShowSelection{Seasons: []int{3}}); !errors.Is(err, ErrInvalidSelection) {
+		t.Errorf("expected ErrInvalidSelection for a missing season, got %v", err)
+	}
+	if _, _, err := selectEpisodes("Night Harbor", mediaIdentity{}, nil, ShowSelection{}); !errors.Is(err, ErrInvalidSelection) {
+		t.Errorf("expected ErrInvalidSelection without any seasons, got %v", err)
+	}
+}
+
+func TestSelectEpisodesParsesRangesAndLatestSeason(t *testing.T) {
+	identity := mediaIdentity{seasons: []int{3, 10, 8}, release: releaseSchedule{lastAired: episodeKey{2, 10}}}
+
+	_, wanted, err := selectEpisodes("Night Harbor", identity, nil, ShowSelection{Episodes: "S02E04–E08, s01e02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := wanted.String(); got != "S01E02 S02E04-E08" {
+		t.Errorf("unexpected selection %s", got)
+	}
+
+	// Season 3 is announced but hasn't started airing
+	_, wanted, err = selectEpisodes("Night Harbor", identity, nil, ShowSelection{Latest: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := wanted.String(); got != "S02E01-E10" {
+		t.Errorf("expected the latest aired season, got %s", got)
+	}
+
+	for _, spec := range []string{"S02E09-E11", "S04", "episode 5"} {
+		if _, _, err := selectEpisodes("Night Harbor", identity, nil, ShowSelection{Episodes: spec}); !errors.Is(err, ErrInvalidSelection) {
+			t.Errorf("expected ErrInvalidSelection for %q, got %v", spec, err)
+		}
+	}
+}
+
+func TestMakeShowQueryGrabsOnlySelectedEpisodes(t *testing.T) {
+	indexer, client := setupHarness(t, map[string]string{
+		"Night Harbor S01 season 1080p": "night_harbor_s01.xml",
+		`"Night Harbor" S02E02 1080p`:   "night_harbor_s02e02.xml",
+	})
+
+	selection := ShowSelection{Episodes: "S02E02-E03"}
+	if err := MakeShowQuery(context.Background(), "Night Harbor", []int{3, 3}, selection, 0, 0, "1080p"); err != nil {
+		t.Fatalf("MakeShowQuery: %v", err)
+	}
+	if got := client.links(); len(got) != 1 || got[0] != indexer.server.URL+"/download/night-harbor-s02e02-e03.torrent" {
+		t.Errorf("expected only the S02E02-E03 release, got %v", got)
+	}
+
+	// A partial request isn't followed for new episodes, and asking again finds nothing new to grab
+	records := WantedList()
+	if len(records) != 1 || records[0].Monitored || len(records[0].Grabbed) != 2 {
+		t.Fatalf("unexpected wanted list %+v", records)
+	}
+	if err := MakeShowQuery(context.Background(), "Night Harbor", []int{3, 3}, selection, 0, 0, "1080p"); err != nil {
+		t.Fatalf("MakeShowQuery: %v", err)
+	}
+	if got := client.links(); len(got) != 1 {
+		t.Errorf("grabbed episodes shouldn't be grabbed again, got %v", got)
+	}
+}
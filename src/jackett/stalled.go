@@ -0,0 +1,184 @@
+package jackett
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"high-seas/src/activity"
+	"high-seas/src/db"
+	"high-seas/src/deluge"
+	"high-seas/src/downloads"
+	"high-seas/src/history"
+	"high-seas/src/logger"
+	"high-seas/src/utils"
+)
+
+var (
+	// stallTimeout is how long a download may go without progress before it's replaced
+	stallTimeout = utils.EnvVarDuration("DOWNLOAD_STALL_TIMEOUT", 6*time.Hour)
+	// metadataTimeout is how long a magnet may take to resolve to a torrent
+	metadataTimeout = utils.EnvVarDuration("DOWNLOAD_METADATA_TIMEOUT", time.Hour)
+	// stallCheckInterval is how often downloads are checked for stalls, 0 to disable
+	stallCheckInterval = utils.EnvVarDuration("DOWNLOAD_STALL_CHECK_INTERVAL", 15*time.Minute)
+
+	// removeTorrent removes a torrent from the download client; tests swap in a fake
+	removeTorrent = deluge.RemoveTorrent
+)
+
+// StartStallWatchdog replaces stalled downloads on an interval, until the context is done
+func StartStallWatchdog(ctx context.Context) {
+	if stallCheckInterval <= 0 {
+		logger.WriteInfo("Stalled download checks disabled")
+		return
+	}
+
+	logger.WriteInfo(fmt.Sprintf("Checking for stalled downloads every %s", stallCheckInterval))
+	ticker := time.NewTicker(stallCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.WriteInfo("Stalled download checks stopped")
+			return
+		case <-ticker.C:
+			for _, stall := range downloads.Stalled(stallTimeout, metadataTimeout) {
+				if ctx.Err() != nil {
+					return
+				}
+				if err := replaceStalled(ctx, stall.Download, stall.Reason); err != nil {
+					logger.WriteError(fmt.Sprintf("Failed to replace stalled %s", stall.Download.Title), err)
+				}
+			}
+		}
+	}
+}
+
+// replaceStalled removes a stalled download with its partial data, blocklists the release
+// and searches again for what it was grabbed for. When nothing else is out yet, what's
+// missing waits on the wanted list.
+func replaceStalled(ctx context.Context, download db.Download, reason string) error {
+	logger.WriteWarning(fmt.Sprintf("%s for %s stalled: %s", download.Title, download.Query, reason))
+	if err := removeTorrent(ctx, download.Hash, true); err != nil {
+		return fmt.Errorf("failed to remove %s from Deluge: %w", download.Title, err)
+	}
+	downloads.MarkStalled(download.Hash, reason)
+
+	blockRelease(db.BlockedRelease{
+		MediaType: download.MediaType,
+		Query:     download.Query,
+		TMDb:      download.TMDb,
+		Title:     download.Title,
+		InfoHash:  download.Hash,
+		Reason:    reason,
+	})
+	history.Record(db.GrabHistory{
+		MediaType: download.MediaType,
+		Query:     download.Query,
+		TMDb:      download.TMDb,
+		Title:     download.Title,
+		InfoHash:  download.Hash,
+		Status:    history.StatusStalled,
+		Error:     reason,
+		Episodes:  download.Episodes,
+		Quality:   download.Quality,
+	})
+	activity.Record(db.Activity{
+		Action:    activity.ActionStalledRemoved,
+		MediaType: download.MediaType,
+		Query:     download.Query,
+		TMDb:      download.TMDb,
+		Title:     download.Title,
+		InfoHash:  download.Hash,
+		Message:   fmt.Sprintf("Removed stalled %s from Deluge: %s", download.Title, reason),
+	})
+
+	err := searchReplacement(ctx, download)
+	if errors.Is(err, ErrNoMatches) || errors.Is(err, ErrNotReleased) {
+		logger.WriteInfo(fmt.Sprintf("No replacement for %s yet, it's on the wanted list", download.Title))
+		return nil
+	}
+	return err
+}
+
+// searchReplacement searches again for the movie or episodes a stalled download was for
+func searchReplacement(ctx context.Context, download db.Download) error {
+	switch download.MediaType {
+	case MediaMovie:
+		return MakeMovieQuery(ctx, download.Query, download.TMDb, 0, download.Quality)
+	case MediaAnimeMovie:
+		return MakeAnimeMovieQuery(ctx, download.Query, download.TMDb, download.Quality)
+	}
+
+	episodes := parseEpisodeRanges(download.Episodes)
+	if len(episodes) == 0 {
+		return fmt.Errorf("%s doesn't say which episodes it was grabbed for, search for them again", download.Title)
+	}
+
+	key := wantedKey(download.MediaType, download.TMDb, download.Query)
+	if ungrabEpisodes(key, episodes) {
+		item, _ := findWantedByKey(key)
+		return searchWanted(ctx, item)
+	}
+
+	// Not on the wanted list, so ask for the same episodes again
+	selection := ShowSelection{Episodes: strings.Join(strings.Fields(download.Episodes), ", ")}
+	if download.MediaType == MediaAnimeShow {
+		return MakeAnimeShowQuery(ctx, download.Query, nil, selection, download.TMDb, download.Quality)
+	}
+	return MakeShowQuery(ctx, download.Query, nil, selection, download.TMDb, 0, download.Quality)
+}
+
+// ungrabEpisodes puts episodes of a wanted show back on its missing list, reporting
+// whether the show is on the wanted list
+func ungrabEpisodes(key string, episodes episodeSet) bool {
+	ensureWantedLoaded()
+
+	wantedMutex.Lock()
+	item, ok := wanted[key]
+	if !ok {
+		wantedMutex.Unlock()
+		return false
+	}
+	for episode := range episodes {
+		delete(item.grabbed, episode)
+		item.missing[episode] = true
+	}
+	record := item.record()
+	wantedMutex.Unlock()
+
+	saveWanted(record)
+	return true
+}
+
+func findWantedByKey(key string) (*wantedItem, bool) {
+	wantedMutex.Lock()
+	defer wantedMutex.Unlock()
+	item, ok := wanted[key]
+	return item, ok
+}
+
+// parseEpisodeRanges reads an episode list as written by episodeSet.String
+func parseEpisodeRanges(list string) episodeSet {
+	set := episodeSet{}
+	for _, spec := range strings.Fields(list) {
+		match := episodeSpecPattern.FindStringSubmatch(spec)
+		if match == nil || match[2] == "" {
+			continue
+		}
+		season, _ := strconv.Atoi(match[1])
+		first, _ := strconv.Atoi(match[2])
+		last := first
+		if match[3] != "" {
+			last, _ = strconv.Atoi(match[3])
+		}
+		for episode := first; episode <= last; episode++ {
+			set[episodeKey{season, episode}] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,61 @@
+package jackett
+
+import (
+	"context"
+	"testing"
+
+	"high-seas/src/db"
+	"high-seas/src/downloads"
+)
+
+func TestReplaceStalledMovieGrabsNextBest(t *testing.T) {
+	_, client := setupHarness(t, map[string]string{
+		`"The Quiet Orbit" 1080p`: "movie_quiet_orbit.xml",
+	})
+	var removed []string
+	oldRemove := removeTorrent
+	removeTorrent = func(ctx context.Context, hash string, removeData bool) error {
+		removed = append(removed, hash)
+		return nil
+	}
+	t.Cleanup(func() { removeTorrent = oldRemove })
+
+	if err := MakeMovieQuery(context.Background(), "The Quiet Orbit", 0, 2017, "1080p"); err != nil {
+		t.Fatalf("MakeMovieQuery: %v", err)
+	}
+	stalled := trackedDownload(t, client.links()[0])
+
+	if err := replaceStalled(context.Background(), stalled, "no progress at 0.0% for 6h0m0s"); err != nil {
+		t.Fatal(err)
+	}
+	links := client.links()
+	if len(links) != 2 || links[1] == links[0] {
+		t.Fatalf("expected another release to be grabbed, got %v", links)
+	}
+	if len(removed) != 1 || removed[0] != stalled.Hash {
+		t.Errorf("expected the stalled torrent to be removed, got %v", removed)
+	}
+	if got := trackedDownload(t, links[0]); got.State != downloads.StateStalled {
+		t.Errorf("expected the download to be marked stalled, got %s", got.State)
+	}
+
+	// The blocklisted release isn't picked again
+	if err := MakeMovieQuery(context.Background(), "The Quiet Orbit", 0, 2017, "1080p"); err != nil {
+		t.Fatalf("MakeMovieQuery: %v", err)
+	}
+	if got := client.links(); got[2] == links[0] {
+		t.Errorf("the stalled release was grabbed again")
+	}
+}
+
+// trackedDownload finds the download the fake Deluge added for a link
+func trackedDownload(t *testing.T, link string) db.Download {
+	t.Helper()
+	for _, download := range downloads.List(downloads.Filter{}) {
+		if download.Hash == fakeHash(link) {
+			return download
+		}
+	}
+	t.Fatalf("%s isn't tracked", link)
+	return db.Download{}
+}
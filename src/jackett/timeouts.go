@@ -0,0 +1,73 @@
+package jackett
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"high-seas/src/utils"
+)
+
+var (
+	// searchTimeout bounds a whole search and grab, from the first query to the last grab
+	searchTimeout = utils.EnvVarDuration("SEARCH_TIMEOUT", 15*time.Minute)
+	// indexerTimeout bounds a single Jackett request, so one hung indexer can't stall a search
+	indexerTimeout = utils.EnvVarDuration("INDEXER_TIMEOUT", 30*time.Second)
+)
+
+// TimeoutError reports that an indexer request or a whole search ran past its deadline
+type TimeoutError struct {
+	Operation string
+	Timeout   time.Duration
+	Err       error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Operation, e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// withSearchDeadline applies the overall search deadline on top of the caller's context
+func withSearchDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if searchTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, searchTimeout)
+}
+
+// withIndexerDeadline applies the per-request deadline for a single indexer query
+func withIndexerDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if indexerTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, indexerTimeout)
+}
+
+// searchError returns a TimeoutError if the search ran out of time, the cancellation
+// error if the caller gave up, or fallback otherwise
+func searchError(ctx context.Context, query string, fallback error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return &TimeoutError{Operation: fmt.Sprintf("search for %s", query), Timeout: searchTimeout, Err: ctx.Err()}
+	case ctx.Err() != nil:
+		return fmt.Errorf("search for %s cancelled: %w", query, ctx.Err())
+	}
+	return fallback
+}
+
+// pause waits between queries, returning early with the context's error if it's done
+func pause(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
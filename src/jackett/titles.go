@@ -0,0 +1,142 @@
+package jackett
+
+import (
+	"fmt"
+	"sync"
+	"unicode"
+
+	"high-seas/src/db"
+	"high-seas/src/logger"
+)
+
+// MAX_TITLE_VARIANTS caps how many titles a single request searches for
+const MAX_TITLE_VARIANTS = 4
+
+// TMDb namespaces for remembered aliases, shared by the anime and non-anime searches
+const (
+	aliasKindMovie = "movie"
+	aliasKindShow  = "tv"
+)
+
+var (
+	aliases      = make(map[string]string)
+	aliasesMutex sync.RWMutex
+)
+
+func aliasKey(kind string, tmdbID int) string {
+	return fmt.Sprintf("%s:%d", kind, tmdbID)
+}
+
+// rememberedAlias returns the title that last found a release for this TMDb title, if any
+func rememberedAlias(kind string, tmdbID int) string {
+	if tmdbID <= 0 {
+		return ""
+	}
+
+	aliasesMutex.RLock()
+	title, ok := aliases[aliasKey(kind, tmdbID)]
+	aliasesMutex.RUnlock()
+	if ok {
+		return title
+	}
+
+	if conn, err := db.GetDB(); err == nil {
+		var alias db.TitleAlias
+		if err := conn.Where(&db.TitleAlias{Kind: kind, TMDb: tmdbID}).Limit(1).Find(&alias).Error; err != nil {
+			logger.WriteError("Failed to load title alias", err)
+		} else if alias.Title != "" {
+			title = alias.Title
+		}
+	}
+
+	aliasesMutex.Lock()
+	aliases[aliasKey(kind, tmdbID)] = title
+	aliasesMutex.Unlock()
+	return title
+}
+
+// rememberAlias records the title that found a release so later searches try it first
+func rememberAlias(kind string, tmdbID int, title string) {
+	if tmdbID <= 0 || title == "" {
+		return
+	}
+
+	aliasesMutex.Lock()
+	previous := aliases[aliasKey(kind, tmdbID)]
+	aliases[aliasKey(kind, tmdbID)] = title
+	aliasesMutex.Unlock()
+	if previous == title {
+		return
+	}
+
+	logger.WriteInfo(fmt.Sprintf("Remembering %q as the working title for %s %d", title, kind, tmdbID))
+	if conn, err := db.GetDB(); err == nil {
+		var alias db.TitleAlias
+		err := conn.Where(&db.TitleAlias{Kind: kind, TMDb: tmdbID}).
+			Assign(db.TitleAlias{Title: title}).
+			FirstOrCreate(&alias).Error
+		if err != nil {
+			logger.WriteError("Failed to persist title alias", err)
+		}
+	}
+}
+
+// aliasKind returns the TMDb namespace a media type's aliases are stored under
+func aliasKind(mediaType string) string {
+	if mediaType == MediaShow || mediaType == MediaAnimeShow {
+		return aliasKindShow
+	}
+	return aliasKindMovie
+}
+
+// searchTitles orders the titles to search for: the alias that worked last time, the
+// client's query, then TMDb's original and alternative titles that indexers could carry
+func searchTitles(kind string, tmdbID int, query string, alternatives []string) []string {
+	candidates := append([]string{rememberedAlias(kind, tmdbID), query}, alternatives...)
+
+	seen := make(map[string]bool)
+	var titles []string
+	for _, title := range candidates {
+		key := cleanExactTitle(title)
+		if key == "" || seen[key] || !isSearchableTitle(title) {
+			continue
+		}
+		seen[key] = true
+		titles = append(titles, title)
+		if len(titles) == MAX_TITLE_VARIANTS {
+			break
+		}
+	}
+
+	// Always search for what the client asked for, even in a script we'd otherwise skip
+	if len(titles) == 0 {
+		titles = []string{query}
+	}
+	return titles
+}
+
+// isSearchableTitle skips titles in scripts release names don't use, such as kana or Cyrillic
+func isSearchableTitle(title string) bool {
+	letters, latin := 0, 0
+	for _, r := range title {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if r < unicode.MaxLatin1 || unicode.Is(unicode.Latin, r) {
+			latin++
+		}
+	}
+	return letters > 0 && latin*2 >= letters
+}
+
+// titleInRelease returns the first title the release name contains on word boundaries
+func titleInRelease(releaseTitle string, titles []string) string {
+	cleanRelease := cleanTitleForComparison(releaseTitle)
+	for _, title := range titles {
+		if containsWords(cleanRelease, cleanExactTitle(title)) {
+			return title
+		}
+	}
+	return ""
+}
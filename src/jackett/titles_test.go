@@ -0,0 +1,86 @@
+package jackett
+
+import (
+	"reflect"
+	"testing"
+)
+
+func resetAliases(t *testing.T) {
+	t.Cleanup(func() {
+		aliasesMutex.Lock()
+		aliases = make(map[string]string)
+		aliasesMutex.Unlock()
+	})
+}
+
+func TestSearchTitles(t *testing.T) {
+	resetAliases(t)
+
+	tests := []struct {
+		name         string
+		query        string
+		alternatives []string
+		want         []string
+	}{
+		{"query first", "Kaiju Academy", []string{"Kaijuu Gakuen", "Kaiju Gakuen"}, []string{"Kaiju Academy", "Kaijuu Gakuen", "Kaiju Gakuen"}},
+		{"duplicates dropped", "Kaiju Academy", []string{"KAIJU ACADEMY", "Kaiju: Academy", "Kaijuu Gakuen"}, []string{"Kaiju Academy", "Kaijuu Gakuen"}},
+		{"other scripts skipped", "Kaiju Academy", []string{"怪獣学園", "Академия Кайдзю", "Kaijuu Gakuen"}, []string{"Kaiju Academy", "Kaijuu Gakuen"}},
+		{"capped", "Night Harbor", []string{"Nachthafen", "Port de Nuit", "Puerto Nocturno", "Porto Notturno", "Nattehavn"},
+			[]string{"Night Harbor", "Nachthafen", "Port de Nuit", "Puerto Nocturno"}},
+		{"query kept in any script", "怪獣学園", nil, []string{"怪獣学園"}},
+	}
+
+	for _, test := range tests {
+		if got := searchTitles(aliasKindShow, 0, test.query, test.alternatives); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, got)
+		}
+	}
+}
+
+func TestSearchTitlesTriesRememberedAliasFirst(t *testing.T) {
+	resetAliases(t)
+
+	rememberAlias(aliasKindShow, 42, "Kaijuu Gakuen")
+	got := searchTitles(aliasKindShow, 42, "Kaiju Academy", []string{"Kaijuu Gakuen", "Kaiju Gakuen"})
+	if want := []string{"Kaijuu Gakuen", "Kaiju Academy", "Kaiju Gakuen"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// Aliases are kept per TMDb namespace
+	if got := searchTitles(aliasKindMovie, 42, "Kaiju Academy", nil); !reflect.DeepEqual(got, []string{"Kaiju Academy"}) {
+		t.Errorf("expected the movie search to ignore the show alias, got %q", got)
+	}
+}
+
+func TestIsSearchableTitle(t *testing.T) {
+	tests := map[string]bool{
+		"Night Harbor":       true,
+		"Amélie":             true,
+		"Shingeki no Kyojin": true,
+		"進撃の巨人":              false,
+		"Атака титанов":      false,
+		"2012":               false,
+		"Attack on 巨人":       true,
+	}
+	for title, want := range tests {
+		if got := isSearchableTitle(title); got != want {
+			t.Errorf("%q: expected %v, got %v", title, want, got)
+		}
+	}
+}
+
+func TestTitleInRelease(t *testing.T) {
+	titles := []string{"Kaiju Academy", "Kaijuu Gakuen"}
+	tests := map[string]string{
+		"[Anime Time] Kaijuu Gakuen - 01 [1080p].mkv": "Kaijuu Gakuen",
+		"Kaiju.Academy.S01E01.1080p.WEB":              "Kaiju Academy",
+		"Kaiju.Academy.Returns.S01E01.1080p":          "Kaiju Academy",
+		"Kaiju.Academyx.S01E01.1080p":                 "",
+		"Night.Harbor.S01E01.1080p":                   "",
+	}
+	for release, want := range tests {
+		if got := titleInRelease(release, titles); got != want {
+			t.Errorf("%s: expected %q, got %q", release, want, got)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package jackett
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	jackett "github.com/webtor-io/go-jackett"
+	"high-seas/src/logger"
+	"high-seas/src/utils"
+)
+
+// Torznab search functions
+const (
+	torznabSearch   = "search"
+	torznabMovie    = "movie"
+	torznabTVSearch = "tvsearch"
+)
+
+// searchQuery is a single indexer query, either free text or by external ID
+type searchQuery struct {
+	searchType string
+	text       string
+	categories []uint
+	imdbID     string
+	tvdbID     int
+	tmdbID     int
+	season     int
+	episode    int
+}
+
+// hasIDs reports whether the query searches by external ID rather than text alone
+func (q searchQuery) hasIDs() bool {
+	return q.imdbID != "" || q.tvdbID > 0 || q.tmdbID > 0
+}
+
+// textQueries wraps free-text query strings as searchQueries
+func textQueries(categories []uint, texts ...string) []searchQuery {
+	queries := make([]searchQuery, 0, len(texts))
+	for _, text := range texts {
+		queries = append(queries, searchQuery{searchType: torznabSearch, text: text, categories: categories})
+	}
+	return queries
+}
+
+// showIDQuery builds a tvsearch by the show's best known ID that the indexers accept,
+// preferring TVDB which most TV indexers support. Episode 0 searches the whole season.
+func showIDQuery(identity mediaIdentity, season, episode int) (searchQuery, bool) {
+	q := searchQuery{searchType: torznabTVSearch, categories: searchCategories(MediaShow), season: season, episode: episode}
+	numbering := []string{"season"}
+	if episode > 0 {
+		numbering = append(numbering, "ep")
+	}
+	supports := func(param string) bool {
+		return supportsParams(torznabTVSearch, append(numbering, param)...)
+	}
+
+	switch {
+	case identity.tvdbID > 0 && supports("tvdbid"):
+		q.tvdbID = identity.tvdbID
+	case identity.imdbID != "" && supports("imdbid"):
+		q.imdbID = identity.imdbID
+	case identity.tmdbID > 0 && supports("tmdbid"):
+		q.tmdbID = identity.tmdbID
+	default:
+		return q, false
+	}
+	return q, true
+}
+
+func (q searchQuery) String() string {
+	parts := []string{}
+	if q.text != "" {
+		parts = append(parts, fmt.Sprintf("q=%q", q.text))
+	}
+	if q.imdbID != "" {
+		parts = append(parts, "imdbid="+q.imdbID)
+	}
+	if q.tvdbID > 0 {
+		parts = append(parts, fmt.Sprintf("tvdbid=%d", q.tvdbID))
+	}
+	if q.tmdbID > 0 {
+		parts = append(parts, fmt.Sprintf("tmdbid=%d", q.tmdbID))
+	}
+	if q.season > 0 {
+		parts = append(parts, fmt.Sprintf("season=%d", q.season))
+	}
+	if q.episode > 0 {
+		parts = append(parts, fmt.Sprintf("ep=%d", q.episode))
+	}
+	return fmt.Sprintf("%s(%s)", q.searchType, strings.Join(parts, " "))
+}
+
+// TorznabError is an error document returned by a Torznab endpoint
+type TorznabError struct {
+	Code        int    `xml:"code,attr"`
+	Description string `xml:"description,attr"`
+}
+
+func (e *TorznabError) Error() string {
+	return fmt.Sprintf("torznab error %d: %s", e.Code, e.Description)
+}
+
+// Unsupported reports whether the indexer rejected the search function or one of its parameters
+func (e *TorznabError) Unsupported() bool {
+	return e.Code >= 200 && e.Code <= 203
+}
+
+type torznabFeed struct {
+	Channel struct {
+		Items []torznabItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type torznabItem struct {
+	Title    string `xml:"title"`
+	GUID     string `xml:"guid"`
+	Link     string `xml:"link"`
+	Comments string `xml:"comments"`
+	PubDate  string `xml:"pubDate"`
+	Size     uint   `xml:"size"`
+	Indexer  struct {
+		ID   string `xml:"id,attr"`
+		Name string `xml:",chardata"`
+	} `xml:"jackettindexer"`
+	Enclosure struct {
+		URL    string `xml:"url,attr"`
+		Length uint   `xml:"length,attr"`
+	} `xml:"enclosure"`
+	Attrs []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"attr"`
+}
+
+// fetchResults runs a search query, using Torznab when it carries external IDs. Recent
+// responses are reused unless the context asks for a refresh.
+func fetchResults(ctx context.Context, j *jackett.Jackett, q searchQuery) ([]jackett.Result, error) {
+	key := resultCacheKey(allIndexers, q)
+	if results, ok := cachedResults(ctx, key); ok {
+		logger.WriteInfo(fmt.Sprintf("Using cached results for %s", q))
+		return results, nil
+	}
+
+	results, err := fetchFromIndexers(ctx, j, q)
+	if err != nil {
+		return nil, err
+	}
+	storeResults(key, results)
+	return results, nil
+}
+
+func fetchFromIndexers(ctx context.Context, j *jackett.Jackett, q searchQuery) ([]jackett.Result, error) {
+	if q.hasIDs() {
+		return torznabFetch(ctx, allIndexers, q)
+	}
+
+	if err := getIndexerLimiter().wait(ctx, allIndexers); err != nil {
+		return nil, err
+	}
+
+	callCtx, cancel := withIndexerDeadline(ctx)
+	defer cancel()
+	resp, err := j.Fetch(callCtx, &jackett.FetchRequest{
+		Categories: q.categories,
+		Query:      q.text,
+	})
+	if err != nil {
+		return nil, indexerError(ctx, callCtx, fmt.Sprintf("indexer query %s", q), err)
+	}
+	return resp.Results, nil
+}
+
+// indexerError turns a request that ran past its own deadline, rather than the search's,
+// into a TimeoutError
+func indexerError(ctx, callCtx context.Context, operation string, err error) error {
+	if ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return &TimeoutError{Operation: operation, Timeout: indexerTimeout, Err: err}
+	}
+	return err
+}
+
+// logFetchError logs a failed query, noting when the indexers simply can't search by ID
+func logFetchError(message string, err error) {
+	var torznabErr *TorznabError
+	if errors.As(err, &torznabErr) && torznabErr.Unsupported() {
+		logger.WriteWarning(fmt.Sprintf("%s: ID search not supported (%s), falling back to text search", message, torznabErr.Description))
+		return
+	}
+	logger.WriteError(message, err)
+}
+
+// torznabURL builds the Jackett Torznab endpoint for an indexer
+func torznabURL(indexer string, params url.Values) string {
+	params.Set("apikey", apiKey)
+	return fmt.Sprintf("http://%s:%s/api/v2.0/indexers/%s/results/torznab/api?%s",
+		ip, port, url.PathEscape(indexer), params.Encode())
+}
+
+// torznabFetch queries a single Jackett indexer (or "all") through its Torznab endpoint
+func torznabFetch(ctx context.Context, indexer string, q searchQuery) ([]jackett.Result, error) {
+	params := url.Values{}
+	params.Set("t", q.searchType)
+	if q.text != "" {
+		params.Set("q", q.text)
+	}
+	if len(q.categories) > 0 {
+		cats := make([]string, len(q.categories))
+		for i, c := range q.categories {
+			cats[i] = strconv.FormatUint(uint64(c), 10)
+		}
+		params.Set("cat", strings.Join(cats, ","))
+	}
+	if q.imdbID != "" {
+		params.Set("imdbid", q.imdbID)
+	}
+	if q.tvdbID > 0 {
+		params.Set("tvdbid", strconv.Itoa(q.tvdbID))
+	}
+	if q.tmdbID > 0 {
+		params.Set("tmdbid", strconv.Itoa(q.tmdbID))
+	}
+	if q.season > 0 {
+		params.Set("season", strconv.Itoa(q.season))
+	}
+	if q.episode > 0 {
+		params.Set("ep", strconv.Itoa(q.episode))
+	}
+
+	body, err := torznabRequest(ctx, indexer, params, fmt.Sprintf("indexer query %s", q))
+	if err != nil {
+		return nil, err
+	}
+	return parseTorznabResponse(body)
+}
+
+// torznabRequest sends one rate-limited request to an indexer's Torznab endpoint and
+// returns the raw response body
+func torznabRequest(ctx context.Context, indexer string, params url.Values, operation string) ([]byte, error) {
+	if err := getIndexerLimiter().wait(ctx, indexer); err != nil {
+		return nil, err
+	}
+
+	callCtx, cancel := withIndexerDeadline(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(callCtx, "GET", torznabURL(indexer, params), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create torznab request: %w", err)
+	}
+
+	resp, err := utils.CreateHTTPClient().Do(req)
+	if err != nil {
+		return nil, indexerError(ctx, callCtx, operation, fmt.Errorf("failed to make torznab request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, indexerError(ctx, callCtx, operation, fmt.Errorf("failed to read torznab response: %w", err))
+	}
+	return body, nil
+}
+
+// parseTorznabResponse converts a Torznab RSS document into indexer results
+func parseTorznabResponse(body []byte) ([]jackett.Result, error) {
+	if err := torznabErrorFrom(body); err != nil {
+		return nil, err
+	}
+
+	var feed torznabFeed
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		return nil, fmt.Errorf("failed to parse torznab feed: %w", err)
+	}
+
+	results := make([]jackett.Result, 0, len(feed.Channel.Items))
+	for _, item := range feed.Channel.Items {
+		results = append(results, item.toResult())
+	}
+	return results, nil
+}
+
+// torznabErrorFrom returns the error a Torznab document reports, if it is an error
+func torznabErrorFrom(body []byte) error {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.NewDecoder(bytes.NewReader(body)).Decode(&root); err != nil {
+		return fmt.Errorf("failed to parse torznab response: %w", err)
+	}
+
+	if root.XMLName.Local == "error" {
+		var torznabErr TorznabError
+		if err := xml.Unmarshal(body, &torznabErr); err != nil {
+			return fmt.Errorf("failed to parse torznab error: %w", err)
+		}
+		return &torznabErr
+	}
+	return nil
+}
+
+// toResult maps a Torznab item onto the result type used by the scoring code
+func (item torznabItem) toResult() jackett.Result {
+	result := jackett.Result{
+		Title:     item.Title,
+		Guid:      item.GUID,
+		Link:      item.Link,
+		Comments:  item.Comments,
+		Size:      item.Size,
+		Tracker:   item.Indexer.Name,
+		TrackerId: item.Indexer.ID,
+	}
+
+	if result.Link == "" {
+		result.Link = item.Enclosure.URL
+	}
+	if result.Size == 0 {
+		result.Size = item.Enclosure.Length
+	}
+	if published, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
+		result.PublishDate.Time = published
+	}
+
+	for _, attr := range item.Attrs {
+		number, _ := strconv.ParseUint(strings.TrimPrefix(attr.Value, "tt"), 10, 64)
+		switch attr.Name {
+		case "seeders":
+			result.Seeders = uint(number)
+		case "peers":
+			result.Peers = uint(number)
+		case "size":
+			if result.Size == 0 {
+				result.Size = uint(number)
+			}
+		case "files":
+			result.Files = uint(number)
+		case "grabs":
+			result.Grabs = uint(number)
+		case "infohash":
+			result.InfoHash = attr.Value
+		case "magneturl":
+			result.MagnetUri = attr.Value
+		case "imdb", "imdbid":
+			result.Imdb = uint(number)
+		case "tmdbid":
+			result.TMDb = uint(number)
+		case "tvdbid":
+			result.TVDBId = uint(number)
+		case "category":
+			result.Category = append(result.Category, uint(number))
+		}
+	}
+
+	if result.Link == "" {
+		result.Link = result.MagnetUri
+	}
+
+	return result
+}
@@ -0,0 +1,579 @@
+package jackett
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"high-seas/src/db"
+	"high-seas/src/logger"
+	"high-seas/src/utils"
+)
+
+var (
+	// The wait before searching for a wanted item again, doubling after each empty search
+	wantedRetryMin = utils.EnvVarDuration("WANTED_RETRY_MIN", time.Hour)
+	wantedRetryMax = utils.EnvVarDuration("WANTED_RETRY_MAX", 7*24*time.Hour)
+)
+
+var (
+	// ErrNoMatches is returned when a search finds nothing to grab; what's missing is
+	// left on the wanted list
+	ErrNoMatches = errors.New("no suitable matches found")
+	// ErrNotReleased is returned when a movie or every wanted episode is still to be
+	// released; it's searched for once the release date arrives
+	ErrNotReleased = errors.New("not released yet")
+	// ErrWantedNotFound is returned for a wanted list ID that isn't on the list
+	ErrWantedNotFound = errors.New("wanted item not found")
+)
+
+// wantedItem is something a search couldn't fully grab, or a show to keep following,
+// which RSS sync and scheduled retries look for. The search details are fixed once the
+// item is stored; the progress fields are only read or written with wantedMutex held.
+type wantedItem struct {
+	key       string
+	id        uint
+	mediaType string
+	query     string
+	tmdbID    int
+	quality   string
+	identity  mediaIdentity
+	addedAt   time.Time
+
+	// Shows only: episode counts per season and the anime numbering
+	seasons []int
+	mapping *animeMapping
+
+	// The episodes still missing, those grabbed since, and whether episodes after the
+	// last known one should be grabbed as they air
+	missing   episodeSet
+	grabbed   episodeSet
+	monitored bool
+	release   releaseSchedule
+
+	attempts       int
+	lastSearchedAt time.Time
+	nextSearchAt   time.Time
+
+	// Items loaded from the database look their identity up on first use
+	needsLookup bool
+	lookup      sync.Once
+}
+
+var (
+	wanted       = make(map[string]*wantedItem)
+	wantedMutex  sync.Mutex
+	nextWantedID uint = 1
+	wantedLoaded sync.Once
+)
+
+func wantedKey(mediaType string, tmdbID int, query string) string {
+	if tmdbID > 0 {
+		return fmt.Sprintf("%s:%d", mediaType, tmdbID)
+	}
+	return fmt.Sprintf("%s:%s", mediaType, cleanExactTitle(query))
+}
+
+func (w *wantedItem) isMovie() bool {
+	return w.mediaType == MediaMovie || w.mediaType == MediaAnimeMovie
+}
+
+// retryDelay is how long to wait after the given number of earlier empty searches
+func retryDelay(attempts int) time.Duration {
+	delay := wantedRetryMin << min(attempts, 16)
+	if delay <= 0 || delay > wantedRetryMax {
+		return wantedRetryMax
+	}
+	return delay
+}
+
+// searched records a search that left something to find and schedules the next one,
+// counting on from the previous searches. Anything not out yet is searched for on its
+// release date, and shows with nothing missing are left to RSS sync. Callers hold
+// wantedMutex.
+func (w *wantedItem) searched(previousAttempts int) {
+	now := time.Now()
+	w.lastSearchedAt = now
+	w.nextSearchAt = time.Time{}
+
+	// Nothing can be found before a movie's release, so the backoff starts from there
+	if w.isMovie() && !w.release.released(now) {
+		w.attempts = 0
+		w.nextSearchAt = w.release.next
+		return
+	}
+
+	w.attempts = previousAttempts + 1
+	aired, upcoming := w.release.splitAired(w.missing)
+	if w.isMovie() || len(aired) > 0 || (len(upcoming) > 0 && w.release.released(now)) {
+		w.nextSearchAt = now.Add(retryDelay(w.attempts - 1))
+	}
+	if len(upcoming) > 0 && !w.release.released(now) &&
+		(w.nextSearchAt.IsZero() || w.release.next.Before(w.nextSearchAt)) {
+		w.nextSearchAt = w.release.next
+	}
+}
+
+// wants reports whether grabbing the episode would help: it's missing, or it aired after
+// everything we knew about and the show is monitored
+func (w *wantedItem) wants(key episodeKey) bool {
+	if w.missing[key] {
+		return true
+	}
+	if !w.monitored || w.grabbed[key] || key.season < len(w.seasons) {
+		return false
+	}
+	if key.season == len(w.seasons) {
+		return key.episode > w.seasons[key.season-1]
+	}
+	return key.season > len(w.seasons) && key.episode > 0
+}
+
+// usefulEpisodes returns the episodes of a release still worth grabbing for a wanted show
+func usefulEpisodes(key string, covered episodeSet) episodeSet {
+	wantedMutex.Lock()
+	defer wantedMutex.Unlock()
+
+	useful := episodeSet{}
+	item, ok := wanted[key]
+	if !ok {
+		return useful
+	}
+	for episode := range covered {
+		if item.wants(episode) {
+			useful[episode] = true
+		}
+	}
+	return useful
+}
+
+// movieWanted reports whether a movie is still on the wanted list
+func movieWanted(key string) bool {
+	wantedMutex.Lock()
+	defer wantedMutex.Unlock()
+	_, ok := wanted[key]
+	return ok
+}
+
+// storeWanted puts an item on the list in place of any earlier one for the same title,
+// keeping its ID, grabs and retry count. Episodes the earlier item was missing stay
+// missing unless they were part of this search.
+func storeWanted(item *wantedItem, searched episodeSet) {
+	ensureWantedLoaded()
+
+	wantedMutex.Lock()
+	attempts := 0
+	if previous, ok := wanted[item.key]; ok {
+		item.id = previous.id
+		item.addedAt = previous.addedAt
+		item.grabbed.union(previous.grabbed)
+		item.monitored = item.monitored || previous.monitored
+		for episode := range previous.missing {
+			if !searched[episode] && !item.grabbed[episode] {
+				item.missing[episode] = true
+			}
+		}
+		attempts = previous.attempts
+	} else {
+		item.id = nextWantedID
+		nextWantedID++
+	}
+	item.searched(attempts)
+	wanted[item.key] = item
+	record := item.record()
+	wantedMutex.Unlock()
+
+	saveWanted(record)
+}
+
+// wantMovie records a movie no search could grab yet, or another empty search for it
+func wantMovie(mediaType string, query string, identity mediaIdentity, quality string) {
+	storeWanted(&wantedItem{
+		key:       wantedKey(mediaType, identity.tmdbID, query),
+		mediaType: mediaType,
+		query:     query,
+		tmdbID:    identity.tmdbID,
+		quality:   quality,
+		identity:  identity,
+		addedAt:   time.Now(),
+		grabbed:   episodeSet{},
+		release:   identity.release,
+	}, nil)
+	logger.WriteInfo(fmt.Sprintf("Added %s to the wanted list", query))
+}
+
+// wantShow records the episodes a search grabbed, retries any of the searched episodes it
+// left missing and, when monitored, follows the show for new episodes
+func wantShow(mediaType string, query string, identity mediaIdentity, quality string, seasons []int, searched episodeSet, missing []episodeKey, mapping *animeMapping, monitored bool) {
+	grabbed := newEpisodeSet(searched.missing(newEpisodeSet(missing)))
+	storeWanted(&wantedItem{
+		key:       wantedKey(mediaType, identity.tmdbID, query),
+		mediaType: mediaType,
+		query:     query,
+		tmdbID:    identity.tmdbID,
+		quality:   quality,
+		identity:  identity,
+		addedAt:   time.Now(),
+		seasons:   seasons,
+		mapping:   mapping,
+		missing:   newEpisodeSet(missing),
+		grabbed:   grabbed,
+		monitored: monitored,
+		release:   identity.release,
+	}, searched)
+	if monitored {
+		logger.WriteInfo(fmt.Sprintf("Monitoring %s for new episodes, %d missing", query, len(missing)))
+	} else {
+		logger.WriteInfo(fmt.Sprintf("Added %d missing episodes of %s to the wanted list", len(missing), query))
+	}
+}
+
+// fulfilMovie drops a movie from the wanted list once it has been grabbed
+func fulfilMovie(mediaType string, tmdbID int, query string) {
+	ensureWantedLoaded()
+	key := wantedKey(mediaType, tmdbID, query)
+
+	wantedMutex.Lock()
+	item, ok := wanted[key]
+	delete(wanted, key)
+	wantedMutex.Unlock()
+
+	if ok {
+		deleteWanted(item.id)
+	}
+}
+
+// releaseGrabbed updates the wanted list for a release grabbed outside a search or sync
+func releaseGrabbed(mediaType string, tmdbID int, query string, title string) {
+	key := wantedKey(mediaType, tmdbID, query)
+	if mediaType == MediaMovie || mediaType == MediaAnimeMovie {
+		fulfilMovie(mediaType, tmdbID, query)
+		return
+	}
+
+	ensureWantedLoaded()
+	wantedMutex.Lock()
+	item, ok := wanted[key]
+	wantedMutex.Unlock()
+	if !ok {
+		return
+	}
+
+	item.resolve()
+	if mediaType == MediaAnimeShow {
+		markEpisodesGrabbed(key, parseAnimeCoverage(title, showTitles(item.query, item.identity), item.mapping))
+	} else {
+		markEpisodesGrabbed(key, parseCoverage(title, item.seasons))
+	}
+}
+
+// markEpisodesGrabbed records episodes grabbed for a wanted show
+func markEpisodesGrabbed(key string, covered episodeSet) {
+	if len(covered) == 0 {
+		return
+	}
+
+	wantedMutex.Lock()
+	item, ok := wanted[key]
+	if !ok {
+		wantedMutex.Unlock()
+		return
+	}
+	for episode := range covered {
+		delete(item.missing, episode)
+		item.grabbed[episode] = true
+	}
+	if len(item.missing) == 0 {
+		item.nextSearchAt = time.Time{}
+	}
+	record := item.record()
+	wantedMutex.Unlock()
+
+	saveWanted(record)
+}
+
+// episodesSearched records a retry of a show's missing episodes with its latest release
+// schedule, scheduling another for the ones still missing
+func episodesSearched(key string, release releaseSchedule, stillMissing []episodeKey) {
+	wantedMutex.Lock()
+	item, ok := wanted[key]
+	if !ok {
+		wantedMutex.Unlock()
+		return
+	}
+	item.missing = newEpisodeSet(stillMissing)
+	item.release = release
+	item.searched(item.attempts)
+	record := item.record()
+	wantedMutex.Unlock()
+
+	saveWanted(record)
+}
+
+// grabbedEpisodes returns the episodes of a show already grabbed from the wanted list
+func grabbedEpisodes(key string) episodeSet {
+	ensureWantedLoaded()
+	wantedMutex.Lock()
+	defer wantedMutex.Unlock()
+
+	grabbed := episodeSet{}
+	if item, ok := wanted[key]; ok {
+		grabbed.union(item.grabbed)
+	}
+	return grabbed
+}
+
+// wantedItems returns the current wanted list
+func wantedItems() []*wantedItem {
+	ensureWantedLoaded()
+	wantedMutex.Lock()
+	defer wantedMutex.Unlock()
+
+	items := make([]*wantedItem, 0, len(wanted))
+	for _, item := range wanted {
+		items = append(items, item)
+	}
+	return items
+}
+
+// dueWantedItems returns the items whose next retry is due, longest overdue first
+func dueWantedItems(now time.Time) []*wantedItem {
+	ensureWantedLoaded()
+	wantedMutex.Lock()
+	defer wantedMutex.Unlock()
+
+	var due []*wantedItem
+	for _, item := range wanted {
+		if !item.nextSearchAt.IsZero() && !item.nextSearchAt.After(now) {
+			due = append(due, item)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].nextSearchAt.Before(due[j].nextSearchAt) })
+	return due
+}
+
+func findWanted(id uint) (*wantedItem, bool) {
+	ensureWantedLoaded()
+	wantedMutex.Lock()
+	defer wantedMutex.Unlock()
+
+	for _, item := range wanted {
+		if item.id == id {
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+// WantedList returns the wanted list, soonest retry first and shows that are only
+// monitored for new episodes last
+func WantedList() []db.WantedItem {
+	ensureWantedLoaded()
+	wantedMutex.Lock()
+	records := make([]db.WantedItem, 0, len(wanted))
+	for _, item := range wanted {
+		records = append(records, item.record())
+	}
+	wantedMutex.Unlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i].NextSearchAt, records[j].NextSearchAt
+		switch {
+		case a != nil && b != nil && !a.Equal(*b):
+			return a.Before(*b)
+		case (a == nil) != (b == nil):
+			return a != nil
+		}
+		return records[i].ID < records[j].ID
+	})
+	return records
+}
+
+// DropWanted removes an entry from the wanted list, stopping its retries and RSS matching
+func DropWanted(id uint) error {
+	item, ok := findWanted(id)
+	if !ok {
+		return ErrWantedNotFound
+	}
+
+	wantedMutex.Lock()
+	delete(wanted, item.key)
+	wantedMutex.Unlock()
+
+	deleteWanted(item.id)
+	logger.WriteInfo(fmt.Sprintf("Dropped %s from the wanted list", item.query))
+	return nil
+}
+
+// record converts an item to its stored form. Callers hold wantedMutex.
+func (w *wantedItem) record() db.WantedItem {
+	record := db.WantedItem{
+		ID:        w.id,
+		CreatedAt: w.addedAt,
+		Key:       w.key,
+		MediaType: w.mediaType,
+		Query:     w.query,
+		TMDb:      w.tmdbID,
+		Year:      w.identity.year,
+		Quality:   w.quality,
+		Seasons:   w.seasons,
+		Missing:   episodeNames(w.missing),
+		Grabbed:   episodeNames(w.grabbed),
+		Monitored: w.monitored,
+		Attempts:  w.attempts,
+	}
+	if !w.lastSearchedAt.IsZero() {
+		lastSearchedAt := w.lastSearchedAt
+		record.LastSearchedAt = &lastSearchedAt
+	}
+	if !w.nextSearchAt.IsZero() {
+		nextSearchAt := w.nextSearchAt
+		record.NextSearchAt = &nextSearchAt
+	}
+	if w.release.lastAired.season > 0 {
+		record.LastAired = w.release.lastAired.String()
+	}
+	if !w.release.next.IsZero() {
+		releaseAt := w.release.next
+		record.ReleaseAt = &releaseAt
+	}
+	return record
+}
+
+func episodeNames(set episodeSet) []string {
+	keys := set.missing(nil)
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.String()
+	}
+	return names
+}
+
+func episodesFromNames(names []string) episodeSet {
+	set := episodeSet{}
+	for _, name := range names {
+		var key episodeKey
+		if _, err := fmt.Sscanf(name, "S%dE%d", &key.season, &key.episode); err == nil {
+			set[key] = true
+		}
+	}
+	return set
+}
+
+// ensureWantedLoaded reads the persisted wanted list the first time it's needed
+func ensureWantedLoaded() {
+	wantedLoaded.Do(func() {
+		conn, err := db.GetDB()
+		if err != nil {
+			return
+		}
+
+		var records []db.WantedItem
+		if err := conn.Find(&records).Error; err != nil {
+			logger.WriteError("Failed to load the wanted list", err)
+			return
+		}
+
+		items := make([]*wantedItem, 0, len(records))
+		for _, record := range records {
+			items = append(items, fromRecord(record))
+		}
+
+		wantedMutex.Lock()
+		defer wantedMutex.Unlock()
+		for _, item := range items {
+			wanted[item.key] = item
+			if item.id >= nextWantedID {
+				nextWantedID = item.id + 1
+			}
+		}
+		logger.WriteInfo(fmt.Sprintf("Loaded %d wanted items", len(items)))
+	})
+}
+
+// resolve looks up the identity and anime numbering of an item loaded from the database
+// the first time it's matched or searched for. Until then it's known by name and year only.
+func (w *wantedItem) resolve() {
+	if !w.needsLookup {
+		return
+	}
+	w.lookup.Do(func() {
+		var identity mediaIdentity
+		if w.isMovie() {
+			identity = resolveMovieIdentity(w.query, w.tmdbID, w.identity.year)
+		} else {
+			identity = resolveShowIdentity(w.query, w.tmdbID, w.identity.year)
+		}
+		var mapping *animeMapping
+		if w.mediaType == MediaAnimeShow {
+			mapping = resolveAnimeMapping(w.tmdbID, w.seasons)
+		}
+
+		wantedMutex.Lock()
+		defer wantedMutex.Unlock()
+		w.identity = identity
+		w.mapping = mapping
+		// Keep the stored schedule when TMDb couldn't be reached
+		if identity.release != (releaseSchedule{}) {
+			w.release = identity.release
+		}
+	})
+}
+
+// fromRecord rebuilds a stored item without any lookups; its identity is resolved when
+// it's first needed
+func fromRecord(record db.WantedItem) *wantedItem {
+	item := &wantedItem{
+		key:       record.Key,
+		id:        record.ID,
+		mediaType: record.MediaType,
+		query:     record.Query,
+		tmdbID:    record.TMDb,
+		quality:   record.Quality,
+		addedAt:   record.CreatedAt,
+		seasons:   record.Seasons,
+		missing:   episodesFromNames(record.Missing),
+		grabbed:   episodesFromNames(record.Grabbed),
+		monitored: record.Monitored,
+		attempts:  record.Attempts,
+
+		needsLookup: true,
+	}
+	if record.LastSearchedAt != nil {
+		item.lastSearchedAt = *record.LastSearchedAt
+	}
+	if record.NextSearchAt != nil {
+		item.nextSearchAt = *record.NextSearchAt
+	}
+
+	item.identity = mediaIdentity{tmdbID: record.TMDb, year: record.Year}
+	for key := range episodesFromNames([]string{record.LastAired}) {
+		item.release.lastAired = key
+	}
+	if record.ReleaseAt != nil {
+		item.release.next = *record.ReleaseAt
+	}
+	return item
+}
+
+func saveWanted(record db.WantedItem) {
+	conn, err := db.GetDB()
+	if err != nil {
+		return
+	}
+	if err := conn.Save(&record).Error; err != nil {
+		logger.WriteError(fmt.Sprintf("Failed to persist wanted item %s", record.Query), err)
+	}
+}
+
+func deleteWanted(id uint) {
+	conn, err := db.GetDB()
+	if err != nil {
+		return
+	}
+	if err := conn.Delete(&db.WantedItem{}, id).Error; err != nil {
+		logger.WriteError("Failed to delete wanted item", err)
+	}
+}
@@ -0,0 +1,47 @@
+package jackett
+
+import (
+	"context"
+	"sync"
+
+	"high-seas/src/utils"
+)
+
+// episodeSearchWorkers bounds how many episodes are searched at once
+var episodeSearchWorkers = utils.EnvVarInt("EPISODE_SEARCH_WORKERS", 4)
+
+// searchEpisodes runs search for every episode on a bounded worker pool. Results come back
+// in the same order as keys so whatever is grabbed from them doesn't depend on timing.
+// Episodes not yet started when the context is cancelled get no results.
+func searchEpisodes(ctx context.Context, keys []episodeKey, search func(context.Context, episodeKey) []searchResult) [][]searchResult {
+	results := make([][]searchResult, len(keys))
+	workers := episodeSearchWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = search(ctx, keys[i])
+			}
+		}()
+	}
+
+feed:
+	for i := range keys {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
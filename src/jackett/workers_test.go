@@ -0,0 +1,67 @@
+package jackett
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jackett "github.com/webtor-io/go-jackett"
+)
+
+func TestSearchEpisodesKeepsOrder(t *testing.T) {
+	oldWorkers := episodeSearchWorkers
+	episodeSearchWorkers = 3
+	t.Cleanup(func() { episodeSearchWorkers = oldWorkers })
+
+	keys := wantedEpisodes([]int{12}).missing(nil)
+	var running, most int32
+	results := searchEpisodes(context.Background(), keys, func(ctx context.Context, key episodeKey) []searchResult {
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			seen := atomic.LoadInt32(&most)
+			if now <= seen || atomic.CompareAndSwapInt32(&most, seen, now) {
+				break
+			}
+		}
+		// Finish out of order
+		time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+		return []searchResult{{result: &jackett.Result{Title: key.String()}}}
+	})
+
+	for i, key := range keys {
+		if len(results[i]) != 1 || results[i][0].result.Title != key.String() {
+			t.Errorf("result %d should be for %s, got %v", i, key, results[i])
+		}
+	}
+	if most > 3 {
+		t.Errorf("expected at most 3 searches at once, saw %d", most)
+	}
+}
+
+func TestSearchEpisodesStopsOnCancel(t *testing.T) {
+	oldWorkers := episodeSearchWorkers
+	episodeSearchWorkers = 1
+	t.Cleanup(func() { episodeSearchWorkers = oldWorkers })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	keys := wantedEpisodes([]int{5}).missing(nil)
+	var searched int32
+	results := searchEpisodes(ctx, keys, func(ctx context.Context, key episodeKey) []searchResult {
+		if atomic.AddInt32(&searched, 1) == 2 {
+			cancel()
+			// Give the feeder time to notice before the worker is free again
+			time.Sleep(10 * time.Millisecond)
+		}
+		return []searchResult{{}}
+	})
+
+	if searched > 3 {
+		t.Errorf("expected the searches to stop soon after cancelling, %d ran", searched)
+	}
+	if len(results) != len(keys) || len(results[len(keys)-1]) != 0 {
+		t.Errorf("episodes not started should have no results, got %v", results)
+	}
+}
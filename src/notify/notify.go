@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"high-seas/src/logger"
+	"high-seas/src/utils"
+)
+
+// webhookURL receives a JSON POST for each event, unset to disable notifications
+var webhookURL = utils.EnvVar("NOTIFY_WEBHOOK_URL", "")
+
+// Events
+const (
+	EventAvailable = "available"
+)
+
+// Event is something about a request worth telling someone
+type Event struct {
+	Event     string    `json:"event"`
+	MediaType string    `json:"media_type"`
+	Query     string    `json:"query"`
+	TMDb      int       `json:"TMDb,omitempty"`
+	Title     string    `json:"title"`
+	Episodes  string    `json:"episodes,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+}
+
+// Send posts an event to the webhook. Notifications are best effort, so failures are
+// logged rather than returned.
+func Send(ctx context.Context, event Event) {
+	if webhookURL == "" {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.WriteError("Failed to marshal notification", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		logger.WriteError("Failed to create notification request", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logger.WriteWarning(fmt.Sprintf("Failed to send %s notification for %s: %v", event.Event, event.Query, err))
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		logger.WriteWarning(fmt.Sprintf("Notification webhook answered %d for %s", resp.StatusCode, event.Query))
+	}
+}
@@ -0,0 +1,231 @@
+package plex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"high-seas/src/utils"
+)
+
+var (
+	serverURL = utils.EnvVar("PLEX_URL", "")
+	token     = utils.EnvVar("PLEX_TOKEN", "")
+)
+
+var (
+	// ErrNotConfigured is returned when PLEX_URL or PLEX_TOKEN isn't set
+	ErrNotConfigured = errors.New("PLEX_URL and PLEX_TOKEN are not set")
+	// ErrNoSection is returned when no library section holds a folder
+	ErrNoSection = errors.New("no Plex library section")
+)
+
+// Section is a Plex library section and the folders it scans
+type Section struct {
+	Key       string   `json:"key"`
+	Type      string   `json:"type"` // movie or show
+	Title     string   `json:"title"`
+	Locations []string `json:"locations"`
+}
+
+// Item is a movie or show in a Plex library
+type Item struct {
+	RatingKey string `json:"rating_key"`
+	Title     string `json:"title"`
+}
+
+// Episode is an episode Plex has for a show
+type Episode struct {
+	Season  int
+	Episode int
+}
+
+type mediaContainer struct {
+	MediaContainer struct {
+		Directory []struct {
+			Key      string `json:"key"`
+			Type     string `json:"type"`
+			Title    string `json:"title"`
+			Location []struct {
+				Path string `json:"path"`
+			} `json:"Location"`
+		} `json:"Directory"`
+		Metadata []struct {
+			RatingKey   string `json:"ratingKey"`
+			Title       string `json:"title"`
+			GUID        string `json:"guid"`
+			ParentIndex int    `json:"parentIndex"`
+			Index       int    `json:"index"`
+			Guids       []struct {
+				ID string `json:"id"`
+			} `json:"Guid"`
+		} `json:"Metadata"`
+	} `json:"MediaContainer"`
+}
+
+// Configured reports whether a Plex server and token are set
+func Configured() bool {
+	return serverURL != "" && token != ""
+}
+
+// Sections lists the server's library sections
+func Sections(ctx context.Context) ([]Section, error) {
+	var container mediaContainer
+	if err := get(ctx, "/library/sections", nil, &container); err != nil {
+		return nil, err
+	}
+
+	var sections []Section
+	for _, directory := range container.MediaContainer.Directory {
+		section := Section{Key: directory.Key, Type: directory.Type, Title: directory.Title}
+		for _, location := range directory.Location {
+			section.Locations = append(section.Locations, location.Path)
+		}
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
+
+// ScanFolder asks Plex to scan just the given folder of the section of that kind
+// holding it, rather than the whole library
+func ScanFolder(ctx context.Context, kind string, folder string) (Section, error) {
+	sections, err := Sections(ctx)
+	if err != nil {
+		return Section{}, err
+	}
+	section, ok := sectionFor(sections, kind, folder)
+	if !ok {
+		return Section{}, fmt.Errorf("%w of type %s holds %s", ErrNoSection, kind, folder)
+	}
+
+	query := url.Values{"path": {folder}}
+	if err := get(ctx, "/library/sections/"+section.Key+"/refresh", query, nil); err != nil {
+		return section, err
+	}
+	return section, nil
+}
+
+// sectionFor picks the section whose folders hold the path, or the only section of
+// that kind when the library is mounted elsewhere for Plex
+func sectionFor(sections []Section, kind string, folder string) (Section, bool) {
+	var ofKind []Section
+	for _, section := range sections {
+		if section.Type != kind {
+			continue
+		}
+		ofKind = append(ofKind, section)
+		for _, location := range section.Locations {
+			if folder == location || strings.HasPrefix(folder, strings.TrimRight(location, "/")+string(filepath.Separator)) {
+				return section, true
+			}
+		}
+	}
+	if len(ofKind) == 1 {
+		return ofKind[0], true
+	}
+	return Section{}, false
+}
+
+// FindByTMDb looks for the item with a TMDb GUID in a section, first among the items
+// titled like it, then the whole section. It returns nil when Plex doesn't have it.
+func FindByTMDb(ctx context.Context, section Section, tmdbID int, title string) (*Item, error) {
+	for _, filter := range []string{title, ""} {
+		query := url.Values{"includeGuids": {"1"}}
+		if filter != "" {
+			query.Set("title", filter)
+		}
+
+		var container mediaContainer
+		if err := get(ctx, "/library/sections/"+section.Key+"/all", query, &container); err != nil {
+			return nil, err
+		}
+		for _, metadata := range container.MediaContainer.Metadata {
+			guids := []string{metadata.GUID}
+			for _, guid := range metadata.Guids {
+				guids = append(guids, guid.ID)
+			}
+			if hasTMDbGUID(guids, tmdbID) {
+				return &Item{RatingKey: metadata.RatingKey, Title: metadata.Title}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// hasTMDbGUID matches the tmdb:// GUIDs of Plex's agents and the legacy TMDb agent's
+func hasTMDbGUID(guids []string, tmdbID int) bool {
+	for _, guid := range guids {
+		for _, prefix := range []string{"tmdb://", "com.plexapp.agents.themoviedb://"} {
+			id, ok := strings.CutPrefix(guid, prefix)
+			if !ok {
+				continue
+			}
+			if id, _, _ = strings.Cut(id, "?"); id == fmt.Sprint(tmdbID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Episodes lists the episodes Plex has for a show
+func Episodes(ctx context.Context, ratingKey string) ([]Episode, error) {
+	var container mediaContainer
+	if err := get(ctx, "/library/metadata/"+ratingKey+"/allLeaves", nil, &container); err != nil {
+		return nil, err
+	}
+
+	var episodes []Episode
+	for _, metadata := range container.MediaContainer.Metadata {
+		episodes = append(episodes, Episode{Season: metadata.ParentIndex, Episode: metadata.Index})
+	}
+	return episodes, nil
+}
+
+// get calls the Plex API and unmarshals the response into target, when there is one
+func get(ctx context.Context, path string, query url.Values, target interface{}) error {
+	if !Configured() {
+		return ErrNotConfigured
+	}
+
+	endpoint := strings.TrimRight(serverURL, "/") + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Add("X-Plex-Token", token)
+	req.Header.Add("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Plex: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Plex responded with status %d for %s", resp.StatusCode, path)
+	}
+	if target == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	return nil
+}
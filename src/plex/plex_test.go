@@ -0,0 +1,63 @@
+package plex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScanFolderAndFindByTMDb(t *testing.T) {
+	var scanned string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Plex-Token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/library/sections":
+			w.Write([]byte(`{"MediaContainer":{"Directory":[
+				{"key":"1","type":"movie","title":"Movies","Location":[{"path":"/media/movies"}]},
+				{"key":"2","type":"show","title":"TV","Location":[{"path":"/media/tv"}]},
+				{"key":"3","type":"show","title":"Anime","Location":[{"path":"/media/anime"}]}]}}`))
+		case "/library/sections/3/refresh":
+			scanned = r.URL.Query().Get("path")
+		case "/library/sections/3/all":
+			// The title filter misses, so the whole section is searched
+			if r.URL.Query().Get("title") != "" {
+				w.Write([]byte(`{"MediaContainer":{}}`))
+				return
+			}
+			w.Write([]byte(`{"MediaContainer":{"Metadata":[
+				{"ratingKey":"10","title":"Other","Guid":[{"id":"tmdb://4"}]},
+				{"ratingKey":"11","title":"Night Harbour","guid":"com.plexapp.agents.themoviedb://42?lang=en"}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	oldURL, oldToken := serverURL, token
+	serverURL, token = server.URL, "token"
+	t.Cleanup(func() { serverURL, token = oldURL, oldToken })
+
+	section, err := ScanFolder(context.Background(), "show", "/media/anime/Night Harbor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if section.Key != "3" || scanned != "/media/anime/Night Harbor" {
+		t.Errorf("expected a scan of the anime folder only, got section %s and path %q", section.Key, scanned)
+	}
+
+	item, err := FindByTMDb(context.Background(), section, 42, "Night Harbor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item == nil || item.RatingKey != "11" {
+		t.Errorf("expected the show with TMDb GUID 42, got %+v", item)
+	}
+
+	if _, err := ScanFolder(context.Background(), "show", "/elsewhere/Night Harbor"); err == nil {
+		t.Error("expected an error when two show sections could hold the folder")
+	}
+}
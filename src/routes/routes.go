@@ -13,8 +13,13 @@ import (
 	"time"
 
 	"high-seas/src/api"
+	"high-seas/src/deluge"
+	"high-seas/src/downloads"
+	"high-seas/src/importer"
+	"high-seas/src/jackett"
 	"high-seas/src/logger"
 	"high-seas/src/metrics"
+	"high-seas/src/seeding"
 	"high-seas/src/utils"
 
 	"github.com/gin-contrib/cors"
@@ -22,12 +27,12 @@ import (
 )
 
 var (
-	metricsCollector = metrics.New()
-)
+	// Shared with the search packages so their counters, such as indexer cache hits, show up here
+	metricsCollector = metrics.GetGlobalMetrics()
 
-func init() {
-	metricsCollector = metrics.New()
-}
+	// Background jobs such as RSS sync run until shutdown cancels this context
+	backgroundCtx, stopBackground = context.WithCancel(context.Background())
+)
 
 // Enhanced CORS middleware
 func setupCORS() gin.HandlerFunc {
@@ -193,6 +198,25 @@ func SetupRouter() {
 			download.POST("/anime", api.DownloadAnime)
 		}
 
+		v2.GET("/history", api.GrabHistory)
+		v2.GET("/activity", api.Activity)
+		v2.GET("/downloads", api.Downloads)
+		v2.GET("/downloads/:hash", api.Download)
+		v2.POST("/downloads/:hash/import", api.ImportDownload)
+		v2.GET("/disk", api.DiskSpace)
+
+		wanted := v2.Group("/wanted")
+		{
+			wanted.GET("", api.WantedList)
+			wanted.POST("/:id/search", api.SearchWanted)
+			wanted.DELETE("/:id", api.DropWanted)
+		}
+
+		admin := v2.Group("/admin")
+		{
+			admin.GET("/indexers", api.IndexerCapabilities)
+		}
+
 		status := v2.Group("/status")
 		{
 			status.GET("/deluge", api.DelugeStatus)
@@ -242,6 +266,13 @@ func SetupRouter() {
 		tmdbMovie.POST("/all-movies-from-date", api.QueryAllMoviesFromSelectedDate)
 	}
 
+	go jackett.StartRSSSync(backgroundCtx)
+	go jackett.StartWantedRetries(backgroundCtx)
+	go downloads.StartTracker(backgroundCtx)
+	go jackett.StartStallWatchdog(backgroundCtx)
+	go importer.StartImporter(backgroundCtx)
+	go seeding.StartSeedingCleanup(backgroundCtx)
+
 	// Start server with appropriate protocol
 	startServer(r)
 }
@@ -352,6 +383,7 @@ func gracefulShutdown(server *http.Server) {
 	<-quit
 
 	logger.WriteInfo("Shutting down server...")
+	stopBackground()
 
 	// Give outstanding requests 30 seconds to complete
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -361,5 +393,10 @@ func gracefulShutdown(server *http.Server) {
 		logger.WriteError("Server forced to shutdown", err)
 	}
 
+	// Close Deluge sessions once the requests using them are done
+	if err := deluge.Shutdown(ctx); err != nil {
+		logger.WriteError("Deluge connections forced to close", err)
+	}
+
 	logger.WriteInfo("Server exited")
 }
@@ -0,0 +1,203 @@
+package seeding
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"high-seas/src/activity"
+	"high-seas/src/db"
+	"high-seas/src/deluge"
+	"high-seas/src/downloads"
+	"high-seas/src/logger"
+	"high-seas/src/utils"
+)
+
+var (
+	// Default policy for any download without its own; with none of them set, torrents seed forever
+	defaultMinRatio    = utils.EnvVar("SEED_MIN_RATIO", "0")
+	defaultMinSeedTime = utils.EnvVarDuration("SEED_MIN_TIME", 0)
+	defaultMaxSeedTime = utils.EnvVarDuration("SEED_MAX_TIME", 0)
+	// Per-indexer or per-media-type overrides as "name=ratio:min:max,...", e.g. "nyaasi=1:72h:336h,movie=2::"
+	policyOverrides = utils.EnvVar("SEED_POLICIES", "")
+	// checkInterval is how often seeding torrents are checked against their policy, 0 to disable
+	checkInterval = utils.EnvVarDuration("SEED_CHECK_INTERVAL", 15*time.Minute)
+
+	// removeTorrent removes a torrent from the download client; tests swap in a fake
+	removeTorrent = deluge.RemoveTorrent
+)
+
+// Policy says how long a torrent seeds before it's removed. It's satisfied once both
+// the ratio and seed time are reached, or after the maximum seed time regardless.
+type Policy struct {
+	MinRatio    float64
+	MinSeedTime time.Duration
+	MaxSeedTime time.Duration
+}
+
+// Enabled reports whether the policy ever removes a torrent
+func (p Policy) Enabled() bool {
+	return p.MinRatio > 0 || p.MinSeedTime > 0 || p.MaxSeedTime > 0
+}
+
+// Satisfied returns why a torrent with this ratio and seed time is done seeding, or
+// an empty string while it should keep going
+func (p Policy) Satisfied(ratio float64, seedTime time.Duration) string {
+	if !p.Enabled() {
+		return ""
+	}
+	if p.MaxSeedTime > 0 && seedTime >= p.MaxSeedTime {
+		return fmt.Sprintf("seeded for the maximum %s", p.MaxSeedTime)
+	}
+	// A policy with only a maximum seed time keeps seeding until it's reached
+	if (p.MinRatio > 0 || p.MinSeedTime > 0) && ratio >= p.MinRatio && seedTime >= p.MinSeedTime {
+		return fmt.Sprintf("reached ratio %.2f after seeding for %s", ratio, seedTime.Round(time.Minute))
+	}
+	return ""
+}
+
+// policies holds the default policy and the overrides by lowercased indexer or media type
+type policies struct {
+	fallback  Policy
+	overrides map[string]Policy
+}
+
+var (
+	globalPolicies     *policies
+	globalPoliciesOnce sync.Once
+)
+
+// getPolicies returns the policies built from the environment
+func getPolicies() *policies {
+	globalPoliciesOnce.Do(func() {
+		ratio, err := strconv.ParseFloat(defaultMinRatio, 64)
+		if err != nil {
+			logger.WriteWarning(fmt.Sprintf("Invalid SEED_MIN_RATIO %q, ignoring it", defaultMinRatio))
+			ratio = 0
+		}
+		globalPolicies = newPolicies(Policy{MinRatio: ratio, MinSeedTime: defaultMinSeedTime, MaxSeedTime: defaultMaxSeedTime}, policyOverrides)
+	})
+	return globalPolicies
+}
+
+func newPolicies(fallback Policy, overrides string) *policies {
+	p := &policies{fallback: fallback, overrides: make(map[string]Policy)}
+
+	for _, entry := range strings.Split(overrides, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, spec, ok := strings.Cut(entry, "=")
+		policy, err := parsePolicy(spec, fallback)
+		if !ok || err != nil {
+			logger.WriteWarning(fmt.Sprintf("Ignoring invalid seeding policy %q", entry))
+			continue
+		}
+		p.overrides[strings.ToLower(strings.TrimSpace(name))] = policy
+	}
+
+	return p
+}
+
+// parsePolicy reads "ratio:min:max", taking anything left empty from the fallback
+func parsePolicy(spec string, fallback Policy) (Policy, error) {
+	policy := fallback
+	parts := strings.Split(spec, ":")
+	if len(parts) > 3 {
+		return policy, fmt.Errorf("expected ratio:min:max")
+	}
+
+	if ratio := strings.TrimSpace(parts[0]); ratio != "" {
+		parsed, err := strconv.ParseFloat(ratio, 64)
+		if err != nil {
+			return policy, err
+		}
+		policy.MinRatio = parsed
+	}
+	for i, target := range []*time.Duration{&policy.MinSeedTime, &policy.MaxSeedTime} {
+		if i+1 >= len(parts) || strings.TrimSpace(parts[i+1]) == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(strings.TrimSpace(parts[i+1]))
+		if err != nil {
+			return policy, err
+		}
+		*target = parsed
+	}
+
+	return policy, nil
+}
+
+// forDownload returns the policy for a download: its indexer's, then its media type's, then the default
+func (p *policies) forDownload(download db.Download) Policy {
+	if policy, ok := p.overrides[strings.ToLower(download.Indexer)]; ok && download.Indexer != "" {
+		return policy
+	}
+	if policy, ok := p.overrides[strings.ToLower(download.MediaType)]; ok {
+		return policy
+	}
+	return p.fallback
+}
+
+// StartSeedingCleanup removes imported torrents that are done seeding on an interval,
+// until the context is done
+func StartSeedingCleanup(ctx context.Context) {
+	if checkInterval <= 0 {
+		logger.WriteInfo("Seeding cleanup disabled")
+		return
+	}
+
+	logger.WriteInfo(fmt.Sprintf("Checking seeding policies every %s", checkInterval))
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.WriteInfo("Seeding cleanup stopped")
+			return
+		case <-ticker.C:
+			cleanup(ctx, getPolicies())
+		}
+	}
+}
+
+// cleanup removes every imported torrent whose policy is satisfied, along with its data.
+// The library copy is checked first so nothing is removed that wasn't imported.
+func cleanup(ctx context.Context, p *policies) {
+	for _, download := range downloads.Seeding() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		reason := p.forDownload(download).Satisfied(download.Ratio, time.Duration(download.SeedingTime)*time.Second)
+		if reason == "" {
+			continue
+		}
+		if _, err := os.Stat(download.ImportedTo); download.ImportedTo == "" || err != nil {
+			logger.WriteWarning(fmt.Sprintf("Keeping %s seeding, its library copy at %q is missing", download.Title, download.ImportedTo))
+			continue
+		}
+
+		if err := removeTorrent(ctx, download.Hash, true); err != nil {
+			logger.WriteError(fmt.Sprintf("Failed to remove %s from Deluge", download.Title), err)
+			continue
+		}
+		downloads.MarkRemoved(download.Hash, reason)
+		activity.Record(db.Activity{
+			Action:    activity.ActionSeedingDone,
+			MediaType: download.MediaType,
+			Query:     download.Query,
+			TMDb:      download.TMDb,
+			Title:     download.Title,
+			InfoHash:  download.Hash,
+			Message:   fmt.Sprintf("Removed %s from Deluge: %s", download.Title, reason),
+		})
+	}
+}
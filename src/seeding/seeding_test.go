@@ -0,0 +1,54 @@
+package seeding
+
+import (
+	"testing"
+	"time"
+
+	"high-seas/src/db"
+)
+
+func TestPoliciesPreferIndexerThenMediaType(t *testing.T) {
+	fallback := Policy{MinRatio: 1}
+	p := newPolicies(fallback, "NyaaSi=2:72h:336h, movie=:24h:, broken=x")
+
+	anime := p.forDownload(db.Download{Indexer: "nyaasi", MediaType: "anime_show"})
+	if anime != (Policy{MinRatio: 2, MinSeedTime: 72 * time.Hour, MaxSeedTime: 336 * time.Hour}) {
+		t.Errorf("unexpected indexer policy %+v", anime)
+	}
+	// Parts left empty come from the default
+	movie := p.forDownload(db.Download{Indexer: "1337x", MediaType: "movie"})
+	if movie != (Policy{MinRatio: 1, MinSeedTime: 24 * time.Hour}) {
+		t.Errorf("unexpected media type policy %+v", movie)
+	}
+	if show := p.forDownload(db.Download{MediaType: "show"}); show != fallback {
+		t.Errorf("expected the default policy for shows, got %+v", show)
+	}
+	if _, ok := p.overrides["broken"]; ok {
+		t.Error("invalid policies should be ignored")
+	}
+}
+
+func TestPolicySatisfied(t *testing.T) {
+	policy := Policy{MinRatio: 1, MinSeedTime: time.Hour, MaxSeedTime: 24 * time.Hour}
+
+	if reason := policy.Satisfied(2, 30*time.Minute); reason != "" {
+		t.Errorf("shouldn't be done before the minimum seed time, got %q", reason)
+	}
+	if reason := policy.Satisfied(1, time.Hour); reason == "" {
+		t.Error("should be done once both ratio and seed time are reached")
+	}
+	if reason := policy.Satisfied(0.1, 24*time.Hour); reason == "" {
+		t.Error("should be done after the maximum seed time")
+	}
+
+	maxOnly := Policy{MaxSeedTime: 336 * time.Hour}
+	if reason := maxOnly.Satisfied(0, time.Minute); reason != "" {
+		t.Errorf("a maximum-only policy shouldn't be done before the maximum, got %q", reason)
+	}
+	if reason := maxOnly.Satisfied(0, 336*time.Hour); reason == "" {
+		t.Error("a maximum-only policy should be done after the maximum seed time")
+	}
+	if reason := (Policy{}).Satisfied(10, 1000*time.Hour); reason != "" {
+		t.Errorf("an empty policy should seed forever, got %q", reason)
+	}
+}
@@ -0,0 +1,296 @@
+package tmdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"high-seas/src/cache"
+	"high-seas/src/utils"
+)
+
+var (
+	baseURL  = utils.EnvVar("TMDB_API_URL", "https://api.themoviedb.org/3")
+	apiToken = utils.EnvVar("TMDB_API_TOKEN", "")
+	cacheTTL = utils.EnvVarDuration("TMDB_CACHE_TTL", 12*time.Hour)
+)
+
+// ExternalIDs holds the IDs other databases use for a TMDb title
+type ExternalIDs struct {
+	IMDbID string `json:"imdb_id"`
+	TVDBID int    `json:"tvdb_id"`
+}
+
+// AlternativeTitle is another name a title is known by in some country
+type AlternativeTitle struct {
+	Country string `json:"iso_3166_1"`
+	Title   string `json:"title"`
+	Type    string `json:"type"`
+}
+
+// MovieInfo is the subset of TMDb movie details used when searching indexers
+type MovieInfo struct {
+	ID                int         `json:"id"`
+	Title             string      `json:"title"`
+	OriginalTitle     string      `json:"original_title"`
+	ReleaseDate       string      `json:"release_date"`
+	ExternalIDs       ExternalIDs `json:"external_ids"`
+	AlternativeTitles struct {
+		Titles []AlternativeTitle `json:"titles"`
+	} `json:"alternative_titles"`
+	ReleaseDates struct {
+		Results []struct {
+			Country      string        `json:"iso_3166_1"`
+			ReleaseDates []ReleaseDate `json:"release_dates"`
+		} `json:"results"`
+	} `json:"release_dates"`
+}
+
+// TMDb release types; digital and physical releases are the ones indexers carry
+const (
+	ReleaseTheatrical = 3
+	ReleaseDigital    = 4
+	ReleasePhysical   = 5
+)
+
+// ReleaseDate is one of a movie's releases in some country
+type ReleaseDate struct {
+	Type        int    `json:"type"`
+	ReleaseDate string `json:"release_date"`
+	Note        string `json:"note"`
+}
+
+// EpisodeSummary is the last or next episode entry from TMDb TV details
+type EpisodeSummary struct {
+	SeasonNumber  int    `json:"season_number"`
+	EpisodeNumber int    `json:"episode_number"`
+	AirDate       string `json:"air_date"`
+	Name          string `json:"name"`
+}
+
+// SeasonSummary is a season entry from TMDb TV details
+type SeasonSummary struct {
+	SeasonNumber int    `json:"season_number"`
+	EpisodeCount int    `json:"episode_count"`
+	AirDate      string `json:"air_date"`
+	Name         string `json:"name"`
+}
+
+// SeasonInfo is a season's details from TMDb, listing its episodes
+type SeasonInfo struct {
+	SeasonNumber int              `json:"season_number"`
+	Name         string           `json:"name"`
+	Episodes     []EpisodeSummary `json:"episodes"`
+}
+
+// ShowInfo is the subset of TMDb TV details used when searching indexers
+type ShowInfo struct {
+	ID            int             `json:"id"`
+	Name          string          `json:"name"`
+	OriginalName  string          `json:"original_name"`
+	FirstAirDate  string          `json:"first_air_date"`
+	OriginCountry []string        `json:"origin_country"`
+	Seasons       []SeasonSummary `json:"seasons"`
+	ExternalIDs   ExternalIDs     `json:"external_ids"`
+	// Either is missing when TMDb doesn't know of one
+	LastEpisodeToAir *EpisodeSummary `json:"last_episode_to_air"`
+	NextEpisodeToAir *EpisodeSummary `json:"next_episode_to_air"`
+	// TV alternative titles come back under "results" rather than "titles"
+	AlternativeTitles struct {
+		Results []AlternativeTitle `json:"results"`
+	} `json:"alternative_titles"`
+}
+
+// Year returns the release year, or 0 when TMDb has no release date
+func (m *MovieInfo) Year() int {
+	return yearFromDate(m.ReleaseDate)
+}
+
+// Year returns the first air year, or 0 when TMDb has no air date
+func (s *ShowInfo) Year() int {
+	return yearFromDate(s.FirstAirDate)
+}
+
+// HomeRelease returns the earliest digital or physical release in any country, falling
+// back to the primary release date when TMDb lists neither. It's zero when unknown.
+func (m *MovieInfo) HomeRelease() time.Time {
+	var earliest time.Time
+	for _, country := range m.ReleaseDates.Results {
+		for _, release := range country.ReleaseDates {
+			if release.Type != ReleaseDigital && release.Type != ReleasePhysical {
+				continue
+			}
+			date := ParseDate(release.ReleaseDate)
+			if !date.IsZero() && (earliest.IsZero() || date.Before(earliest)) {
+				earliest = date
+			}
+		}
+	}
+	if earliest.IsZero() {
+		return ParseDate(m.ReleaseDate)
+	}
+	return earliest
+}
+
+// Titles returns the title, original title and alternative titles, without duplicates
+func (m *MovieInfo) Titles() []string {
+	titles := []string{m.Title, m.OriginalTitle}
+	for _, alt := range m.AlternativeTitles.Titles {
+		titles = append(titles, alt.Title)
+	}
+	return uniqueTitles(titles)
+}
+
+// Titles returns the name, original name and alternative titles, without duplicates
+func (s *ShowInfo) Titles() []string {
+	titles := []string{s.Name, s.OriginalName}
+	for _, alt := range s.AlternativeTitles.Results {
+		titles = append(titles, alt.Title)
+	}
+	return uniqueTitles(titles)
+}
+
+// EpisodeCounts returns the episode count of each regular season, index 0 being season 1.
+// Specials (season 0) are left out.
+func (s *ShowInfo) EpisodeCounts() []int {
+	var counts []int
+	for _, season := range s.Seasons {
+		if season.SeasonNumber <= 0 {
+			continue
+		}
+		for len(counts) < season.SeasonNumber {
+			counts = append(counts, 0)
+		}
+		counts[season.SeasonNumber-1] = season.EpisodeCount
+	}
+	return counts
+}
+
+// SpecialsCount returns the number of episodes in season 0, where TMDb lists specials
+func (s *ShowInfo) SpecialsCount() int {
+	for _, season := range s.Seasons {
+		if season.SeasonNumber == 0 {
+			return season.EpisodeCount
+		}
+	}
+	return 0
+}
+
+// Configured reports whether a TMDb API token is available to the backend
+func Configured() bool {
+	return apiToken != ""
+}
+
+// GetMovie fetches movie details including external IDs, alternative titles and release dates
+func GetMovie(tmdbID int) (*MovieInfo, error) {
+	var info MovieInfo
+	if err := get(fmt.Sprintf("/movie/%d?append_to_response=external_ids,alternative_titles,release_dates", tmdbID), &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// GetShow fetches TV show details including external IDs and alternative titles
+func GetShow(tmdbID int) (*ShowInfo, error) {
+	var info ShowInfo
+	if err := get(fmt.Sprintf("/tv/%d?append_to_response=external_ids,alternative_titles", tmdbID), &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// GetSeason fetches a season of a TV show with its episode titles
+func GetSeason(tmdbID int, season int) (*SeasonInfo, error) {
+	var info SeasonInfo
+	if err := get(fmt.Sprintf("/tv/%d/season/%d", tmdbID, season), &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// get performs a cached GET against the TMDb API and unmarshals the response into target
+func get(path string, target interface{}) error {
+	if !Configured() {
+		return fmt.Errorf("TMDB_API_TOKEN is not set")
+	}
+
+	cacheKey := "tmdb:" + path
+	if cache.GetJSON(cacheKey, target) {
+		return nil
+	}
+
+	client := &http.Client{Timeout: time.Second * 30}
+	req, err := http.NewRequest("GET", strings.TrimRight(baseURL, "/")+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// The setup script shares the frontend's token, which may already carry the scheme
+	req.Header.Add("Authorization", "Bearer "+strings.TrimPrefix(apiToken, "Bearer "))
+	req.Header.Add("accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("TMDb responded with status %d for %s", resp.StatusCode, path)
+	}
+
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	cache.SetWithTTL(cacheKey, body, cacheTTL)
+	return nil
+}
+
+// ParseDate reads a TMDb date, either a plain day or a full timestamp, returning zero
+// when it's empty or malformed
+func ParseDate(date string) time.Time {
+	if parsed, err := time.Parse(time.RFC3339, date); err == nil {
+		return parsed
+	}
+	if parsed, err := time.Parse("2006-01-02", date); err == nil {
+		return parsed
+	}
+	return time.Time{}
+}
+
+func yearFromDate(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(date[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
+
+// uniqueTitles drops empty and case-insensitively repeated titles, keeping the first spelling
+func uniqueTitles(titles []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, title := range titles {
+		title = strings.TrimSpace(title)
+		key := strings.ToLower(title)
+		if title == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, title)
+	}
+	return unique
+}
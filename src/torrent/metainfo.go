@@ -0,0 +1,258 @@
+package torrent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"high-seas/src/utils"
+)
+
+// maxTorrentSize caps how much of a .torrent download we'll read
+const maxTorrentSize = 10 << 20
+
+// maxNesting caps how deeply lists and dictionaries may nest; real torrents need a handful
+const maxNesting = 64
+
+// File is a single file inside a torrent
+type File struct {
+	Path   string `json:"path"`
+	Length int64  `json:"length"`
+}
+
+// MetaInfo is the subset of a .torrent file the backend inspects before adding it
+type MetaInfo struct {
+	Name     string `json:"name"`
+	InfoHash string `json:"info_hash"`
+	Private  bool   `json:"private"`
+	Files    []File `json:"files"`
+}
+
+// TotalSize returns the combined length of every file in the torrent
+func (m *MetaInfo) TotalSize() int64 {
+	var total int64
+	for _, f := range m.Files {
+		total += f.Length
+	}
+	return total
+}
+
+// MagnetRedirect is returned by Fetch when an indexer link redirects to a magnet URI,
+// which carries no file list
+type MagnetRedirect struct {
+	URI string
+}
+
+func (m *MagnetRedirect) Error() string {
+	return "torrent link redirected to a magnet URI"
+}
+
+// Fetch downloads and parses the .torrent behind an indexer link
+func Fetch(ctx context.Context, link string) (*MetaInfo, error) {
+	if strings.HasPrefix(link, "magnet:") {
+		return nil, &MagnetRedirect{URI: link}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	client := utils.CreateHTTPClient()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme == "magnet" {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download torrent: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if location := resp.Header.Get("Location"); strings.HasPrefix(location, "magnet:") {
+		return nil, &MagnetRedirect{URI: location}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("torrent download responded with status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTorrentSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read torrent: %w", err)
+	}
+
+	return Parse(data)
+}
+
+// Parse decodes a bencoded .torrent file
+func Parse(data []byte) (*MetaInfo, error) {
+	d := &decoder{data: data}
+	value, err := d.decode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode torrent: %w", err)
+	}
+
+	root, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("torrent is not a dictionary")
+	}
+	info, ok := root["info"].(map[string]interface{})
+	if !ok || d.infoEnd == 0 {
+		return nil, errors.New("torrent has no info dictionary")
+	}
+
+	hash := sha1.Sum(data[d.infoStart:d.infoEnd])
+	meta := &MetaInfo{
+		Name:     stringValue(info["name"]),
+		InfoHash: hex.EncodeToString(hash[:]),
+		Private:  intValue(info["private"]) == 1,
+	}
+
+	files, multiFile := info["files"].([]interface{})
+	if !multiFile {
+		meta.Files = []File{{Path: meta.Name, Length: intValue(info["length"])}}
+		return meta, nil
+	}
+
+	for _, entry := range files {
+		file, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		parts := []string{meta.Name}
+		if pathList, ok := file["path"].([]interface{}); ok {
+			for _, part := range pathList {
+				parts = append(parts, stringValue(part))
+			}
+		}
+		meta.Files = append(meta.Files, File{
+			Path:   path.Join(parts...),
+			Length: intValue(file["length"]),
+		})
+	}
+
+	return meta, nil
+}
+
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func intValue(v interface{}) int64 {
+	n, _ := v.(int64)
+	return n
+}
+
+// decoder is a minimal bencode reader that remembers where the top-level info dictionary sits
+type decoder struct {
+	data      []byte
+	pos       int
+	depth     int
+	nesting   int
+	infoStart int
+	infoEnd   int
+}
+
+func (d *decoder) decode() (interface{}, error) {
+	if d.pos >= len(d.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if d.nesting >= maxNesting {
+		return nil, fmt.Errorf("nested deeper than %d at %d", maxNesting, d.pos)
+	}
+
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		end := bytes.IndexByte(d.data[d.pos:], 'e')
+		if end < 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		n, err := strconv.ParseInt(string(d.data[d.pos+1:d.pos+end]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer at %d: %w", d.pos, err)
+		}
+		d.pos += end + 1
+		return n, nil
+
+	case c == 'l':
+		d.pos++
+		d.nesting++
+		var list []interface{}
+		for d.pos < len(d.data) && d.data[d.pos] != 'e' {
+			item, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		if d.pos >= len(d.data) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		d.pos++
+		d.nesting--
+		return list, nil
+
+	case c == 'd':
+		d.pos++
+		d.depth++
+		d.nesting++
+		dict := make(map[string]interface{})
+		for d.pos < len(d.data) && d.data[d.pos] != 'e' {
+			key, err := d.decodeString()
+			if err != nil {
+				return nil, err
+			}
+			start := d.pos
+			value, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			if d.depth == 1 && key == "info" {
+				d.infoStart, d.infoEnd = start, d.pos
+			}
+			dict[key] = value
+		}
+		if d.pos >= len(d.data) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		d.pos++
+		d.depth--
+		d.nesting--
+		return dict, nil
+
+	case c >= '0' && c <= '9':
+		return d.decodeString()
+
+	default:
+		return nil, fmt.Errorf("unexpected byte %q at %d", c, d.pos)
+	}
+}
+
+func (d *decoder) decodeString() (string, error) {
+	colon := bytes.IndexByte(d.data[d.pos:], ':')
+	if colon < 0 {
+		return "", io.ErrUnexpectedEOF
+	}
+	length, err := strconv.Atoi(string(d.data[d.pos : d.pos+colon]))
+	if err != nil || length < 0 {
+		return "", fmt.Errorf("invalid string length at %d", d.pos)
+	}
+	start := d.pos + colon + 1
+	if length > len(d.data)-start {
+		return "", io.ErrUnexpectedEOF
+	}
+	d.pos = start + length
+	return string(d.data[start:d.pos]), nil
+}
@@ -0,0 +1,63 @@
+package torrent
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	info := "d6:lengthi1024e4:name9:movie.mkv12:piece lengthi16384ee"
+	data := "d8:announce9:http://x/4:info" + info + "e"
+
+	meta, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	hash := sha1.Sum([]byte(info))
+	if meta.Name != "movie.mkv" || meta.InfoHash != hex.EncodeToString(hash[:]) || meta.TotalSize() != 1024 {
+		t.Errorf("unexpected metainfo %+v", meta)
+	}
+}
+
+func TestParseMultiFile(t *testing.T) {
+	data := "d4:infod5:filesld6:lengthi5e4:pathl10:S01E01.mkveed6:lengthi7e4:pathl6:extras5:a.nfoeee4:name4:Showee"
+
+	meta, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(meta.Files) != 2 || meta.Files[0].Path != "Show/S01E01.mkv" || meta.Files[1].Path != "Show/extras/a.nfo" || meta.TotalSize() != 12 {
+		t.Errorf("unexpected files %+v", meta.Files)
+	}
+}
+
+func TestParseRejectsMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		eof  bool
+	}{
+		{"huge string length", "d9223372036854775800:xe", true},
+		{"string past the end", "d4:info5:abce", true},
+		{"unterminated dictionary", "d4:infod4:name4:Show", true},
+		{"unterminated list", "d4:infod5:filesld6:lengthi5eee", true},
+		{"deep nesting", strings.Repeat("l", 100000) + strings.Repeat("e", 100000), false},
+		{"negative length", "d-1:xe", false},
+		{"not a dictionary", "i42e", false},
+	}
+
+	for _, test := range tests {
+		_, err := Parse([]byte(test.data))
+		if err == nil {
+			t.Errorf("%s: expected an error", test.name)
+			continue
+		}
+		if test.eof != errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+	}
+}